@@ -0,0 +1,237 @@
+// Package activity records the history of every task as GORM callbacks,
+// so each insert or update of the tasks table writes its TaskActivity rows
+// in the same transaction, whichever handler issued it.
+package activity
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+	"tusk/models"
+
+	"gorm.io/gorm"
+)
+
+// Actions written to TaskActivity.Action.
+const (
+	Created        = "created"
+	Assigned       = "assigned"
+	StatusChanged  = "status_changed"
+	Submitted      = "submitted"
+	Rejected       = "rejected"
+	DueDateChanged = "due_date_changed"
+	AutoAssigned   = "auto_assigned"
+)
+
+// AutoAssignKey is the gorm setting naming the strategy that picked the
+// assignee of the task being created, recorded as an AutoAssigned entry.
+const AutoAssignKey = "activity:auto_assign"
+
+// ActorFunc returns the user making the change, from the statement context.
+type ActorFunc func(ctx context.Context) (int, bool)
+
+const beforeKey = "activity:before"
+
+type recorder struct {
+	actor ActorFunc
+}
+
+// Register installs the callbacks on db. The after callbacks run before
+// GORM commits the transaction it opens around a write done outside one,
+// so the entries and the change are committed, or rolled back, together.
+func Register(db *gorm.DB, actor ActorFunc) error {
+	r := &recorder{actor: actor}
+
+	err := db.Callback().Create().After("gorm:create").Before("gorm:commit_or_rollback_transaction").
+		Register("activity:after_create", r.afterCreate)
+	if err != nil {
+		return err
+	}
+	if err := db.Callback().Update().Before("gorm:update").Register("activity:before_update", r.beforeUpdate); err != nil {
+		return err
+	}
+	return db.Callback().Update().After("gorm:update").Before("gorm:commit_or_rollback_transaction").
+		Register("activity:after_update", r.afterUpdate)
+}
+
+func isTasks(db *gorm.DB) bool {
+	return db.Error == nil && db.Statement.Schema != nil && db.Statement.Schema.Table == "tasks"
+}
+
+func (r *recorder) afterCreate(db *gorm.DB) {
+	if !isTasks(db) {
+		return
+	}
+
+	tasks := []models.Task{}
+	switch dest := db.Statement.Dest.(type) {
+	case *models.Task:
+		tasks = append(tasks, *dest)
+	case *[]models.Task:
+		tasks = *dest
+	case []models.Task: // a CreateInBatches batch
+		tasks = dest
+	}
+
+	activities := []models.TaskActivity{}
+	for _, task := range tasks {
+		activities = append(activities, r.entry(db, task.Id, Created, nil, map[string]interface{}{
+			"title":    task.Title,
+			"status":   task.Status,
+			"userId":   task.UserId,
+			"priority": models.PriorityName(task.Priority),
+			"dueDate":  formatTime(task.DueDate),
+		}))
+		if strategy, ok := db.Get(AutoAssignKey); ok && task.AutoAssigned {
+			activities = append(activities, r.entry(db, task.Id, AutoAssigned, nil, map[string]interface{}{
+				"userId":   task.UserId,
+				"strategy": strategy,
+			}))
+		}
+	}
+	r.save(db, activities)
+}
+
+// beforeUpdate loads the rows the update is about to change, using its own
+// conditions, so afterUpdate can tell what changed.
+func (r *recorder) beforeUpdate(db *gorm.DB) {
+	if !isTasks(db) {
+		return
+	}
+
+	query := db.Session(&gorm.Session{NewDB: true}).Model(&models.Task{})
+	conditions := false
+	if where, ok := db.Statement.Clauses["WHERE"]; ok && where.Expression != nil {
+		query = query.Clauses(where.Expression)
+		conditions = true
+	}
+	if db.Statement.ReflectValue.IsValid() {
+		if task, ok := db.Statement.ReflectValue.Interface().(models.Task); ok && task.Id != 0 {
+			query = query.Where("id = ?", task.Id)
+			conditions = true
+		}
+	}
+	if !conditions {
+		return
+	}
+
+	before := []models.Task{}
+	if err := query.Find(&before).Error; err != nil {
+		db.AddError(err)
+		return
+	}
+	db.InstanceSet(beforeKey, before)
+}
+
+func (r *recorder) afterUpdate(db *gorm.DB) {
+	if !isTasks(db) {
+		return
+	}
+	value, ok := db.InstanceGet(beforeKey)
+	if !ok {
+		return
+	}
+	before := value.([]models.Task)
+	if len(before) == 0 {
+		return
+	}
+
+	ids := make([]int, 0, len(before))
+	for _, task := range before {
+		ids = append(ids, task.Id)
+	}
+	after := []models.Task{}
+	if err := db.Session(&gorm.Session{NewDB: true}).Where("id IN ?", ids).Find(&after).Error; err != nil {
+		db.AddError(err)
+		return
+	}
+	current := make(map[int]models.Task, len(after))
+	for _, task := range after {
+		current[task.Id] = task
+	}
+
+	activities := []models.TaskActivity{}
+	for _, old := range before {
+		task, ok := current[old.Id]
+		if !ok {
+			continue
+		}
+		activities = append(activities, r.diff(db, old, task)...)
+	}
+	r.save(db, activities)
+}
+
+func (r *recorder) diff(db *gorm.DB, old, task models.Task) []models.TaskActivity {
+	activities := []models.TaskActivity{}
+
+	if old.UserId != task.UserId {
+		activities = append(activities, r.entry(db, task.Id, Assigned,
+			map[string]interface{}{"userId": old.UserId},
+			map[string]interface{}{"userId": task.UserId}))
+	}
+
+	if old.Status != task.Status {
+		action := StatusChanged
+		changed := map[string]interface{}{"status": task.Status}
+		switch task.Status {
+		case models.StatusReview:
+			action = Submitted
+			changed["note"] = task.SubmitNote
+			changed["evidencePath"] = task.EvidencePath
+		case models.StatusRejected:
+			action = Rejected
+			changed["reason"] = task.Reason
+		}
+		activities = append(activities, r.entry(db, task.Id, action,
+			map[string]interface{}{"status": old.Status}, changed))
+	}
+
+	if formatTime(old.DueDate) != formatTime(task.DueDate) {
+		activities = append(activities, r.entry(db, task.Id, DueDateChanged,
+			map[string]interface{}{"dueDate": formatTime(old.DueDate)},
+			map[string]interface{}{"dueDate": formatTime(task.DueDate)}))
+	}
+
+	return activities
+}
+
+func (r *recorder) entry(db *gorm.DB, taskId int, action string, old, changed map[string]interface{}) models.TaskActivity {
+	activity := models.TaskActivity{
+		TaskId:   taskId,
+		Action:   action,
+		OldValue: encode(old),
+		NewValue: encode(changed),
+	}
+	if r.actor != nil {
+		if userId, ok := r.actor(db.Statement.Context); ok {
+			activity.ActorId = &userId
+		}
+	}
+	return activity
+}
+
+// save writes through the statement's connection, which is the open
+// transaction, so a failure rolls the task change back too.
+func (r *recorder) save(db *gorm.DB, activities []models.TaskActivity) {
+	if len(activities) == 0 {
+		return
+	}
+	if err := db.Session(&gorm.Session{NewDB: true}).Create(&activities).Error; err != nil {
+		db.AddError(err)
+	}
+}
+
+func encode(values map[string]interface{}) string {
+	if values == nil {
+		return "{}"
+	}
+	encoded, _ := json.Marshal(values)
+	return string(encoded)
+}
+
+func formatTime(value *time.Time) interface{} {
+	if value == nil {
+		return nil
+	}
+	return value.UTC().Format(time.RFC3339)
+}
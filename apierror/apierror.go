@@ -0,0 +1,80 @@
+// Package apierror defines the error envelope returned by the API:
+//
+//	{"error": {"code": "USER_NOT_FOUND", "message": "...", "details": [...]}}
+//
+// Handlers attach an *Error with c.Error and return; Middleware writes it.
+package apierror
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Error is an API error. Err is the underlying cause; it is logged for
+// server errors but never sent to the client.
+type Error struct {
+	Status  int           `json:"-"`
+	Code    string        `json:"code"`
+	Message string        `json:"message"`
+	Details []interface{} `json:"details,omitempty"`
+	Err     error         `json:"-"`
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Code, e.Message, e.Err)
+	}
+	return e.Code + ": " + e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// WithDetails returns a copy of e with extra details appended.
+func (e *Error) WithDetails(details ...interface{}) *Error {
+	copied := *e
+	copied.Details = append(append([]interface{}(nil), e.Details...), details...)
+	return &copied
+}
+
+// Wrap returns a copy of e carrying err as its cause.
+func (e *Error) Wrap(err error) *Error {
+	copied := *e
+	copied.Err = err
+	return &copied
+}
+
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+func BadRequest(code, message string) *Error {
+	return New(http.StatusBadRequest, code, message)
+}
+
+func Unauthorized(code, message string) *Error {
+	return New(http.StatusUnauthorized, code, message)
+}
+
+func Forbidden(code, message string) *Error {
+	return New(http.StatusForbidden, code, message)
+}
+
+func NotFound(code, message string) *Error {
+	return New(http.StatusNotFound, code, message)
+}
+
+func Conflict(code, message string) *Error {
+	return New(http.StatusConflict, code, message)
+}
+
+// Internal hides err behind a generic message.
+func Internal(err error) *Error {
+	return &Error{
+		Status:  http.StatusInternalServerError,
+		Code:    CodeInternal,
+		Message: "Something went wrong, please try again later",
+		Err:     err,
+	}
+}
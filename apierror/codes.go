@@ -0,0 +1,70 @@
+package apierror
+
+// Error codes returned in the "code" field. Clients should switch on these
+// rather than on the message, which is meant for people and may change.
+const (
+	// CodeBadRequest: the request couldn't be understood, e.g. malformed JSON.
+	CodeBadRequest = "BAD_REQUEST"
+	// CodeValidationFailed: one or more fields are invalid; see details.
+	CodeValidationFailed = "VALIDATION_FAILED"
+	// CodeInvalidId: a path id isn't a number.
+	CodeInvalidId = "INVALID_ID"
+	// CodeUnauthorized: no or an invalid access token.
+	CodeUnauthorized = "UNAUTHORIZED"
+	// CodeInvalidCredentials: wrong email or password.
+	CodeInvalidCredentials = "INVALID_CREDENTIALS"
+	// CodeInvalidToken: a refresh or password reset token is invalid,
+	// expired or already used.
+	CodeInvalidToken = "INVALID_TOKEN"
+	// CodeAccountLocked: too many failed logins; details carry
+	// retryAfterSeconds.
+	CodeAccountLocked = "ACCOUNT_LOCKED"
+	// CodeAccountDeactivated: an Admin has deactivated the account.
+	CodeAccountDeactivated = "ACCOUNT_DEACTIVATED"
+	// CodeRateLimited: too many requests from this client; details carry
+	// retryAfterSeconds.
+	CodeRateLimited = "RATE_LIMITED"
+	// CodeForbidden: the caller may not do this.
+	CodeForbidden = "FORBIDDEN"
+	// CodeNotFound: a generic missing resource.
+	CodeNotFound = "NOT_FOUND"
+	// CodeUserNotFound: the user doesn't exist or was deleted.
+	CodeUserNotFound = "USER_NOT_FOUND"
+	// CodeDepartmentNotFound: the department doesn't exist.
+	CodeDepartmentNotFound = "DEPARTMENT_NOT_FOUND"
+	// CodeAvatarNotFound: the user has no profile picture.
+	CodeAvatarNotFound = "AVATAR_NOT_FOUND"
+	// CodeConflict: a generic conflict with the current state.
+	CodeConflict = "CONFLICT"
+	// CodeEmailTaken: the email belongs to another account.
+	CodeEmailTaken = "EMAIL_TAKEN"
+	// CodeDepartmentTaken: another department has that name.
+	CodeDepartmentTaken = "DEPARTMENT_TAKEN"
+	// CodeDepartmentHasMembers: the department still has users; details
+	// carry members, the count to move first.
+	CodeDepartmentHasMembers = "DEPARTMENT_HAS_MEMBERS"
+	// CodeManagerWithoutDepartment: a Manager must belong to a department.
+	CodeManagerWithoutDepartment = "MANAGER_WITHOUT_DEPARTMENT"
+	// CodeIdempotencyKeyReused: the Idempotency-Key was already used with a
+	// different request body.
+	CodeIdempotencyKeyReused = "IDEMPOTENCY_KEY_REUSED"
+	// CodeIdempotencyInProgress: a request with the same Idempotency-Key is
+	// still being handled; retry shortly.
+	CodeIdempotencyInProgress = "IDEMPOTENCY_IN_PROGRESS"
+	// CodeLastAdmin: the change would leave no Admin.
+	CodeLastAdmin = "LAST_ADMIN"
+	// CodeSelfDeactivation: an Admin tried to deactivate their own account.
+	CodeSelfDeactivation = "SELF_DEACTIVATION"
+	// CodeUserHasOpenTasks: the user still has unapproved tasks; details
+	// carry blockingTasks.
+	CodeUserHasOpenTasks = "USER_HAS_OPEN_TASKS"
+	// CodeFileTooLarge: an upload is over its size limit; details carry
+	// maxBytes.
+	CodeFileTooLarge = "FILE_TOO_LARGE"
+	// CodeUnsupportedMediaType: an upload's content isn't an accepted type.
+	CodeUnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
+	// CodeTimeout: the database didn't answer within the request deadline.
+	CodeTimeout = "TIMEOUT"
+	// CodeInternal: an unexpected server error. The cause is only logged.
+	CodeInternal = "INTERNAL"
+)
@@ -0,0 +1,37 @@
+package apierror
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"tusk/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware writes the last error attached with c.Error in the standard
+// envelope, unless the handler already wrote a response. Errors that
+// aren't an *Error become a generic 500.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		if len(c.Errors) == 0 || c.Writer.Written() {
+			return
+		}
+
+		err := c.Errors.Last().Err
+		var apiErr *Error
+		switch {
+		case errors.Is(err, context.DeadlineExceeded) || errors.Is(c.Request.Context().Err(), context.DeadlineExceeded):
+			apiErr = New(http.StatusServiceUnavailable, CodeTimeout, "The database did not respond in time").Wrap(err)
+		case !errors.As(err, &apiErr):
+			apiErr = Internal(err)
+		}
+
+		if apiErr.Status >= 500 {
+			middlewares.Logger(c).Error(apiErr.Message, "code", apiErr.Code, "error", apiErr.Err)
+		}
+		c.JSON(apiErr.Status, gin.H{"error": apiErr})
+	}
+}
@@ -0,0 +1,136 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+	"tusk/models"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError describes one invalid field in a VALIDATION_FAILED error.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Validation turns a ShouldBindJSON error into a 400, listing each invalid
+// field instead of the validator's own message.
+func Validation(err error) *Error {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		apiErr := BadRequest(CodeValidationFailed, "Request validation failed")
+		for _, fieldErr := range validationErrs {
+			apiErr.Details = append(apiErr.Details, FieldError{
+				Field:   fieldErr.Field(),
+				Rule:    fieldErr.Tag(),
+				Message: fieldMessage(fieldErr),
+			})
+		}
+		return apiErr
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return BadRequest(CodeValidationFailed, "Request validation failed").WithDetails(FieldError{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Message: typeErr.Field + " must be a " + typeErr.Type.String(),
+		})
+	}
+
+	if errors.Is(err, io.EOF) {
+		return BadRequest(CodeBadRequest, "Request body is required")
+	}
+	return BadRequest(CodeBadRequest, "Request body must be valid JSON")
+}
+
+// FieldMessages maps each invalid field (by JSON name) to a readable
+// message, e.g. "email" -> "email must be a valid email address". It is
+// empty when err isn't a validation error.
+func FieldMessages(err error) map[string]string {
+	messages := map[string]string{}
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		for _, fieldErr := range validationErrs {
+			messages[fieldErr.Field()] = fieldMessage(fieldErr)
+		}
+	}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		messages[typeErr.Field] = typeErr.Field + " must be a " + typeErr.Type.String()
+	}
+	return messages
+}
+
+// fieldMessage falls back to a generic sentence for tags it doesn't know.
+func fieldMessage(fieldErr validator.FieldError) string {
+	field := fieldErr.Field()
+	switch fieldErr.Tag() {
+	case "required", "required_if":
+		return field + " is required"
+	case "email":
+		return field + " must be a valid email address"
+	case "min":
+		if fieldErr.Kind() == reflect.String {
+			return field + " must be at least " + fieldErr.Param() + " characters"
+		}
+		return field + " must be at least " + fieldErr.Param()
+	case "max":
+		if fieldErr.Kind() == reflect.String {
+			return field + " must be at most " + fieldErr.Param() + " characters"
+		}
+		return field + " must be at most " + fieldErr.Param()
+	case "oneof":
+		return field + " must be one of " + strings.ReplaceAll(fieldErr.Param(), " ", ", ")
+	case "role":
+		return field + " must be one of " + strings.Join(models.Roles, ", ")
+	case "priority":
+		return field + " must be one of " + strings.Join(models.Priorities, ", ")
+	case "timezone", "eq=|timezone":
+		return field + " must be an IANA time zone such as Asia/Jakarta"
+	case "future":
+		return field + " must be in the future"
+	}
+	return field + " is invalid"
+}
+
+// RegisterValidators sets up gin's validator at startup: errors report the
+// JSON name of a field ("newPassword") rather than the Go one, and the
+// custom tags below become available to binding rules.
+//
+//	role      the value is one of models.Roles
+//	priority  the value is one of models.Priorities
+//	future    a time.Time after now
+func RegisterValidators() {
+	validate, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return
+	}
+
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name := strings.SplitN(field.Tag.Get("json"), ",", 2)[0]
+		if name == "" || name == "-" {
+			return field.Name
+		}
+		return name
+	})
+
+	validate.RegisterValidation("role", func(fl validator.FieldLevel) bool {
+		return models.ValidRole(fl.Field().String())
+	})
+	validate.RegisterValidation("priority", func(fl validator.FieldLevel) bool {
+		_, ok := models.ParsePriority(fl.Field().String())
+		return ok
+	})
+	validate.RegisterValidation("future", func(fl validator.FieldLevel) bool {
+		value, ok := fl.Field().Interface().(time.Time)
+		return ok && value.After(time.Now())
+	})
+}
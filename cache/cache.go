@@ -0,0 +1,134 @@
+// Package cache is a small in-process TTL cache for read-heavy endpoints.
+package cache
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+var errLoadPanicked = errors.New("cache: load panicked")
+
+type entry[V any] struct {
+	value   V
+	expires time.Time
+}
+
+// call is a load in progress; concurrent Gets of the same key wait for it
+// instead of running their own.
+type call[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+}
+
+// Cache holds values for a fixed TTL and is safe for concurrent use. A nil
+// *Cache caches nothing, every Get runs its load.
+type Cache[V any] struct {
+	ttl     time.Duration
+	now     func() time.Time
+	mu      sync.Mutex
+	entries map[string]entry[V]
+	calls   map[string]*call[V]
+	sweepAt int
+}
+
+// New returns a cache keeping values for ttl.
+func New[V any](ttl time.Duration) *Cache[V] {
+	return &Cache[V]{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[string]entry[V]{},
+		calls:   map[string]*call[V]{},
+		sweepAt: 1024,
+	}
+}
+
+// Get returns the cached value of key, or runs load to fill it. Only one
+// load per key runs at a time. Errors are returned but not cached.
+func (c *Cache[V]) Get(key string, load func() (V, error)) (V, error) {
+	if c == nil || c.ttl <= 0 {
+		return load()
+	}
+
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok && c.now().Before(e.expires) {
+		c.mu.Unlock()
+		return e.value, nil
+	}
+	if running, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		<-running.done
+		return running.value, running.err
+	}
+	running := &call[V]{done: make(chan struct{}), err: errLoadPanicked}
+	c.calls[key] = running
+	c.mu.Unlock()
+
+	defer c.finish(key, running)
+	value, err := load()
+	running.value, running.err = value, err
+	return value, err
+}
+
+func (c *Cache[V]) finish(key string, running *call[V]) {
+	c.mu.Lock()
+	// Invalidate drops running calls, their result may predate the change
+	if c.calls[key] == running {
+		delete(c.calls, key)
+		if running.err == nil {
+			c.store(key, running.value)
+		}
+	}
+	c.mu.Unlock()
+	close(running.done)
+}
+
+// store must be called with mu held. Expired entries are only dropped once
+// the map has doubled since the last sweep.
+func (c *Cache[V]) store(key string, value V) {
+	now := c.now()
+	if len(c.entries) >= c.sweepAt {
+		for k, e := range c.entries {
+			if !now.Before(e.expires) {
+				delete(c.entries, k)
+			}
+		}
+		c.sweepAt = 2 * max(len(c.entries), 512)
+	}
+	c.entries[key] = entry[V]{value: value, expires: now.Add(c.ttl)}
+}
+
+// Invalidate drops every key starting with one of prefixes. Loads already
+// running for those keys still return to their callers but aren't stored.
+func (c *Cache[V]) Invalidate(prefixes ...string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, prefix := range prefixes {
+		for key := range c.entries {
+			if strings.HasPrefix(key, prefix) {
+				delete(c.entries, key)
+			}
+		}
+		for key := range c.calls {
+			if strings.HasPrefix(key, prefix) {
+				delete(c.calls, key)
+			}
+		}
+	}
+}
+
+// Len is the number of stored entries, expired ones included.
+func (c *Cache[V]) Len() int {
+	if c == nil {
+		return 0
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
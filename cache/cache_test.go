@@ -0,0 +1,154 @@
+package cache
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGet(t *testing.T) {
+	c := New[int](time.Minute)
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return now }
+	loads := 0
+	load := func() (int, error) {
+		loads++
+		return loads, nil
+	}
+
+	if got, _ := c.Get("employees:", load); got != 1 {
+		t.Fatalf("Get = %d, want the first load", got)
+	}
+	if got, _ := c.Get("employees:", load); got != 1 || loads != 1 {
+		t.Errorf("Get = %d after %d loads, want it cached", got, loads)
+	}
+	if got, _ := c.Get("employees:page=2", load); got != 2 {
+		t.Errorf("Get = %d, want another key loaded on its own", got)
+	}
+
+	now = now.Add(time.Minute)
+	if got, _ := c.Get("employees:", load); got != 3 {
+		t.Errorf("Get = %d, want it loaded again after the TTL", got)
+	}
+}
+
+func TestErrorsAreNotCached(t *testing.T) {
+	c := New[int](time.Minute)
+	failure := errors.New("database is down")
+	if _, err := c.Get("stats:", func() (int, error) { return 0, failure }); !errors.Is(err, failure) {
+		t.Fatalf("err = %v, want the load's", err)
+	}
+	if got, err := c.Get("stats:", func() (int, error) { return 7, nil }); got != 7 || err != nil {
+		t.Errorf("Get = %d, %v; want the failure forgotten", got, err)
+	}
+}
+
+func TestDisabled(t *testing.T) {
+	for name, c := range map[string]*Cache[int]{"nil": nil, "zero TTL": New[int](0)} {
+		t.Run(name, func(t *testing.T) {
+			loads := 0
+			for i := 0; i < 3; i++ {
+				c.Get("stats:", func() (int, error) { loads++; return loads, nil })
+			}
+			c.Invalidate("stats:")
+			if loads != 3 || c.Len() != 0 {
+				t.Errorf("%d loads, %d entries; want every Get to load", loads, c.Len())
+			}
+		})
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	c := New[int](time.Minute)
+	for _, key := range []string{"employees:", "employees:page=2", "stats:dashboard"} {
+		c.Get(key, func() (int, error) { return 1, nil })
+	}
+	c.Invalidate("employees:")
+	if c.Len() != 1 {
+		t.Errorf("%d entries, want only stats:dashboard kept", c.Len())
+	}
+}
+
+func TestColdKeyLoadsOnce(t *testing.T) {
+	c := New[int](time.Minute)
+	var loads atomic.Int64
+	release := make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := c.Get("stats:", func() (int, error) {
+				loads.Add(1)
+				<-release
+				return 42, nil
+			})
+			if got != 42 || err != nil {
+				t.Errorf("Get = %d, %v; want the shared load", got, err)
+			}
+		}()
+	}
+	time.Sleep(20 * time.Millisecond) // let every Get reach the running load
+	close(release)
+	wg.Wait()
+	if got := loads.Load(); got != 1 {
+		t.Errorf("%d loads for one cold key, want 1", got)
+	}
+}
+
+func TestInvalidateDropsRunningLoad(t *testing.T) {
+	c := New[int](time.Minute)
+	started, release := make(chan struct{}), make(chan struct{})
+	go func() {
+		c.Get("stats:", func() (int, error) {
+			close(started)
+			<-release
+			return 1, nil // read before the write below
+		})
+	}()
+	<-started
+	c.Invalidate("stats:")
+	close(release)
+
+	if got, _ := c.Get("stats:", func() (int, error) { return 2, nil }); got != 2 {
+		t.Errorf("Get = %d, want the stale load left out of the cache", got)
+	}
+}
+
+// TestConcurrentReadersDuringInvalidation is meant for go test -race. A
+// Get that starts after Invalidate returned must never see a value loaded
+// before it.
+func TestConcurrentReadersDuringInvalidation(t *testing.T) {
+	c := New[int64](time.Minute)
+	var version, invalidated atomic.Int64
+	load := func() (int64, error) { return version.Load(), nil }
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 2000; j++ {
+				least := invalidated.Load()
+				if got, _ := c.Get("stats:", load); got < least {
+					t.Errorf("Get = %d after invalidating version %d", got, least)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 500; i++ {
+			next := version.Add(1)
+			c.Invalidate("stats:")
+			invalidated.Store(next)
+		}
+	}()
+	wg.Wait()
+}
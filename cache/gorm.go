@@ -0,0 +1,83 @@
+package cache
+
+import (
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// pending holds, for each transaction opened by Transaction, the
+// invalidations its writes queued until it commits.
+var pending sync.Map // gorm.ConnPool -> *queue
+
+type queue struct {
+	mu    sync.Mutex
+	calls []func()
+}
+
+func (q *queue) add(call func()) {
+	q.mu.Lock()
+	q.calls = append(q.calls, call)
+	q.mu.Unlock()
+}
+
+// InvalidateOnWrite registers GORM callbacks that call invalidate with the
+// prefixes of a table after every successful create, update or delete on
+// it, so no write path can forget to. They run once GORM has committed
+// the write; a write inside a transaction opened by Transaction is
+// invalidated when that transaction commits, so a load running meanwhile
+// can't cache the rows as they were before it.
+func InvalidateOnWrite(db *gorm.DB, tables map[string][]string, invalidate func(prefixes ...string)) error {
+	after := func(db *gorm.DB) {
+		if db.Error != nil {
+			return
+		}
+		prefixes, ok := tables[db.Statement.Table]
+		if !ok {
+			return
+		}
+		if q, ok := pending.Load(db.Statement.ConnPool); ok {
+			q.(*queue).add(func() { invalidate(prefixes...) })
+			return
+		}
+		invalidate(prefixes...)
+	}
+
+	const commit = "gorm:commit_or_rollback_transaction"
+	if err := db.Callback().Create().After(commit).Register("cache:invalidate_create", after); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().After(commit).Register("cache:invalidate_update", after); err != nil {
+		return err
+	}
+	return db.Callback().Delete().After(commit).Register("cache:invalidate_delete", after)
+}
+
+// Transaction runs fn in a transaction like db.Transaction and, once it
+// has committed, the invalidations its writes queued. Nested in another
+// transaction it leaves them to the outermost one.
+func Transaction(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	if _, nested := db.Statement.ConnPool.(gorm.TxCommitter); nested {
+		return db.Transaction(fn)
+	}
+
+	var q *queue
+	var pool gorm.ConnPool
+	defer func() {
+		if pool != nil {
+			pending.Delete(pool)
+		}
+	}()
+	err := db.Transaction(func(tx *gorm.DB) error {
+		q, pool = &queue{}, tx.Statement.ConnPool
+		pending.Store(pool, q)
+		return fn(tx)
+	})
+	if err == nil && q != nil {
+		pending.Delete(pool)
+		for _, call := range q.calls {
+			call()
+		}
+	}
+	return err
+}
@@ -0,0 +1,80 @@
+package cache_test
+
+import (
+	"errors"
+	"testing"
+	"tusk/cache"
+	"tusk/models"
+	"tusk/testutil"
+
+	"gorm.io/gorm"
+)
+
+func TestInvalidateOnWrite(t *testing.T) {
+	db := testutil.DB(t)
+	invalidated := []string{}
+	err := cache.InvalidateOnWrite(db, map[string][]string{"tags": {"tags:"}}, func(prefixes ...string) {
+		invalidated = append(invalidated, prefixes...)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := func(t *testing.T, want int) {
+		t.Helper()
+		if len(invalidated) != want {
+			t.Errorf("%d invalidations, want %d", len(invalidated), want)
+		}
+		invalidated = invalidated[:0]
+	}
+
+	t.Run("after a single write", func(t *testing.T) {
+		tag := models.Tag{Name: "finance"}
+		db.Create(&tag)
+		expect(t, 1)
+		db.Model(&tag).Update("name", "legal")
+		expect(t, 1)
+		db.Delete(&tag)
+		expect(t, 1)
+		db.Create(&models.Department{Name: "Sales"})
+		expect(t, 0)
+	})
+
+	t.Run("after the transaction commits", func(t *testing.T) {
+		err := cache.Transaction(db, func(tx *gorm.DB) error {
+			tx.Create(&models.Tag{Name: "ops"})
+			tx.Create(&models.Tag{Name: "hr"})
+			expect(t, 0)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect(t, 2)
+	})
+
+	t.Run("not after a rollback", func(t *testing.T) {
+		failure := errors.New("rolled back")
+		err := cache.Transaction(db, func(tx *gorm.DB) error {
+			tx.Create(&models.Tag{Name: "it"})
+			return failure
+		})
+		if !errors.Is(err, failure) {
+			t.Fatalf("error = %v", err)
+		}
+		expect(t, 0)
+	})
+
+	t.Run("nested, after the outer commit", func(t *testing.T) {
+		err := cache.Transaction(db, func(tx *gorm.DB) error {
+			err := cache.Transaction(tx, func(tx *gorm.DB) error {
+				return tx.Create(&models.Tag{Name: "qa"}).Error
+			})
+			expect(t, 0)
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect(t, 1)
+	})
+}
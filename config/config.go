@@ -0,0 +1,492 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"tusk/middlewares"
+	"tusk/passwordpolicy"
+	"tusk/ratelimit"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Version is the application version, overridable at build time with
+// -ldflags "-X tusk/config.Version=...".
+var Version = "dev"
+
+// Config is every setting the app reads at startup, loaded from the
+// environment by Load.
+type Config struct {
+	Env             string // APP_ENV: development, staging or production
+	DB              DBConfig
+	ServerHost      string
+	ServerPort      int
+	ShutdownTimeout time.Duration
+
+	JWTSecret     string
+	TokenExpiry   time.Duration
+	RefreshExpiry time.Duration
+	BcryptCost    int
+
+	QueryTimeout   time.Duration
+	CacheTTL       time.Duration // employee list and dashboard stats
+	Lockout        LockoutConfig
+	Forecast       ForecastConfig
+	AutoAssign     AutoAssignConfig
+	SMTP           SMTPConfig
+	ResetURL       string
+	VerifyURL      string // the token is appended as ?token=
+	Uploads        UploadConfig
+	Avatars        AvatarConfig
+	Bulk           BulkConfig
+	Reminders      ReminderConfig
+	FCMCredentials string   // service account JSON file; empty only logs pushes
+	LogSkipPaths   []string // request paths left out of the access log
+	RateLimit      RateLimitConfig
+	TrustedProxies []string // CIDRs or IPs whose X-Forwarded-For is honoured
+	CORS           middlewares.CORSConfig
+	Metrics        MetricsConfig
+	Owner          OwnerConfig
+}
+
+// DBConfig selects the database driver and where to connect. Path is only
+// used by sqlite and may be ":memory:".
+type DBConfig struct {
+	Driver   string
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Name     string
+	SSLMode  string
+	Path     string
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnectTimeout  time.Duration // total time to keep retrying at startup
+	AutoMigrate     bool          // also AutoMigrate the models; never in production
+}
+
+// LockoutConfig controls account lockout after repeated failed logins.
+type LockoutConfig struct {
+	MaxAttempts int           // consecutive failures before locking
+	Window      time.Duration // failures older than this start a new count
+	Duration    time.Duration // how long the account stays locked
+}
+
+// ForecastConfig holds the workload forecast defaults.
+type ForecastConfig struct {
+	DailyCapacity int // estimated minutes per employee per day
+	HorizonDays   int
+}
+
+// AutoAssignConfig controls how tasks created without an assignee are
+// distributed among employees.
+type AutoAssignConfig struct {
+	Strategy string // round_robin, least_loaded, or empty to disable
+	Pool     []int  // employee ids to pick from; empty means every employee
+	Always   bool   // assign every unassigned task, not only those asking for it
+}
+
+// UploadConfig limits task attachments and says where they are stored.
+type UploadConfig struct {
+	Dir          string
+	MaxSize      int64    // bytes
+	AllowedTypes []string // sniffed MIME types
+	EvidenceMax  int64    // bytes, for task submission photos
+}
+
+// AvatarConfig says where profile pictures are stored and how big they may
+// be. Uploads are cropped square and scaled down to Size pixels.
+type AvatarConfig struct {
+	Dir     string
+	MaxSize int64 // bytes, of the uploaded file
+	Size    int   // pixels, width and height of the stored image
+}
+
+// RateLimitConfig holds the per client IP limits of the unauthenticated
+// account endpoints.
+type RateLimitConfig struct {
+	Login          ratelimit.Rate
+	CreateAccount  ratelimit.Rate
+	ForgotPassword ratelimit.Rate
+	ResendVerify   ratelimit.Rate
+}
+
+// OwnerConfig is the bootstrap Admin created on first start. It must
+// change its password before it can do anything else.
+type OwnerConfig struct {
+	Email    string
+	Password string
+}
+
+// MetricsConfig controls where GET /metrics is served. With Addr set it
+// gets its own listener, e.g. ":9090", reachable only inside the cluster;
+// otherwise it is on the main router, requiring "Bearer <Token>". With
+// neither it is not served.
+type MetricsConfig struct {
+	Addr  string
+	Token string
+}
+
+// BulkConfig limits bulk task operations.
+type BulkConfig struct {
+	MaxIds     int     // ids accepted in one request
+	MaxFailure float64 // fraction of skipped ids above which nothing is applied
+}
+
+// ReminderConfig drives the due date reminders. Every Interval the
+// assignees of open tasks due within DueSoon, and of tasks that became
+// overdue less than OverdueWindow ago, are reminded once. Interval 0 turns
+// the scheduler off; POST /admin/reminders/run still works.
+type ReminderConfig struct {
+	Interval      time.Duration
+	DueSoon       time.Duration
+	OverdueWindow time.Duration
+	BatchSize     int // tasks loaded per query
+}
+
+// SMTPConfig holds the outgoing mail settings; an empty Host disables SMTP.
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	TLS      bool // implicit TLS, usually port 465
+}
+
+// Load reads the configuration from environment variables, falling back to
+// defaults suitable for local development. Malformed values and a missing
+// JWT_SECRET are reported together in the returned error.
+func Load() (Config, error) {
+	env := &envReader{}
+
+	cfg := Config{
+		Env: env.str("APP_ENV", "development"),
+		DB: DBConfig{
+			Driver:   env.str("DB_DRIVER", "mysql"),
+			Host:     env.str("DB_HOST", "localhost"),
+			User:     env.str("DB_USER", "root"),
+			Password: env.str("DB_PASSWORD", ""),
+			Name:     env.str("DB_NAME", "tusk"),
+			SSLMode:  env.str("DB_SSLMODE", "disable"),
+			Path:     env.str("DB_PATH", "tusk.db"),
+
+			MaxOpenConns:    env.int("DB_MAX_OPEN_CONNS", 25),
+			MaxIdleConns:    env.int("DB_MAX_IDLE_CONNS", 10),
+			ConnMaxLifetime: env.duration("DB_CONN_MAX_LIFETIME", 30*time.Minute),
+			ConnectTimeout:  env.duration("DB_CONNECT_TIMEOUT", 30*time.Second),
+			AutoMigrate:     env.bool("DB_AUTO_MIGRATE", false),
+		},
+		ServerHost:      env.str("SERVER_HOST", ""),
+		ServerPort:      env.int("SERVER_PORT", 8080),
+		ShutdownTimeout: env.duration("SHUTDOWN_TIMEOUT", 15*time.Second),
+
+		JWTSecret:     env.required("JWT_SECRET"),
+		TokenExpiry:   env.duration("JWT_EXPIRY", 15*time.Minute),
+		RefreshExpiry: env.duration("REFRESH_TOKEN_EXPIRY", 30*24*time.Hour),
+		BcryptCost:    env.int("BCRYPT_COST", bcrypt.DefaultCost),
+
+		QueryTimeout: env.duration("DB_QUERY_TIMEOUT", 5*time.Second),
+		CacheTTL:     env.duration("CACHE_TTL", 30*time.Second),
+		Lockout: LockoutConfig{
+			MaxAttempts: env.int("LOGIN_MAX_ATTEMPTS", 5),
+			Window:      env.duration("LOGIN_ATTEMPT_WINDOW", 15*time.Minute),
+			Duration:    env.duration("LOGIN_LOCKOUT_DURATION", 15*time.Minute),
+		},
+		Forecast: ForecastConfig{
+			DailyCapacity: env.int("FORECAST_DAILY_CAPACITY", 480),
+			HorizonDays:   env.int("FORECAST_HORIZON_DAYS", 14),
+		},
+		AutoAssign: AutoAssignConfig{
+			Strategy: env.str("AUTO_ASSIGN_STRATEGY", ""),
+			Pool:     env.intList("AUTO_ASSIGN_POOL"),
+			Always:   env.bool("AUTO_ASSIGN_ALWAYS", false),
+		},
+		SMTP: SMTPConfig{
+			Host:     env.str("SMTP_HOST", ""),
+			Port:     env.int("SMTP_PORT", 587),
+			Username: env.str("SMTP_USER", ""),
+			Password: env.str("SMTP_PASSWORD", ""),
+			From:     env.str("SMTP_FROM", ""),
+			TLS:      env.bool("SMTP_TLS", false),
+		},
+		ResetURL:       env.str("RESET_PASSWORD_URL", "http://localhost:8080/reset-password"),
+		VerifyURL:      env.str("VERIFY_EMAIL_URL", "http://localhost:8080/api/v1/auth/verify"),
+		FCMCredentials: env.str("FCM_CREDENTIALS_FILE", ""),
+		LogSkipPaths:   env.list("LOG_SKIP_PATHS", []string{"/healthz", "/readyz"}),
+		Uploads: UploadConfig{
+			Dir:     env.str("UPLOAD_DIR", "./uploads"),
+			MaxSize: int64(env.int("UPLOAD_MAX_SIZE", 10<<20)),
+			AllowedTypes: env.list("UPLOAD_ALLOWED_TYPES", []string{
+				"image/jpeg", "image/png", "image/gif", "image/webp",
+				"application/pdf", "application/zip",
+			}),
+			EvidenceMax: int64(env.int("EVIDENCE_MAX_SIZE", 5<<20)),
+		},
+		Avatars: AvatarConfig{
+			Dir:     env.str("AVATAR_DIR", "./avatars"),
+			MaxSize: int64(env.int("AVATAR_MAX_SIZE", 2<<20)),
+			Size:    env.int("AVATAR_SIZE", 256),
+		},
+		RateLimit: RateLimitConfig{
+			Login:          env.rate("RATE_LIMIT_LOGIN", "10/1m"),
+			CreateAccount:  env.rate("RATE_LIMIT_CREATE_ACCOUNT", "20/1h"),
+			ForgotPassword: env.rate("RATE_LIMIT_FORGOT_PASSWORD", "5/1h"),
+			ResendVerify:   env.rate("RATE_LIMIT_RESEND_VERIFICATION", "5/1h"),
+		},
+		TrustedProxies: env.list("TRUSTED_PROXIES", nil),
+		CORS: middlewares.CORSConfig{
+			AllowedOrigins: env.list("CORS_ALLOWED_ORIGINS", nil),
+			AllowedMethods: env.list("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "PATCH", "DELETE"}),
+			AllowedHeaders: env.list("CORS_ALLOWED_HEADERS", []string{"Authorization", "Content-Type", "Idempotency-Key", "X-Request-ID"}),
+			ExposedHeaders: env.list("CORS_EXPOSED_HEADERS", []string{
+				"Content-Disposition", "Deprecation", "Link", "Retry-After", "Idempotency-Replayed", "X-Request-ID",
+			}),
+			AllowCredentials: env.bool("CORS_ALLOW_CREDENTIALS", false),
+			MaxAge:           env.duration("CORS_MAX_AGE", 10*time.Minute),
+		},
+		Bulk: BulkConfig{
+			MaxIds:     env.int("BULK_MAX_IDS", 100),
+			MaxFailure: env.fraction("BULK_MAX_FAILURE", 0.5),
+		},
+		Reminders: ReminderConfig{
+			Interval:      env.optionalDuration("REMINDER_INTERVAL", 15*time.Minute),
+			DueSoon:       env.duration("REMINDER_DUE_SOON", 24*time.Hour),
+			OverdueWindow: env.duration("REMINDER_OVERDUE_WINDOW", 24*time.Hour),
+			BatchSize:     env.int("REMINDER_BATCH_SIZE", 100),
+		},
+		Owner: OwnerConfig{
+			Email:    strings.ToLower(strings.TrimSpace(env.str("OWNER_EMAIL", ""))),
+			Password: env.str("OWNER_PASSWORD", ""),
+		},
+		Metrics: MetricsConfig{
+			Addr:  env.str("METRICS_ADDR", ""),
+			Token: env.str("METRICS_TOKEN", ""),
+		},
+	}
+
+	switch cfg.DB.Driver {
+	case "mysql":
+		cfg.DB.Port = env.int("DB_PORT", 3306)
+	case "postgres":
+		cfg.DB.Port = env.int("DB_PORT", 5432)
+	case "sqlite":
+	default:
+		env.fail("DB_DRIVER must be mysql, postgres or sqlite, got %q", cfg.DB.Driver)
+	}
+	// development falls back to the old well-known owner, production must
+	// set its own
+	if cfg.IsProduction() {
+		if cfg.Owner.Email == "" || cfg.Owner.Password == "" {
+			env.fail("OWNER_EMAIL and OWNER_PASSWORD are required with APP_ENV=production")
+		} else if violation := passwordpolicy.Check(cfg.Owner.Password, cfg.Owner.Email); violation != nil {
+			env.fail("OWNER_PASSWORD is too weak: %s", violation.Error())
+		}
+	}
+	if cfg.Owner.Email == "" {
+		cfg.Owner.Email = "owner@go.id"
+	}
+	if cfg.Owner.Password == "" {
+		cfg.Owner.Password = "123456"
+	}
+	if cfg.DB.AutoMigrate && cfg.IsProduction() {
+		env.fail("DB_AUTO_MIGRATE can't be used with APP_ENV=production")
+	}
+	// browsers refuse a wildcard origin on credentialed requests
+	for _, origin := range cfg.CORS.AllowedOrigins {
+		if origin == "*" && cfg.CORS.AllowCredentials {
+			env.fail("CORS_ALLOWED_ORIGINS can't be * with CORS_ALLOW_CREDENTIALS=true, list the origins")
+		}
+	}
+	if cfg.Avatars.Size < 16 || cfg.Avatars.Size > 2048 {
+		env.fail("AVATAR_SIZE must be between 16 and 2048")
+	}
+	if cfg.Reminders.BatchSize < 1 {
+		env.fail("REMINDER_BATCH_SIZE must be at least 1")
+	}
+	if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+		env.fail("BCRYPT_COST must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	if s := cfg.AutoAssign.Strategy; s != "" && s != "round_robin" && s != "least_loaded" {
+		env.fail("AUTO_ASSIGN_STRATEGY must be round_robin or least_loaded")
+	}
+
+	return cfg, errors.Join(env.errs...)
+}
+
+// IsProduction reports whether APP_ENV is production.
+func (c Config) IsProduction() bool {
+	return c.Env == "production"
+}
+
+// DSN is the connection string in the format expected by the driver.
+func (c DBConfig) DSN() string {
+	switch c.Driver {
+	case "postgres":
+		return fmt.Sprintf(
+			"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s TimeZone=UTC",
+			c.Host, c.Port, c.User, c.Password, c.Name, c.SSLMode,
+		)
+	case "sqlite":
+		if c.Path == ":memory:" {
+			// shared cache so every pooled connection sees the same database
+			return "file::memory:?cache=shared"
+		}
+		return c.Path
+	default:
+		return fmt.Sprintf(
+			"%s:%s@tcp(%s:%d)/%s?charset=utf8&parseTime=True&loc=UTC",
+			c.User, c.Password, c.Host, c.Port, c.Name,
+		)
+	}
+}
+
+// Addr is the host:port the HTTP server listens on.
+func (c Config) Addr() string {
+	return fmt.Sprintf("%s:%d", c.ServerHost, c.ServerPort)
+}
+
+// envReader collects parse errors so Load can report all of them at once.
+type envReader struct {
+	errs []error
+}
+
+func (e *envReader) fail(format string, args ...interface{}) {
+	e.errs = append(e.errs, fmt.Errorf(format, args...))
+}
+
+func (e *envReader) str(name, fallback string) string {
+	if value, ok := os.LookupEnv(name); ok {
+		return value
+	}
+	return fallback
+}
+
+func (e *envReader) required(name string) string {
+	value := os.Getenv(name)
+	if value == "" {
+		e.fail("%s is required", name)
+	}
+	return value
+}
+
+func (e *envReader) int(name string, fallback int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+
+	number, err := strconv.Atoi(value)
+	if err != nil || number < 0 {
+		e.fail("%s must be a non-negative integer, got %q", name, value)
+		return fallback
+	}
+	return number
+}
+
+func (e *envReader) duration(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		e.fail("%s must be a positive duration like 15m, got %q", name, value)
+		return fallback
+	}
+	return duration
+}
+
+// optionalDuration is duration for settings where 0 turns the feature off.
+func (e *envReader) optionalDuration(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		e.fail("%s must be 0 or a positive duration like 15m, got %q", name, value)
+		return fallback
+	}
+	return duration
+}
+
+func (e *envReader) fraction(name string, fallback float64) float64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 || number > 1 {
+		e.fail("%s must be a number between 0 and 1, got %q", name, value)
+		return fallback
+	}
+	return number
+}
+
+func (e *envReader) rate(name, fallback string) ratelimit.Rate {
+	value := e.str(name, fallback)
+	rate, err := ratelimit.ParseRate(value)
+	if err != nil {
+		e.fail("%s must look like 10/1m, got %q", name, value)
+		rate, _ = ratelimit.ParseRate(fallback)
+	}
+	return rate
+}
+
+func (e *envReader) bool(name string, fallback bool) bool {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		e.fail("%s must be true or false, got %q", name, value)
+		return fallback
+	}
+	return parsed
+}
+
+func (e *envReader) list(name string, fallback []string) []string {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback
+	}
+
+	values := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			values = append(values, item)
+		}
+	}
+	return values
+}
+
+func (e *envReader) intList(name string) []int {
+	values := []int{}
+	for _, value := range strings.Split(os.Getenv(name), ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+
+		number, err := strconv.Atoi(value)
+		if err != nil {
+			e.fail("%s must be a comma separated list of ids, got %q", name, value)
+			continue
+		}
+		values = append(values, number)
+	}
+	return values
+}
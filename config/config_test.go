@@ -0,0 +1,167 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestReminderDurations(t *testing.T) {
+	cases := []struct {
+		name, value string
+		interval    time.Duration
+		fails       bool
+	}{
+		{"default", "", 15 * time.Minute, false},
+		{"turned off", "0", 0, false},
+		{"set", "5m", 5 * time.Minute, false},
+		{"negative", "-1m", 0, true},
+		{"not a duration", "often", 0, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("JWT_SECRET", "test-secret")
+			t.Setenv("REMINDER_INTERVAL", tc.value)
+
+			cfg, err := Load()
+			if tc.fails {
+				if err == nil {
+					t.Errorf("REMINDER_INTERVAL=%q loaded", tc.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Reminders.Interval != tc.interval {
+				t.Errorf("interval = %s, want %s", cfg.Reminders.Interval, tc.interval)
+			}
+		})
+	}
+
+	t.Run("due soon must be positive", func(t *testing.T) {
+		t.Setenv("JWT_SECRET", "test-secret")
+		t.Setenv("REMINDER_DUE_SOON", "0")
+		if _, err := Load(); err == nil {
+			t.Error("REMINDER_DUE_SOON=0 loaded")
+		}
+	})
+}
+
+// unsetenv clears names for the test; t.Setenv restores them afterwards.
+func unsetenv(t *testing.T, names ...string) {
+	t.Helper()
+
+	for _, name := range names {
+		t.Setenv(name, "")
+		os.Unsetenv(name)
+	}
+}
+
+func TestLoadDatabase(t *testing.T) {
+	dbVars := []string{"DB_DRIVER", "DB_HOST", "DB_PORT", "DB_USER", "DB_PASSWORD", "DB_NAME", "DB_SSLMODE", "DB_PATH", "SERVER_HOST", "SERVER_PORT", "BCRYPT_COST"}
+
+	cases := []struct {
+		name string
+		env  map[string]string
+		dsn  string
+	}{
+		{
+			name: "defaults",
+			dsn:  "root:@tcp(localhost:3306)/tusk?charset=utf8&parseTime=True&loc=UTC",
+		},
+		{
+			name: "mysql",
+			env:  map[string]string{"DB_HOST": "db", "DB_PORT": "3307", "DB_USER": "app", "DB_PASSWORD": "s3cret", "DB_NAME": "tasks"},
+			dsn:  "app:s3cret@tcp(db:3307)/tasks?charset=utf8&parseTime=True&loc=UTC",
+		},
+		{
+			name: "postgres",
+			env:  map[string]string{"DB_DRIVER": "postgres", "DB_HOST": "db", "DB_USER": "app", "DB_PASSWORD": "s3cret", "DB_SSLMODE": "require"},
+			dsn:  "host=db port=5432 user=app password=s3cret dbname=tusk sslmode=require TimeZone=UTC",
+		},
+		{
+			name: "sqlite in memory",
+			env:  map[string]string{"DB_DRIVER": "sqlite", "DB_PATH": ":memory:"},
+			dsn:  "file::memory:?cache=shared",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			unsetenv(t, dbVars...)
+			t.Setenv("JWT_SECRET", "test-secret")
+			for name, value := range tc.env {
+				t.Setenv(name, value)
+			}
+
+			cfg, err := Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := cfg.DB.DSN(); got != tc.dsn {
+				t.Errorf("DSN = %q, want %q", got, tc.dsn)
+			}
+		})
+	}
+
+	t.Run("server and bcrypt defaults", func(t *testing.T) {
+		unsetenv(t, dbVars...)
+		t.Setenv("JWT_SECRET", "test-secret")
+
+		cfg, err := Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Addr() != ":8080" || cfg.BcryptCost != bcrypt.DefaultCost || cfg.JWTSecret != "test-secret" {
+			t.Errorf("addr %q, bcrypt cost %d, secret %q", cfg.Addr(), cfg.BcryptCost, cfg.JWTSecret)
+		}
+	})
+
+	t.Run("invalid values are all reported", func(t *testing.T) {
+		unsetenv(t, append(dbVars, "JWT_SECRET")...)
+		t.Setenv("DB_PORT", "three")
+		t.Setenv("BCRYPT_COST", "99")
+
+		_, err := Load()
+		for _, name := range []string{"JWT_SECRET", "DB_PORT", "BCRYPT_COST"} {
+			if err == nil || !strings.Contains(err.Error(), name) {
+				t.Errorf("error %v doesn't mention %s", err, name)
+			}
+		}
+	})
+
+	t.Run("unknown driver", func(t *testing.T) {
+		t.Setenv("JWT_SECRET", "test-secret")
+		t.Setenv("DB_DRIVER", "oracle")
+		if _, err := Load(); err == nil || !strings.Contains(err.Error(), "DB_DRIVER") {
+			t.Errorf("error = %v, want one about DB_DRIVER", err)
+		}
+	})
+}
+
+func TestLoadCORS(t *testing.T) {
+	t.Run("wildcard with credentials is refused", func(t *testing.T) {
+		t.Setenv("JWT_SECRET", "test-secret")
+		t.Setenv("CORS_ALLOWED_ORIGINS", "https://app.example.com,*")
+		t.Setenv("CORS_ALLOW_CREDENTIALS", "true")
+		if _, err := Load(); err == nil || !strings.Contains(err.Error(), "CORS_ALLOWED_ORIGINS") {
+			t.Errorf("error = %v, want one about CORS_ALLOWED_ORIGINS", err)
+		}
+	})
+
+	t.Run("listed origins with credentials", func(t *testing.T) {
+		t.Setenv("JWT_SECRET", "test-secret")
+		t.Setenv("CORS_ALLOWED_ORIGINS", "https://app.example.com, https://*.tusk.id")
+		t.Setenv("CORS_ALLOW_CREDENTIALS", "true")
+		cfg, err := Load()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(cfg.CORS.AllowedOrigins) != 2 || cfg.CORS.AllowedOrigins[1] != "https://*.tusk.id" || !cfg.CORS.AllowCredentials {
+			t.Errorf("CORS = %+v", cfg.CORS)
+		}
+	})
+}
@@ -1,64 +1,161 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"log"
+	"time"
+	"tusk/migrations"
 	"tusk/models"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
-const (
-	host     = "localhost"
-	port     = 3306
-	user     = "root"
-	password = ""
-	dbName   = "tusk"
-)
+// Dialector returns the GORM dialector for the configured driver.
+func Dialector(cfg DBConfig) gorm.Dialector {
+	switch cfg.Driver {
+	case "postgres":
+		return postgres.Open(cfg.DSN())
+	case "sqlite":
+		return sqlite.Open(cfg.DSN())
+	default:
+		return mysql.Open(cfg.DSN())
+	}
+}
+
+// RetryFunc is called before each new connection attempt.
+type RetryFunc func(attempt int, wait time.Duration, err error)
+
+// DatabaseConnection opens and pings the database, retrying with
+// exponential backoff until cfg.DB.ConnectTimeout has passed so the app can
+// start before the database is ready.
+func DatabaseConnection(cfg Config, onRetry RetryFunc) (*gorm.DB, error) {
+	deadline := time.Now().Add(cfg.DB.ConnectTimeout)
+	wait := 500 * time.Millisecond
+
+	for attempt := 1; ; attempt++ {
+		database, err := connect(cfg.DB)
+		if err == nil {
+			return database, nil
+		}
+
+		if time.Now().Add(wait).After(deadline) {
+			return nil, fmt.Errorf("database unreachable after %d attempts: %w", attempt, err)
+		}
 
-func DatabaseConnection() *gorm.DB {
-	dsn := fmt.Sprintf(
-		"%s:%s@tcp(%s:%d)/%s?charset=utf8&parseTime=True&loc=Local",
-		user, password, host, port, dbName,
-	)
+		if onRetry != nil {
+			onRetry(attempt, wait, err)
+		}
+		time.Sleep(wait)
 
-	database, err := gorm.Open(mysql.Open(dsn), &gorm.Config{})
+		wait *= 2
+		if wait > 10*time.Second {
+			wait = 10 * time.Second
+		}
+	}
+}
+
+func connect(cfg DBConfig) (*gorm.DB, error) {
+	// TranslateError maps driver specific duplicate-key errors to gorm.ErrDuplicatedKey
+	database, err := gorm.Open(Dialector(cfg), &gorm.Config{
+		TranslateError: true,
+		// timestamps are stored in UTC whatever zone the server runs in
+		NowFunc: func() time.Time { return time.Now().UTC() },
+	})
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
-	return database
+	sqlDB, err := database.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := sqlDB.Ping(); err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
+	return database, nil
 }
 
-// ✅ Tambahkan function migration
-func RunMigrations(db *gorm.DB) {
-	err := db.AutoMigrate(
-		&models.User{},
-		&models.Task{},
-	)
+// RunMigrations applies the pending versioned migrations and stops the
+// app when the database is ahead of this binary. With DB_AUTO_MIGRATE the
+// current models are also auto-migrated, for trying out model changes in
+// development before writing their migration.
+func RunMigrations(db *gorm.DB, cfg Config) {
+	if _, err := migrations.Up(db, log.Printf); err != nil {
+		if errors.Is(err, migrations.ErrDatabaseAhead) {
+			log.Fatal("❌ Refusing to start: ", err)
+		}
+		log.Fatal("❌ Migration failed: ", err)
+	}
 
-	if err != nil {
-		log.Fatal("❌ Migration failed:", err)
+	if cfg.DB.AutoMigrate {
+		err := db.AutoMigrate(
+			&models.Department{},
+			&models.User{},
+			&models.Task{},
+			&models.RefreshToken{},
+			&models.PasswordReset{},
+			&models.EmailVerification{},
+			&models.IdempotencyKey{},
+			&models.Attachment{},
+			&models.TaskSubmission{},
+			&models.Comment{},
+			&models.Tag{},
+			&models.Subtask{},
+			&models.TaskActivity{},
+			&models.TaskRequest{},
+			&models.TaskReminder{},
+		)
+		if err != nil {
+			log.Fatal("❌ Auto migration failed:", err)
+		}
+		log.Println("⚠️ DB_AUTO_MIGRATE is on, models were auto-migrated")
 	}
 
 	log.Println("✅ Database migrated successfully!")
 }
 
-func CreateOwnerAccount(db *gorm.DB) {
-	hashedPasswordBytes, _ := bcrypt.GenerateFromPassword([]byte("123456"), bcrypt.DefaultCost)
-	owner := models.User{
-		Role:     "Admin",
-		Name:     "Owner",
-		Password: string(hashedPasswordBytes),
-		Email:    "owner@go.id",
+// CreateOwnerAccount creates the bootstrap Admin from cfg.Owner unless an
+// account with that email exists, deleted ones included. The owner has to
+// change the configured password at first login.
+func CreateOwnerAccount(db *gorm.DB, cfg Config) error {
+	var existing int64
+	if err := db.Unscoped().Model(&models.User{}).Where("LOWER(email) = ?", cfg.Owner.Email).Count(&existing).Error; err != nil {
+		return err
 	}
-
-	if db.Where("email=?", owner.Email).First(&owner).RowsAffected == 0 {
-		db.Create(&owner)
-		log.Println("✅ Owner account created!")
-	} else {
+	if existing > 0 {
 		log.Println("ℹ️ Owner account already exists")
+		return nil
 	}
+
+	hashedPasswordBytes, err := bcrypt.GenerateFromPassword([]byte(cfg.Owner.Password), cfg.BcryptCost)
+	if err != nil {
+		return err
+	}
+	owner := models.User{
+		Role:               models.RoleAdmin,
+		Name:               "Owner",
+		Password:           string(hashedPasswordBytes),
+		Email:              cfg.Owner.Email,
+		MustChangePassword: true,
+		IsActive:           true,
+		EmailVerified:      true,
+	}
+	if err := db.Create(&owner).Error; err != nil {
+		return err
+	}
+
+	log.Println("✅ Owner account created!", owner.Email)
+	return nil
 }
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// closedPort returns a local port nothing listens on.
+func closedPort(t *testing.T) int {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+	return port
+}
+
+func TestDatabaseConnectionGivesUp(t *testing.T) {
+	cfg := Config{DB: DBConfig{
+		Driver:         "mysql",
+		Host:           "127.0.0.1",
+		Port:           closedPort(t),
+		User:           "root",
+		Name:           "tusk",
+		ConnectTimeout: 2 * time.Second,
+	}}
+
+	waits := []time.Duration{}
+	started := time.Now()
+	db, err := DatabaseConnection(cfg, func(attempt int, wait time.Duration, err error) {
+		if attempt != len(waits)+1 || err == nil {
+			t.Errorf("retry %d after %v", attempt, err)
+		}
+		waits = append(waits, wait)
+	})
+	elapsed := time.Since(started)
+
+	if db != nil || err == nil {
+		t.Fatalf("connected to a closed port: %v", err)
+	}
+	var netErr *net.OpError
+	if !strings.Contains(err.Error(), "database unreachable after") || !errors.As(err, &netErr) {
+		t.Errorf("error = %v, want the dial error wrapped", err)
+	}
+	if len(waits) != 2 || waits[0] != 500*time.Millisecond || waits[1] != time.Second {
+		t.Errorf("waited %v, want 500ms then 1s", waits)
+	}
+	if elapsed > 5*time.Second {
+		t.Errorf("gave up after %s, want about ConnectTimeout", elapsed)
+	}
+}
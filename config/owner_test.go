@@ -0,0 +1,160 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"tusk/migrations"
+	"tusk/models"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// migratedDB is a fresh in-memory database with the current schema.
+func migratedDB(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file:"+strings.ReplaceAll(t.Name(), "/", "_")+"?mode=memory&cache=shared"), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := db.DB()
+	t.Cleanup(func() { sqlDB.Close() })
+	if _, err := migrations.Up(db, func(string, ...interface{}) {}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func ownerConfig() Config {
+	return Config{
+		BcryptCost: bcrypt.MinCost,
+		Owner:      OwnerConfig{Email: "owner@tusk.id", Password: "kopi-susu-9"},
+	}
+}
+
+func TestCreateOwnerAccount(t *testing.T) {
+	t.Run("fresh database", func(t *testing.T) {
+		db := migratedDB(t)
+		if err := CreateOwnerAccount(db, ownerConfig()); err != nil {
+			t.Fatal(err)
+		}
+
+		owner := models.User{}
+		if err := db.Where("email = ?", "owner@tusk.id").First(&owner).Error; err != nil {
+			t.Fatal(err)
+		}
+		if owner.Role != models.RoleAdmin || !owner.MustChangePassword || !owner.IsActive || !owner.EmailVerified {
+			t.Errorf("owner = %+v, want an active Admin who must change the password", owner)
+		}
+		if bcrypt.CompareHashAndPassword([]byte(owner.Password), []byte("kopi-susu-9")) != nil {
+			t.Error("the owner's hash doesn't match OWNER_PASSWORD")
+		}
+	})
+
+	t.Run("already seeded", func(t *testing.T) {
+		db := migratedDB(t)
+		if err := CreateOwnerAccount(db, ownerConfig()); err != nil {
+			t.Fatal(err)
+		}
+		// the owner has since changed the password
+		db.Model(&models.User{}).Where("email = ?", "owner@tusk.id").Updates(map[string]interface{}{"password": "changed-hash", "must_change_password": false})
+
+		cfg := ownerConfig()
+		cfg.Owner.Password = "teh-manis-7"
+		if err := CreateOwnerAccount(db, cfg); err != nil {
+			t.Fatal(err)
+		}
+		owners := []models.User{}
+		db.Where("email = ?", "owner@tusk.id").Find(&owners)
+		if len(owners) != 1 || owners[0].Password != "changed-hash" || owners[0].MustChangePassword {
+			t.Errorf("owners = %+v, want the existing account untouched", owners)
+		}
+	})
+
+	t.Run("email in another case", func(t *testing.T) {
+		db := migratedDB(t)
+		db.Create(&models.User{Role: models.RoleAdmin, Name: "Owner", Email: "Owner@Tusk.id", Password: "hash"})
+		if err := CreateOwnerAccount(db, ownerConfig()); err != nil {
+			t.Fatal(err)
+		}
+		var n int64
+		db.Model(&models.User{}).Count(&n)
+		if n != 1 {
+			t.Errorf("%d users, want the existing owner reused", n)
+		}
+	})
+
+	t.Run("deleted owner isn't recreated", func(t *testing.T) {
+		db := migratedDB(t)
+		if err := CreateOwnerAccount(db, ownerConfig()); err != nil {
+			t.Fatal(err)
+		}
+		db.Where("email = ?", "owner@tusk.id").Delete(&models.User{})
+		if err := CreateOwnerAccount(db, ownerConfig()); err != nil {
+			t.Fatal(err)
+		}
+		var n int64
+		db.Unscoped().Model(&models.User{}).Count(&n)
+		if n != 1 {
+			t.Errorf("%d users, want the deleted owner kept as the only one", n)
+		}
+	})
+
+	t.Run("errors are returned", func(t *testing.T) {
+		cfg := ownerConfig()
+		cfg.BcryptCost = bcrypt.MaxCost + 1
+		if err := CreateOwnerAccount(migratedDB(t), cfg); err == nil {
+			t.Error("an invalid bcrypt cost was ignored")
+		}
+
+		db := migratedDB(t)
+		db.Exec("DROP TABLE users")
+		if err := CreateOwnerAccount(db, ownerConfig()); err == nil {
+			t.Error("a missing users table was ignored")
+		}
+	})
+}
+
+func TestLoadOwner(t *testing.T) {
+	cases := []struct {
+		name     string
+		env      map[string]string
+		email    string
+		password string
+		fails    string // part of the error, "" when it loads
+	}{
+		{"development default", nil, "owner@go.id", "123456", ""},
+		{"from the environment", map[string]string{"OWNER_EMAIL": " Owner@Tusk.id ", "OWNER_PASSWORD": "kopi-susu-9"}, "owner@tusk.id", "kopi-susu-9", ""},
+		{"production needs them", map[string]string{"APP_ENV": "production"}, "", "", "OWNER_EMAIL and OWNER_PASSWORD are required"},
+		{"production rejects weak ones", map[string]string{"APP_ENV": "production", "OWNER_EMAIL": "owner@tusk.id", "OWNER_PASSWORD": "password123"}, "", "", "OWNER_PASSWORD is too weak"},
+		{"production with the email as password", map[string]string{"APP_ENV": "production", "OWNER_EMAIL": "pemilik-tusk@tusk.id", "OWNER_PASSWORD": "Pemilik-Tusk"}, "", "", "OWNER_PASSWORD is too weak"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			unsetenv(t, "APP_ENV", "OWNER_EMAIL", "OWNER_PASSWORD")
+			t.Setenv("JWT_SECRET", "test-secret")
+			for name, value := range tc.env {
+				t.Setenv(name, value)
+			}
+
+			cfg, err := Load()
+			if tc.fails != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.fails) {
+					t.Errorf("error = %v, want %q", err, tc.fails)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Owner.Email != tc.email || cfg.Owner.Password != tc.password {
+				t.Errorf("owner = %+v, want %s / %s", cfg.Owner, tc.email, tc.password)
+			}
+		})
+	}
+}
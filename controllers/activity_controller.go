@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"tusk/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// ActivityController serves the task history written by package activity.
+type ActivityController struct {
+	DB *gorm.DB
+}
+
+type ActivityActor struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+type ActivityResponse struct {
+	Id        int             `json:"id"`
+	TaskId    int             `json:"taskId"`
+	Action    string          `json:"action"`
+	OldValue  json.RawMessage `json:"oldValue"`
+	NewValue  json.RawMessage `json:"newValue"`
+	CreatedAt string          `json:"createdAt"`
+	Actor     *ActivityActor  `json:"actor"`
+}
+
+func newActivityResponse(activity models.TaskActivity) ActivityResponse {
+	response := ActivityResponse{
+		Id:        activity.Id,
+		TaskId:    activity.TaskId,
+		Action:    activity.Action,
+		OldValue:  rawJSON(activity.OldValue),
+		NewValue:  rawJSON(activity.NewValue),
+		CreatedAt: formatTime(activity.CreatedAt),
+	}
+	if activity.ActorId != nil {
+		response.Actor = &ActivityActor{
+			Id:   activity.Actor.Id,
+			Name: activity.Actor.Name,
+			Role: activity.Actor.Role,
+		}
+	}
+	return response
+}
+
+func rawJSON(value string) json.RawMessage {
+	if value == "" {
+		return json.RawMessage("{}")
+	}
+	return json.RawMessage(value)
+}
+
+// List returns a task's history oldest first.
+func (ac *ActivityController) List(c *gin.Context) {
+	task := models.Task{}
+	if err := ac.DB.WithContext(c.Request.Context()).First(&task, c.Param("id")).Error; err != nil {
+		respondDBError(c, err, http.StatusNotFound, "not found")
+		return
+	}
+	if c.GetString("role") != models.RoleAdmin && task.UserId != c.GetInt("userId") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the assignee or an Admin can see this history"})
+		return
+	}
+
+	page, errPage := strconv.Atoi(c.Query("page"))
+	if errPage != nil || page < 1 {
+		page = 1
+	}
+	limit, errLimit := strconv.Atoi(c.Query("limit"))
+	if errLimit != nil || limit < 1 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	query := ac.DB.WithContext(c.Request.Context()).Model(&models.TaskActivity{}).Where("task_id=?", task.Id)
+
+	var total int64
+	if errDB := query.Count(&total).Error; errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	activities := []models.TaskActivity{}
+	errDB := query.Preload("Actor", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).
+		Order("created_at ASC, id ASC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&activities).Error
+	if errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	responses := make([]ActivityResponse, 0, len(activities))
+	for _, activity := range activities {
+		responses = append(responses, newActivityResponse(activity))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       responses,
+		"page":       page,
+		"limit":      limit,
+		"total":      total,
+		"totalPages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
@@ -0,0 +1,87 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+	"tusk/config"
+	"tusk/middlewares"
+	"tusk/models"
+	"tusk/reminders"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AdminController struct {
+	DB        *gorm.DB
+	Stats     *middlewares.RequestStats
+	StartedAt time.Time
+	Reminders *reminders.Worker
+}
+
+// RunReminders makes a reminder pass now instead of waiting for the
+// scheduler. Reminders already sent are not sent again.
+func (a *AdminController) RunReminders(c *gin.Context) {
+	result, errRun := a.Reminders.Run(c.Request.Context())
+	if errRun != nil {
+		respondDBError(c, errRun, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (a *AdminController) MetricsSnapshot(c *gin.Context) {
+	db := a.DB.WithContext(c.Request.Context())
+
+	var totalRequests, totalErrors int64
+	routes := a.Stats.Snapshot()
+	for _, route := range routes {
+		totalRequests += route.Requests
+		totalErrors += route.Errors
+	}
+
+	errorRate := 0.0
+	if totalRequests > 0 {
+		errorRate = float64(totalErrors) / float64(totalRequests)
+	}
+
+	var users, tasks int64
+	if errDB := db.Model(&models.User{}).Count(&users).Error; errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+	if errDB := db.Model(&models.Task{}).Count(&tasks).Error; errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	pool := gin.H{}
+	if sqlDB, err := a.DB.DB(); err == nil {
+		stats := sqlDB.Stats()
+		pool = gin.H{
+			"maxOpen": stats.MaxOpenConnections,
+			"open":    stats.OpenConnections,
+			"inUse":   stats.InUse,
+			"idle":    stats.Idle,
+			"waits":   stats.WaitCount,
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"timestamp":     time.Now().UTC().Format(time.RFC3339),
+		"version":       config.Version,
+		"uptimeSeconds": int64(time.Since(a.StartedAt).Seconds()),
+		"requests": gin.H{
+			"total":     totalRequests,
+			"errors":    totalErrors,
+			"errorRate": errorRate,
+			"routes":    routes,
+		},
+		"dbPool": pool,
+		"totals": gin.H{
+			"users": users,
+			"tasks": tasks,
+		},
+	})
+}
@@ -0,0 +1,220 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"tusk/config"
+	"tusk/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AttachmentController struct {
+	DB      *gorm.DB
+	Uploads config.UploadConfig
+}
+
+func (a *AttachmentController) Upload(c *gin.Context) {
+	task := models.Task{}
+	if err := a.DB.WithContext(c.Request.Context()).First(&task, c.Param("id")).Error; err != nil {
+		respondDBError(c, err, http.StatusNotFound, "not found")
+		return
+	}
+
+	userId := c.GetInt("userId")
+	if c.GetString("role") != models.RoleAdmin && task.UserId != userId {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the assignee or an Admin can upload attachments"})
+		return
+	}
+
+	file, mime, ok := a.receive(c, "file")
+	if !ok {
+		return
+	}
+
+	attachment, errStore := a.store(c, a.DB.WithContext(c.Request.Context()), task, userId, file, mime)
+	if errStore != nil {
+		respondDBError(c, errStore, http.StatusInternalServerError, "Attachment could not be saved")
+		return
+	}
+
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// receive reads the uploaded file in field and checks its size and sniffed
+// type, answering the client itself when they don't pass.
+func (a *AttachmentController) receive(c *gin.Context, field string) (*multipart.FileHeader, string, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, a.Uploads.MaxSize+1<<20)
+	file, errFile := c.FormFile(field)
+	if errFile != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": field + " is required"})
+		return nil, "", false
+	}
+	if file.Size > a.Uploads.MaxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": field + " exceeds the maximum size of " + strconv.FormatInt(a.Uploads.MaxSize, 10) + " bytes"})
+		return nil, "", false
+	}
+
+	mime, errSniff := sniffContentType(file.Open)
+	if errSniff != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errSniff.Error()})
+		return nil, "", false
+	}
+	if !a.allowed(mime) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "file type " + mime + " is not allowed"})
+		return nil, "", false
+	}
+	return file, mime, true
+}
+
+// store saves file under a random name in the upload directory and records
+// it with db, which may be a transaction. The client's file name is only
+// kept for display. The file is removed again when the record can't be
+// written.
+func (a *AttachmentController) store(c *gin.Context, db *gorm.DB, task models.Task, userId int, file *multipart.FileHeader, mime string) (models.Attachment, error) {
+	name, err := randomToken()
+	if err != nil {
+		return models.Attachment{}, err
+	}
+	name += strings.ToLower(filepath.Ext(file.Filename))
+
+	if err := os.MkdirAll(a.Uploads.Dir, 0o755); err != nil {
+		return models.Attachment{}, err
+	}
+	if err := c.SaveUploadedFile(file, filepath.Join(a.Uploads.Dir, name)); err != nil {
+		return models.Attachment{}, err
+	}
+
+	attachment := models.Attachment{
+		TaskId:     task.Id,
+		UploaderId: userId,
+		FileName:   filepath.Base(file.Filename),
+		Path:       name,
+		Size:       file.Size,
+		Mime:       mime,
+	}
+	if err := db.Create(&attachment).Error; err != nil {
+		os.Remove(filepath.Join(a.Uploads.Dir, name))
+		return models.Attachment{}, err
+	}
+	return attachment, nil
+}
+
+func (a *AttachmentController) List(c *gin.Context) {
+	if err := a.DB.WithContext(c.Request.Context()).First(&models.Task{}, c.Param("id")).Error; err != nil {
+		respondDBError(c, err, http.StatusNotFound, "not found")
+		return
+	}
+
+	attachments := []models.Attachment{}
+	errDB := a.DB.WithContext(c.Request.Context()).
+		Where("task_id=?", c.Param("id")).
+		Order("created_at ASC").
+		Find(&attachments).Error
+	if errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	c.JSON(http.StatusOK, attachments)
+}
+
+func (a *AttachmentController) Download(c *gin.Context) {
+	attachment := models.Attachment{}
+	if err := a.DB.WithContext(c.Request.Context()).First(&attachment, c.Param("id")).Error; err != nil {
+		respondDBError(c, err, http.StatusNotFound, "not found")
+		return
+	}
+
+	c.Header("Content-Type", attachment.Mime)
+	c.FileAttachment(filepath.Join(a.Uploads.Dir, attachment.Path), attachment.FileName)
+}
+
+func (a *AttachmentController) Delete(c *gin.Context) {
+	attachment := models.Attachment{}
+	if err := a.DB.WithContext(c.Request.Context()).First(&attachment, c.Param("id")).Error; err != nil {
+		respondDBError(c, err, http.StatusNotFound, "not found")
+		return
+	}
+
+	if c.GetString("role") != models.RoleAdmin && attachment.UploaderId != c.GetInt("userId") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the uploader or an Admin can delete this attachment"})
+		return
+	}
+
+	if errDB := a.DB.WithContext(c.Request.Context()).Delete(&attachment).Error; errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+	os.Remove(filepath.Join(a.Uploads.Dir, attachment.Path))
+
+	c.JSON(http.StatusOK, "Deleted")
+}
+
+// Serve handles GET /attachments/*path. Legacy submission files live under
+// the same prefix as /attachments/:id/download and gin can't register a
+// wildcard next to a parameter, so both are dispatched from here; only the
+// download goes through auth.
+func (a *AttachmentController) Serve(legacyDir string, auth gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parts := strings.Split(strings.Trim(c.Param("path"), "/"), "/")
+		if len(parts) == 2 && parts[1] == "download" {
+			if _, err := strconv.Atoi(parts[0]); err == nil {
+				auth(c)
+				if c.IsAborted() {
+					return
+				}
+				c.Params = append(c.Params, gin.Param{Key: "id", Value: parts[0]})
+				a.Download(c)
+				return
+			}
+		}
+
+		c.FileFromFS(c.Param("path"), http.Dir(legacyDir))
+	}
+}
+
+func (a *AttachmentController) allowed(mime string) bool {
+	for _, allowed := range a.Uploads.AllowedTypes {
+		if mime == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// removeFiles deletes uploaded files by their stored path.
+func removeFiles(dir string, paths []string) {
+	for _, path := range paths {
+		os.Remove(filepath.Join(dir, path))
+	}
+}
+
+// sniffContentType detects the MIME type from the file content rather than
+// trusting the client supplied header.
+func sniffContentType(open func() (multipart.File, error)) (string, error) {
+	file, err := open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	mime := http.DetectContentType(head[:n])
+	if i := strings.Index(mime, ";"); i >= 0 {
+		mime = mime[:i]
+	}
+	return mime, nil
+}
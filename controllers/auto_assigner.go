@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"errors"
+	"tusk/activity"
+	"tusk/config"
+	"tusk/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var errNoAssignee = errors.New("no employee available for auto-assignment")
+
+// AutoAssigner distributes tasks created without an assignee. Create runs
+// in the caller's transaction and locks the candidate employees first, so
+// concurrent creates, on any instance, pick one after the other and each
+// sees the tasks the previous one committed.
+type AutoAssigner struct {
+	Config config.AutoAssignConfig
+}
+
+// Enabled reports whether a task without an assignee should be assigned.
+func (a *AutoAssigner) Enabled(requested bool) bool {
+	return a != nil && a.Config.Strategy != "" && (requested || a.Config.Always)
+}
+
+// Create picks an employee for the task and inserts it with tx. A task
+// auto-assigned earlier with the same client token makes it fail with
+// gorm.ErrDuplicatedKey.
+func (a *AutoAssigner) Create(tx *gorm.DB, task *models.Task) error {
+	ids := []int{}
+	errDB := a.candidates(tx).Clauses(clause.Locking{Strength: "UPDATE"}).Order("users.id ASC").Pluck("users.id", &ids).Error
+	if errDB != nil {
+		return errDB
+	}
+	if len(ids) == 0 {
+		return errNoAssignee
+	}
+
+	// the retry of a create that went through while this one waited
+	if task.ClientToken != nil {
+		var existing []int
+		errDB := tx.Model(&models.Task{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("client_token = ? AND auto_assigned = ?", *task.ClientToken, true).
+			Limit(1).Pluck("id", &existing).Error
+		if errDB != nil {
+			return errDB
+		}
+		if len(existing) > 0 {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+
+	var userId int
+	if a.Config.Strategy == "least_loaded" {
+		userId, errDB = a.leastLoaded(tx)
+	} else {
+		userId, errDB = a.roundRobin(tx, ids)
+	}
+	if errDB != nil {
+		return errDB
+	}
+
+	task.UserId = userId
+	task.AutoAssigned = true
+	return tx.Set(activity.AutoAssignKey, a.Config.Strategy).Create(task).Error
+}
+
+func (a *AutoAssigner) candidates(db *gorm.DB) *gorm.DB {
+	query := db.Model(&models.User{}).Where("users.role=? AND users.is_active=?", models.RoleEmployee, true)
+	if len(a.Config.Pool) > 0 {
+		query = query.Where("users.id IN ?", a.Config.Pool)
+	}
+	return query
+}
+
+// roundRobin picks the candidate after the assignee of the last
+// auto-assigned task, starting over after the highest id.
+func (a *AutoAssigner) roundRobin(tx *gorm.DB, ids []int) (int, error) {
+	last := []int{}
+	errDB := tx.Model(&models.Task{}).Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("auto_assigned = ?", true).
+		Order("id DESC").
+		Limit(1).
+		Pluck("user_id", &last).Error
+	if errDB != nil {
+		return 0, errDB
+	}
+
+	if len(last) > 0 {
+		for _, id := range ids {
+			if id > last[0] {
+				return id, nil
+			}
+		}
+	}
+	return ids[0], nil
+}
+
+func (a *AutoAssigner) leastLoaded(tx *gorm.DB) (int, error) {
+	ids := []int{}
+	errDB := a.candidates(tx).
+		Joins("LEFT JOIN tasks ON tasks.user_id = users.id AND tasks.status<>?", models.StatusApproved).
+		Group("users.id").
+		Order("COUNT(tasks.id) ASC, users.id ASC").
+		Limit(1).
+		Pluck("users.id", &ids).Error
+	if errDB != nil {
+		return 0, errDB
+	}
+	if len(ids) == 0 {
+		return 0, errNoAssignee
+	}
+
+	return ids[0], nil
+}
@@ -0,0 +1,128 @@
+package controllers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"tusk/activity"
+	"tusk/config"
+	"tusk/controllers"
+	"tusk/models"
+	"tusk/routes"
+	"tusk/testutil"
+)
+
+func autoAssignServer(t *testing.T, strategy string) (*testutil.Server, string, []models.User) {
+	t.Helper()
+
+	s := testutil.NewServer(t, testutil.DB(t), func(cfg *config.Config) {
+		cfg.AutoAssign = config.AutoAssignConfig{Strategy: strategy}
+	})
+	admin := testutil.User(t, s.DB, models.RoleAdmin)
+	employees := []models.User{}
+	for i := 0; i < 3; i++ {
+		employees = append(employees, testutil.User(t, s.DB, models.RoleEmployee))
+	}
+	return s, testutil.Token(t, admin), employees
+}
+
+func createAutoAssigned(t *testing.T, s *testutil.Server, token string, body map[string]interface{}) controllers.TaskResponse {
+	t.Helper()
+
+	body["autoAssign"] = true
+	res := s.Do(t, http.MethodPost, routes.Prefix+"/tasks", token, body)
+	if res.Code != http.StatusCreated && res.Code != http.StatusOK {
+		t.Fatalf("status = %d\n%s", res.Code, res.Body.String())
+	}
+	task := controllers.TaskResponse{}
+	testutil.Decode(t, res, &task)
+	return task
+}
+
+func TestAutoAssignRoundRobin(t *testing.T) {
+	s, token, employees := autoAssignServer(t, "round_robin")
+
+	for i := 0; i < 4; i++ {
+		task := createAutoAssigned(t, s, token, map[string]interface{}{"title": "Intake"})
+		if want := employees[i%3].Id; task.UserId != want || !task.AutoAssigned {
+			t.Errorf("task %d went to %d, want %d", i, task.UserId, want)
+		}
+	}
+}
+
+func TestAutoAssignConcurrentCreatesAreFair(t *testing.T) {
+	s, token, employees := autoAssignServer(t, "round_robin")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 9; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			res := s.Do(t, http.MethodPost, routes.Prefix+"/tasks", token, map[string]interface{}{"title": "Intake", "autoAssign": true})
+			if res.Code != http.StatusCreated {
+				t.Errorf("status = %d\n%s", res.Code, res.Body.String())
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, employee := range employees {
+		var count int64
+		s.DB.Model(&models.Task{}).Where("user_id = ?", employee.Id).Count(&count)
+		if count != 3 {
+			t.Errorf("employee %d got %d tasks, want 3", employee.Id, count)
+		}
+	}
+}
+
+func TestAutoAssignLeastLoaded(t *testing.T) {
+	s, token, employees := autoAssignServer(t, "least_loaded")
+	createTask(t, s, employees[0].Id, models.StatusQueue)
+	createTask(t, s, employees[1].Id, models.StatusQueue)
+	createTask(t, s, employees[1].Id, models.StatusApproved) // done, so not a load
+
+	first := createAutoAssigned(t, s, token, map[string]interface{}{"title": "Intake"})
+	second := createAutoAssigned(t, s, token, map[string]interface{}{"title": "Intake"})
+	if first.UserId != employees[2].Id || second.UserId != employees[0].Id {
+		t.Errorf("assigned to %d then %d, want %d then %d", first.UserId, second.UserId, employees[2].Id, employees[0].Id)
+	}
+}
+
+func TestAutoAssignClientTokenIsIdempotent(t *testing.T) {
+	s, token, _ := autoAssignServer(t, "round_robin")
+
+	first := createAutoAssigned(t, s, token, map[string]interface{}{"title": "Intake", "clientToken": "retry-1"})
+	retry := createAutoAssigned(t, s, token, map[string]interface{}{"title": "Intake", "clientToken": "retry-1"})
+	if retry.Id != first.Id || retry.UserId != first.UserId {
+		t.Errorf("retry = task %d of %d, want task %d of %d", retry.Id, retry.UserId, first.Id, first.UserId)
+	}
+
+	var count int64
+	s.DB.Model(&models.Task{}).Count(&count)
+	if count != 1 {
+		t.Errorf("%d tasks, want 1", count)
+	}
+}
+
+func TestAutoAssignIsRecorded(t *testing.T) {
+	s, token, _ := autoAssignServer(t, "round_robin")
+	task := createAutoAssigned(t, s, token, map[string]interface{}{"title": "Intake"})
+
+	entry := models.TaskActivity{}
+	if err := s.DB.Where("task_id = ? AND action = ?", task.Id, activity.AutoAssigned).First(&entry).Error; err != nil {
+		t.Fatal(err)
+	}
+	recorded := map[string]interface{}{}
+	json.Unmarshal([]byte(entry.NewValue), &recorded)
+	if recorded["strategy"] != "round_robin" || recorded["userId"] != float64(task.UserId) {
+		t.Errorf("activity = %s, want round_robin to %d", entry.NewValue, task.UserId)
+	}
+
+	manual := createTask(t, s, task.UserId, models.StatusQueue)
+	var count int64
+	s.DB.Model(&models.TaskActivity{}).Where("task_id = ? AND action = ?", manual.Id, activity.AutoAssigned).Count(&count)
+	if count != 0 {
+		t.Error("a task with an assignee was recorded as auto-assigned")
+	}
+}
@@ -0,0 +1,221 @@
+package controllers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"tusk/apierror"
+	"tusk/models"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxAvatarPixels menolak gambar kecil yang membengkak saat di-decode
+const maxAvatarPixels = 40_000_000
+
+var avatarTypes = map[string]bool{"image/jpeg": true, "image/png": true, "image/webp": true}
+
+// avatarURL adalah alamat gambar di API versi sekarang. v berubah setiap
+// kali gambarnya diganti, jadi browser boleh menyimpannya lama.
+func avatarURL(user models.User) *string {
+	if user.AvatarPath == "" {
+		return nil
+	}
+	url := fmt.Sprintf("/api/v1/users/%d/avatar?v=%s", user.Id, avatarVersion(user.AvatarPath))
+	return &url
+}
+
+// avatarVersion adalah hash di nama file "<id>-<hash>.jpg", dipakai juga
+// sebagai ETag
+func avatarVersion(path string) string {
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	return name[strings.IndexByte(name, '-')+1:]
+}
+
+// UploadAvatar menerima gambar jpeg, png atau webp di field "avatar". Jenis
+// file dibaca dari isinya, lalu gambar dipotong persegi, diperkecil dan
+// disimpan ulang sebagai JPEG sehingga EXIF ikut hilang. Avatar lama dihapus.
+func (u *UserController) UploadAvatar(c *gin.Context) {
+	userId := c.GetInt("userId")
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, u.Avatars.MaxSize+1<<20)
+	file, errFile := c.FormFile("avatar")
+	if errFile != nil {
+		c.Error(apierror.BadRequest(apierror.CodeBadRequest, "avatar file is required"))
+		return
+	}
+	if file.Size > u.Avatars.MaxSize {
+		c.Error(apierror.New(http.StatusRequestEntityTooLarge, apierror.CodeFileTooLarge, "avatar exceeds the maximum size of "+strconv.FormatInt(u.Avatars.MaxSize, 10)+" bytes").
+			WithDetails(gin.H{"maxBytes": u.Avatars.MaxSize}))
+		return
+	}
+
+	mime, errSniff := sniffContentType(file.Open)
+	if errSniff != nil {
+		c.Error(apierror.BadRequest(apierror.CodeBadRequest, errSniff.Error()))
+		return
+	}
+	if !avatarTypes[mime] {
+		c.Error(apierror.New(http.StatusUnsupportedMediaType, apierror.CodeUnsupportedMediaType, "avatar must be a JPEG, PNG or WebP image"))
+		return
+	}
+
+	opened, errOpen := file.Open()
+	if errOpen != nil {
+		c.Error(apierror.Internal(errOpen))
+		return
+	}
+	data, errRead := io.ReadAll(opened)
+	opened.Close()
+	if errRead != nil {
+		c.Error(apierror.Internal(errRead))
+		return
+	}
+
+	encoded, errImage := u.resizeAvatar(data)
+	if errImage != nil {
+		c.Error(apierror.BadRequest(apierror.CodeBadRequest, "avatar is not a readable image"))
+		return
+	}
+
+	sum := sha256.Sum256(encoded)
+	name := fmt.Sprintf("%d-%s.jpg", userId, hex.EncodeToString(sum[:8]))
+	if err := os.MkdirAll(u.Avatars.Dir, 0o755); err != nil {
+		c.Error(apierror.Internal(err))
+		return
+	}
+	// Tulis ke file sementara dulu supaya yang dilayani tidak pernah setengah jadi
+	temp := filepath.Join(u.Avatars.Dir, name+".tmp")
+	if err := os.WriteFile(temp, encoded, 0o644); err != nil {
+		c.Error(apierror.Internal(err))
+		return
+	}
+	if err := os.Rename(temp, filepath.Join(u.Avatars.Dir, name)); err != nil {
+		os.Remove(temp)
+		c.Error(apierror.Internal(err))
+		return
+	}
+
+	user, oldPath, errDB := u.setAvatar(c, userId, name)
+	if errDB != nil {
+		if oldPath != name {
+			os.Remove(filepath.Join(u.Avatars.Dir, name))
+		}
+		c.Error(apierror.Internal(errDB))
+		return
+	}
+	if oldPath != "" && oldPath != name {
+		os.Remove(filepath.Join(u.Avatars.Dir, oldPath))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Avatar updated successfully",
+		"user":    newUserResponse(user),
+	})
+}
+
+// DeleteAvatar menghapus avatar user yang login; tanpa avatar pun tetap 200.
+func (u *UserController) DeleteAvatar(c *gin.Context) {
+	user, oldPath, errDB := u.setAvatar(c, c.GetInt("userId"), "")
+	if errDB != nil {
+		c.Error(apierror.Internal(errDB))
+		return
+	}
+	if oldPath != "" {
+		os.Remove(filepath.Join(u.Avatars.Dir, oldPath))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Avatar removed successfully",
+		"user":    newUserResponse(user),
+	})
+}
+
+// setAvatar menyimpan path baru dan mengembalikan path lama, supaya file
+// lama baru dihapus setelah commit.
+func (u *UserController) setAvatar(c *gin.Context, userId int, path string) (models.User, string, error) {
+	var user models.User
+	var oldPath string
+	errTx := withTx(c.Request.Context(), u.DB, func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userId).Error; err != nil {
+			return err
+		}
+		oldPath = user.AvatarPath
+		return tx.Model(&user).Update("avatar_path", path).Error
+	})
+	return user, oldPath, errTx
+}
+
+// Avatar melayani gambar profil dengan ETag dan Cache-Control; If-None-Match
+// dijawab 304 oleh http.ServeFile.
+func (u *UserController) Avatar(c *gin.Context) {
+	var user models.User
+	errDB := u.DB.WithContext(c.Request.Context()).Select("id, avatar_path").First(&user, c.Param("id")).Error
+	if errors.Is(errDB, gorm.ErrRecordNotFound) {
+		c.Error(apierror.NotFound(apierror.CodeUserNotFound, "User not found"))
+		return
+	}
+	if errDB != nil {
+		c.Error(apierror.Internal(errDB))
+		return
+	}
+	path := filepath.Join(u.Avatars.Dir, filepath.Base(user.AvatarPath))
+	if _, err := os.Stat(path); user.AvatarPath == "" || err != nil {
+		c.Error(apierror.NotFound(apierror.CodeAvatarNotFound, "User has no avatar"))
+		return
+	}
+
+	c.Header("ETag", `"`+avatarVersion(user.AvatarPath)+`"`)
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.File(path)
+}
+
+// resizeAvatar memotong bagian tengah jadi persegi, memperkecilnya ke
+// Avatars.Size (tidak diperbesar) di atas latar putih untuk gambar
+// transparan, lalu meng-encode ulang sebagai JPEG.
+func (u *UserController) resizeAvatar(data []byte) ([]byte, error) {
+	header, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if header.Width*header.Height > maxAvatarPixels {
+		return nil, errors.New("image is too large")
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := src.Bounds()
+	side := min(bounds.Dx(), bounds.Dy())
+	crop := image.Rect(0, 0, side, side).Add(image.Pt(
+		bounds.Min.X+(bounds.Dx()-side)/2,
+		bounds.Min.Y+(bounds.Dy()-side)/2,
+	))
+	size := min(side, u.Avatars.Size)
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Over, nil)
+
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, dst, &jpeg.Options{Quality: 85}); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
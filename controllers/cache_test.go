@@ -0,0 +1,131 @@
+package controllers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+	"tusk/cache"
+	"tusk/controllers"
+	"tusk/models"
+	"tusk/routes"
+	"tusk/testutil"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// cachedServer wires the employee list cache and its invalidation the way
+// main does, and counts the queries that reach the database.
+func cachedServer(t testing.TB) (*testutil.Server, *atomic.Int64) {
+	s := testutil.NewServer(t, testutil.DB(t))
+	s.Users.Cache = cache.New[gin.H](time.Minute)
+	err := cache.InvalidateOnWrite(s.DB, map[string][]string{
+		"users": {"employees:", "stats:"},
+		"tasks": {"stats:"},
+	}, s.Users.Cache.Invalidate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	queries := &atomic.Int64{}
+	count := func(*gorm.DB) { queries.Add(1) }
+	if err := s.DB.Callback().Query().After("gorm:query").Register("test:count", count); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.DB.Callback().Raw().After("gorm:raw").Register("test:count", count); err != nil {
+		t.Fatal(err)
+	}
+	return s, queries
+}
+
+func TestEmployeeListCache(t *testing.T) {
+	s, queries := cachedServer(t)
+	admin := testutil.User(t, s.DB, models.RoleAdmin)
+	token := testutil.Token(t, admin)
+	testutil.User(t, s.DB, models.RoleEmployee)
+	total := func(t *testing.T) int {
+		t.Helper()
+
+		res := s.Do(t, http.MethodGet, routes.Prefix+"/users/Employee", token, nil)
+		testutil.Expect(t, res, http.StatusOK)
+		body := struct {
+			Total int `json:"total"`
+		}{}
+		testutil.Decode(t, res, &body)
+		return body.Total
+	}
+
+	if got := total(t); got != 1 {
+		t.Fatalf("total = %d, want 1", got)
+	}
+	before := queries.Load()
+	if got := total(t); got != 1 {
+		t.Fatalf("total = %d, want 1", got)
+	}
+	// only the token version check still reaches the database
+	if warm := queries.Load() - before; warm != 1 {
+		t.Errorf("a cached list ran %d queries, want 1", warm)
+	}
+
+	res := s.Do(t, http.MethodPost, routes.Prefix+"/users", token, map[string]interface{}{
+		"name": "Budi", "email": "budi@go.id", "password": "teh-manis-7", "skipVerification": true,
+	})
+	testutil.Expect(t, res, http.StatusCreated)
+	if got := total(t); got != 2 {
+		t.Errorf("total = %d right after a create, want 2", got)
+	}
+
+	s.DB.Model(&models.User{}).Where("email = ?", "budi@go.id").Update("is_active", false)
+	if got := total(t); got != 1 {
+		t.Errorf("total = %d right after a deactivation, want 1", got)
+	}
+}
+
+// BenchmarkEmployeeList reports the database queries per request, with
+// and without the cache. Cached, the one left is the token version check.
+func BenchmarkEmployeeList(b *testing.B) {
+	for _, cached := range []bool{false, true} {
+		name := "uncached"
+		if cached {
+			name = "cached"
+		}
+		b.Run(name, func(b *testing.B) {
+			s, queries := cachedServer(b)
+			if !cached {
+				s.Users.Cache = nil
+			}
+			token := testutil.Token(b, testutil.User(b, s.DB, models.RoleAdmin))
+			for i := 0; i < 50; i++ {
+				testutil.User(b, s.DB, models.RoleEmployee)
+			}
+			path := routes.Prefix + "/users/Employee?limit=20"
+			testutil.Expect(b, s.Do(b, http.MethodGet, path, token, nil), http.StatusOK)
+
+			queries.Store(0)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				s.Do(b, http.MethodGet, path, token, nil)
+			}
+			b.ReportMetric(float64(queries.Load())/float64(b.N), "queries/op")
+		})
+	}
+}
+
+func TestCachedLoadOutlivesItsRequest(t *testing.T) {
+	db := testutil.DB(t)
+	testutil.User(t, db, models.RoleEmployee)
+	dashboard := &controllers.DashboardController{DB: db, Cache: cache.New[gin.H](time.Minute)}
+
+	// the client that started the load is gone, which must not fail the
+	// load every waiter on the key shares
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(res)
+	c.Request = httptest.NewRequest(http.MethodGet, routes.Prefix+"/dashboard/stats", nil).WithContext(ctx)
+	dashboard.Stats(c)
+	testutil.Expect(t, res, http.StatusOK)
+}
@@ -0,0 +1,218 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"tusk/events"
+	"tusk/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CommentController struct {
+	DB     *gorm.DB
+	Events *events.Hub
+}
+
+type CreateCommentRequest struct {
+	Body string `json:"body" binding:"required"`
+}
+
+type CommentAuthor struct {
+	Id   int    `json:"id"`
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+type CommentResponse struct {
+	Id        int           `json:"id"`
+	TaskId    int           `json:"taskId"`
+	Body      string        `json:"body"`
+	CreatedAt string        `json:"createdAt"`
+	Author    CommentAuthor `json:"author"`
+}
+
+func newCommentResponse(comment models.Comment) CommentResponse {
+	return CommentResponse{
+		Id:        comment.Id,
+		TaskId:    comment.TaskId,
+		Body:      comment.Body,
+		CreatedAt: formatTime(comment.CreatedAt),
+		Author: CommentAuthor{
+			Id:   comment.Author.Id,
+			Name: comment.Author.Name,
+			Role: comment.Author.Role,
+		},
+	}
+}
+
+func (cc *CommentController) Create(c *gin.Context) {
+	task, ok := cc.findTask(c)
+	if !ok {
+		return
+	}
+
+	var createReq CreateCommentRequest
+	if errBindJson := c.ShouldBindJSON(&createReq); errBindJson != nil {
+		c.JSON(http.StatusBadRequest, bindError(errBindJson))
+		return
+	}
+	if strings.TrimSpace(createReq.Body) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Request validation failed", "fields": gin.H{"body": "body is required"}})
+		return
+	}
+
+	comment := models.Comment{
+		TaskId:   task.Id,
+		AuthorId: c.GetInt("userId"),
+		Body:     strings.TrimSpace(createReq.Body),
+	}
+	if errDB := cc.DB.WithContext(c.Request.Context()).Create(&comment).Error; errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	if errDB := cc.DB.WithContext(c.Request.Context()).Preload("Author").First(&comment, comment.Id).Error; errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	response := newCommentResponse(comment)
+	cc.Events.Publish(events.Event{
+		Type:    events.CommentCreated,
+		TaskId:  task.Id,
+		Data:    response,
+		UserIds: []int{task.UserId},
+	})
+	c.JSON(http.StatusCreated, response)
+}
+
+// List returns a task's comments oldest first, so a page reads like a chat.
+func (cc *CommentController) List(c *gin.Context) {
+	task, ok := cc.findTask(c)
+	if !ok {
+		return
+	}
+
+	page, errPage := strconv.Atoi(c.Query("page"))
+	if errPage != nil || page < 1 {
+		page = 1
+	}
+	limit, errLimit := strconv.Atoi(c.Query("limit"))
+	if errLimit != nil || limit < 1 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	query := cc.DB.WithContext(c.Request.Context()).Model(&models.Comment{}).Where("task_id=?", task.Id)
+
+	var total int64
+	if errDB := query.Count(&total).Error; errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	comments := []models.Comment{}
+	errDB := query.Preload("Author", func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}).
+		Order("created_at ASC, id ASC").
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&comments).Error
+	if errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	responses := make([]CommentResponse, 0, len(comments))
+	for _, comment := range comments {
+		responses = append(responses, newCommentResponse(comment))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       responses,
+		"page":       page,
+		"limit":      limit,
+		"total":      total,
+		"totalPages": (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+func (cc *CommentController) Delete(c *gin.Context) {
+	comment := models.Comment{}
+	if err := cc.DB.WithContext(c.Request.Context()).First(&comment, c.Param("id")).Error; err != nil {
+		respondDBError(c, err, http.StatusNotFound, "not found")
+		return
+	}
+
+	if c.GetString("role") != models.RoleAdmin && comment.AuthorId != c.GetInt("userId") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author or an Admin can delete this comment"})
+		return
+	}
+
+	if errDB := cc.DB.WithContext(c.Request.Context()).Delete(&comment).Error; errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	c.JSON(http.StatusOK, "Deleted")
+}
+
+// findTask loads the task from the :id param and checks the caller is its
+// assignee or an Admin, writing the error response when not.
+func (cc *CommentController) findTask(c *gin.Context) (models.Task, bool) {
+	task := models.Task{}
+	if err := cc.DB.WithContext(c.Request.Context()).First(&task, c.Param("id")).Error; err != nil {
+		respondDBError(c, err, http.StatusNotFound, "not found")
+		return task, false
+	}
+
+	if c.GetString("role") != models.RoleAdmin && task.UserId != c.GetInt("userId") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the assignee or an Admin can access these comments"})
+		return task, false
+	}
+
+	return task, true
+}
+
+// withCounts fills the comment and subtask counts of task responses.
+func withCounts(db *gorm.DB, responses []TaskResponse) []TaskResponse {
+	return withSubtaskCounts(db, withCommentCounts(db, responses))
+}
+
+// withCommentCounts fills CommentCount on the responses with a single
+// grouped query.
+func withCommentCounts(db *gorm.DB, responses []TaskResponse) []TaskResponse {
+	if len(responses) == 0 {
+		return responses
+	}
+
+	ids := make([]int, 0, len(responses))
+	for _, response := range responses {
+		ids = append(ids, response.Id)
+	}
+
+	counts := []struct {
+		TaskId int
+		Total  int64
+	}{}
+	db.Model(&models.Comment{}).
+		Select("task_id, count(*) as total").
+		Where("task_id IN ?", ids).
+		Group("task_id").
+		Scan(&counts)
+
+	byTask := make(map[int]int64, len(counts))
+	for _, count := range counts {
+		byTask[count.TaskId] = count.Total
+	}
+	for i := range responses {
+		responses[i].CommentCount = byTask[responses[i].Id]
+	}
+	return responses
+}
@@ -0,0 +1,228 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+	"tusk/cache"
+	"tusk/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type DashboardController struct {
+	DB    *gorm.DB
+	Cache *cache.Cache[gin.H] // admin summary; nil disables caching
+}
+
+type windowCounts struct {
+	Last7Days  int64 `json:"last7Days"`
+	Last30Days int64 `json:"last30Days"`
+}
+
+type taskStats struct {
+	Tasks     map[string]int64 `json:"tasks"`
+	Created   windowCounts     `json:"created"`
+	Completed windowCounts     `json:"completed"`
+	Overdue   int64            `json:"overdue"`
+}
+
+type topEmployee struct {
+	UserId    int    `json:"userId"`
+	Name      string `json:"name"`
+	Completed int64  `json:"completed"`
+}
+
+// queryGroup runs a few independent queries at once and keeps the first
+// error, since the dashboard is polled and the round trips add up.
+type queryGroup struct {
+	wg   sync.WaitGroup
+	once sync.Once
+	err  error
+}
+
+func (g *queryGroup) Go(query func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := query(); err != nil {
+			g.once.Do(func() { g.err = err })
+		}
+	}()
+}
+
+func (g *queryGroup) Wait() error {
+	g.wg.Wait()
+	return g.err
+}
+
+// Stats is the admin home screen summary, for one department with
+// ?departmentId= and always for their own one for Managers. It is cached
+// for a short while since every dashboard load asks for it; writes to users
+// or tasks drop it.
+func (d *DashboardController) Stats(c *gin.Context) {
+	key := "stats:dashboard"
+	var department *int
+	if departmentId, scoped := departmentScope(c); scoped {
+		key += ":department=" + strconv.Itoa(departmentId)
+		department = &departmentId
+	}
+
+	response, errDB := d.Cache.Get(key, func() (gin.H, error) {
+		ctx, cancel := cacheLoadContext(c)
+		defer cancel()
+		return d.stats(d.DB.WithContext(ctx), department)
+	})
+	if errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (d *DashboardController) stats(db *gorm.DB, department *int) (gin.H, error) {
+	stats := taskStats{}
+	var employees int64
+	top := []topEmployee{}
+	byPriority := map[string]int64{}
+	for _, name := range models.Priorities {
+		byPriority[name] = 0
+	}
+	priorityCounts := []struct {
+		Priority int
+		Total    int64
+	}{}
+
+	now := time.Now().UTC()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+	// the queries below run at once, so the scoped handles start new sessions
+	tasks, users := db, db
+	if department != nil {
+		members := db.Model(&models.User{}).Select("id").Where("department_id = ?", *department)
+		tasks = db.Where("tasks.user_id IN (?)", members).Session(&gorm.Session{})
+		users = db.Where("users.department_id = ?", *department).Session(&gorm.Session{})
+	}
+
+	group := &queryGroup{}
+	collectTaskStats(group, tasks, nil, now, &stats)
+	group.Go(func() error {
+		return users.Model(&models.User{}).Where("role=?", models.RoleEmployee).Count(&employees).Error
+	})
+	group.Go(func() error {
+		return tasks.Model(&models.Task{}).
+			Select("tasks.user_id, users.name, count(*) as completed").
+			Joins("JOIN users ON users.id = tasks.user_id").
+			Where("tasks.status=? AND tasks.status_changed_at >= ?", models.StatusApproved, monthStart).
+			Group("tasks.user_id, users.name").
+			Order("completed DESC, tasks.user_id ASC").
+			Limit(5).
+			Scan(&top).Error
+	})
+	group.Go(func() error {
+		return tasks.Model(&models.Task{}).
+			Select("priority, count(*) as total").
+			Where("status<>?", models.StatusApproved).
+			Group("priority").
+			Scan(&priorityCounts).Error
+	})
+	if errDB := group.Wait(); errDB != nil {
+		return nil, errDB
+	}
+	for _, count := range priorityCounts {
+		byPriority[models.PriorityName(count.Priority)] += count.Total
+	}
+
+	return gin.H{
+		"tasks":          stats.Tasks,
+		"created":        stats.Created,
+		"completed":      stats.Completed,
+		"overdue":        stats.Overdue,
+		"employees":      employees,
+		"topEmployees":   top,
+		"openByPriority": byPriority,
+	}, nil
+}
+
+// UserStats returns the same task numbers for one assignee. Employees may
+// only look at their own.
+func (d *DashboardController) UserStats(c *gin.Context) {
+	id, errId := strconv.Atoi(c.Param("id"))
+	if errId != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid user id"})
+		return
+	}
+	if c.GetString("role") != models.RoleAdmin && c.GetInt("userId") != id {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only view your own stats"})
+		return
+	}
+
+	db := d.DB.WithContext(c.Request.Context())
+	if err := db.First(&models.User{}, id).Error; err != nil {
+		respondDBError(c, err, http.StatusNotFound, "not found")
+		return
+	}
+
+	stats := taskStats{}
+	group := &queryGroup{}
+	collectTaskStats(group, db, &id, time.Now().UTC(), &stats)
+	if errDB := group.Wait(); errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	c.JSON(http.StatusOK, stats)
+}
+
+// collectTaskStats queues the task count queries on group, optionally
+// scoped to one assignee. Completed means moved to Approved in the window.
+func collectTaskStats(group *queryGroup, db *gorm.DB, userId *int, now time.Time, stats *taskStats) {
+	scoped := func() *gorm.DB {
+		query := db.Model(&models.Task{})
+		if userId != nil {
+			query = query.Where("user_id=?", *userId)
+		}
+		return query
+	}
+	since7 := now.AddDate(0, 0, -7)
+	since30 := now.AddDate(0, 0, -30)
+
+	stats.Tasks = map[string]int64{}
+	for _, status := range models.Statuses {
+		stats.Tasks[status] = 0
+	}
+	counts := []struct {
+		Status string
+		Total  int64
+	}{}
+
+	group.Go(func() error {
+		if err := scoped().Select("status, count(*) as total").Group("status").Scan(&counts).Error; err != nil {
+			return err
+		}
+		for _, count := range counts {
+			stats.Tasks[count.Status] = count.Total
+		}
+		return nil
+	})
+	group.Go(func() error {
+		return scoped().
+			Select("COALESCE(SUM(CASE WHEN created_at >= ? THEN 1 ELSE 0 END), 0) AS last7_days, COUNT(*) AS last30_days", since7).
+			Where("created_at >= ?", since30).
+			Scan(&stats.Created).Error
+	})
+	group.Go(func() error {
+		return scoped().
+			Select("COALESCE(SUM(CASE WHEN status_changed_at >= ? THEN 1 ELSE 0 END), 0) AS last7_days, COUNT(*) AS last30_days", since7).
+			Where("status=? AND status_changed_at >= ?", models.StatusApproved, since30).
+			Scan(&stats.Completed).Error
+	})
+	group.Go(func() error {
+		return scoped().
+			Where("status<>? AND due_date IS NOT NULL AND due_date < ?", models.StatusApproved, now).
+			Count(&stats.Overdue).Error
+	})
+}
@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"tusk/apierror"
+	"tusk/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DepartmentController struct {
+	DB *gorm.DB
+}
+
+type DepartmentRequest struct {
+	Name string `json:"name" binding:"required,max=100"`
+}
+
+type DepartmentResponse struct {
+	Id        int    `json:"id"`
+	Name      string `json:"name"`
+	Members   int64  `json:"members"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+func newDepartmentResponse(department models.Department, members int64) DepartmentResponse {
+	return DepartmentResponse{
+		Id:        department.Id,
+		Name:      department.Name,
+		Members:   members,
+		CreatedAt: formatTime(department.CreatedAt),
+		UpdatedAt: formatTime(department.UpdatedAt),
+	}
+}
+
+func (d *DepartmentController) Create(c *gin.Context) {
+	var createReq DepartmentRequest
+	if err := c.ShouldBindJSON(&createReq); err != nil {
+		c.Error(apierror.Validation(err))
+		return
+	}
+
+	name, ok := departmentName(c, createReq)
+	if !ok {
+		return
+	}
+
+	department := models.Department{Name: name}
+
+	errDB := d.DB.WithContext(c.Request.Context()).Create(&department).Error
+	if isDuplicateKey(errDB) {
+		c.Error(apierror.Conflict(apierror.CodeDepartmentTaken, "Department "+department.Name+" already exists"))
+		return
+	}
+	if errDB != nil {
+		c.Error(apierror.Internal(errDB))
+		return
+	}
+
+	c.JSON(http.StatusCreated, newDepartmentResponse(department, 0))
+}
+
+// List returns every department by name with its member count.
+func (d *DepartmentController) List(c *gin.Context) {
+	rows := []struct {
+		models.Department
+		Members int64
+	}{}
+	errDB := d.DB.WithContext(c.Request.Context()).Model(&models.Department{}).
+		Select("departments.id, departments.name, departments.created_at, departments.updated_at, COUNT(users.id) AS members").
+		Joins("LEFT JOIN users ON users.department_id = departments.id AND users.deleted_at IS NULL").
+		Group("departments.id, departments.name, departments.created_at, departments.updated_at").
+		Order("departments.name ASC").
+		Scan(&rows).Error
+	if errDB != nil {
+		c.Error(apierror.Internal(errDB))
+		return
+	}
+
+	departments := make([]DepartmentResponse, 0, len(rows))
+	for _, row := range rows {
+		departments = append(departments, newDepartmentResponse(row.Department, row.Members))
+	}
+	c.JSON(http.StatusOK, departments)
+}
+
+func (d *DepartmentController) GetByID(c *gin.Context) {
+	department, ok := d.find(c)
+	if !ok {
+		return
+	}
+
+	members, errDB := countMembers(d.DB.WithContext(c.Request.Context()), department.Id)
+	if errDB != nil {
+		c.Error(apierror.Internal(errDB))
+		return
+	}
+
+	c.JSON(http.StatusOK, newDepartmentResponse(department, members))
+}
+
+// Update renames the department.
+func (d *DepartmentController) Update(c *gin.Context) {
+	var updateReq DepartmentRequest
+	if err := c.ShouldBindJSON(&updateReq); err != nil {
+		c.Error(apierror.Validation(err))
+		return
+	}
+
+	department, ok := d.find(c)
+	if !ok {
+		return
+	}
+
+	name, ok := departmentName(c, updateReq)
+	if !ok {
+		return
+	}
+
+	errDB := d.DB.WithContext(c.Request.Context()).Model(&department).Update("name", name).Error
+	if isDuplicateKey(errDB) {
+		c.Error(apierror.Conflict(apierror.CodeDepartmentTaken, "Department "+name+" already exists"))
+		return
+	}
+	if errDB != nil {
+		c.Error(apierror.Internal(errDB))
+		return
+	}
+
+	members, errDB := countMembers(d.DB.WithContext(c.Request.Context()), department.Id)
+	if errDB != nil {
+		c.Error(apierror.Internal(errDB))
+		return
+	}
+
+	c.JSON(http.StatusOK, newDepartmentResponse(department, members))
+}
+
+// Delete only removes an empty department; members have to be moved
+// first. Deleted users still pointing at it are detached.
+func (d *DepartmentController) Delete(c *gin.Context) {
+	department, ok := d.find(c)
+	if !ok {
+		return
+	}
+
+	var members int64
+	errTx := withTx(c.Request.Context(), d.DB, func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&department, department.Id).Error; err != nil {
+			return err
+		}
+		var err error
+		if members, err = countMembers(tx, department.Id); err != nil {
+			return err
+		}
+		if members > 0 {
+			return errDepartmentUsed
+		}
+		if err := tx.Unscoped().Model(&models.User{}).Where("department_id = ?", department.Id).Update("department_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&department).Error
+	})
+	switch {
+	case errors.Is(errTx, gorm.ErrRecordNotFound):
+		c.Error(apierror.NotFound(apierror.CodeDepartmentNotFound, "Department not found"))
+		return
+	case errors.Is(errTx, errDepartmentUsed):
+		c.Error(apierror.Conflict(apierror.CodeDepartmentHasMembers, "Department still has members, move them first").
+			WithDetails(gin.H{"members": members}))
+		return
+	case errTx != nil:
+		c.Error(apierror.Internal(errTx))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Department deleted successfully"})
+}
+
+func (d *DepartmentController) find(c *gin.Context) (models.Department, bool) {
+	department := models.Department{}
+	errDB := d.DB.WithContext(c.Request.Context()).First(&department, c.Param("id")).Error
+	if errors.Is(errDB, gorm.ErrRecordNotFound) {
+		c.Error(apierror.NotFound(apierror.CodeDepartmentNotFound, "Department not found"))
+		return department, false
+	}
+	if errDB != nil {
+		c.Error(apierror.Internal(errDB))
+		return department, false
+	}
+	return department, true
+}
+
+// departmentName trims the name; one that is only spaces is missing.
+func departmentName(c *gin.Context, req DepartmentRequest) (string, bool) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		c.Error(apierror.BadRequest(apierror.CodeValidationFailed, "Request validation failed").
+			WithDetails(apierror.FieldError{Field: "name", Rule: "required", Message: "name is required"}))
+		return "", false
+	}
+	return name, true
+}
+
+// countMembers counts the users, not deleted ones, in a department.
+func countMembers(db *gorm.DB, departmentId int) (int64, error) {
+	var members int64
+	err := db.Model(&models.User{}).Where("department_id = ?", departmentId).Count(&members).Error
+	return members, err
+}
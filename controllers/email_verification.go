@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+	"tusk/apierror"
+	"tusk/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const emailVerificationExpiry = 24 * time.Hour
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// newEmailVerification menyimpan token verifikasi untuk email user saat ini
+// dan mengembalikan link yang dikirim lewat email.
+func (u *UserController) newEmailVerification(db *gorm.DB, user models.User) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	verification := models.EmailVerification{
+		UserId:    user.Id,
+		Email:     user.Email,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().UTC().Add(emailVerificationExpiry),
+	}
+	if err := db.Create(&verification).Error; err != nil {
+		return "", err
+	}
+
+	return u.VerifyURL + "?token=" + url.QueryEscape(token), nil
+}
+
+// VerifyEmail menandai email user terverifikasi. Token hanya bisa dipakai
+// sekali dan tidak berlaku lagi kalau email user sudah diganti.
+func (u *UserController) VerifyEmail(c *gin.Context) {
+	invalid := apierror.BadRequest(apierror.CodeInvalidToken, "Verification token is invalid or expired")
+
+	token := c.Query("token")
+	var verification models.EmailVerification
+	if token == "" || u.DB.Where("token_hash = ?", hashToken(token)).First(&verification).Error != nil ||
+		verification.UsedAt != nil || time.Now().UTC().After(verification.ExpiresAt) {
+		c.Error(invalid)
+		return
+	}
+
+	errDB := withTx(c.Request.Context(), u.DB, func(tx *gorm.DB) error {
+		result := tx.Model(&verification).Where("used_at IS NULL").Update("used_at", time.Now().UTC())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errVerificationInvalid
+		}
+
+		var user models.User
+		if err := tx.First(&user, verification.UserId).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errVerificationInvalid
+			}
+			return err
+		}
+		if user.Email != verification.Email {
+			return errVerificationInvalid
+		}
+		return tx.Model(&user).Update("email_verified", true).Error
+	})
+	if errors.Is(errDB, errVerificationInvalid) {
+		c.Error(invalid)
+		return
+	}
+	if errDB != nil {
+		c.Error(apierror.Internal(errDB))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified successfully"})
+}
+
+// ResendVerification mengirim link verifikasi baru dan mematikan link yang
+// lama. Seperti ForgotPassword, response-nya selalu sama.
+func (u *UserController) ResendVerification(c *gin.Context) {
+	var resendReq ResendVerificationRequest
+	if err := c.ShouldBindJSON(&resendReq); err != nil {
+		c.Error(apierror.Validation(err))
+		return
+	}
+
+	response := gin.H{"message": "If the email is registered and not yet verified, a verification link has been sent"}
+
+	var user models.User
+	if u.DB.Where("LOWER(email) = ?", normalizeEmail(resendReq.Email)).First(&user).Error != nil ||
+		user.EmailVerified || !user.IsActive {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	var link string
+	errDB := withTx(c.Request.Context(), u.DB, func(tx *gorm.DB) error {
+		errRevoke := tx.Model(&models.EmailVerification{}).
+			Where("user_id = ? AND used_at IS NULL", user.Id).
+			Update("used_at", time.Now().UTC()).Error
+		if errRevoke != nil {
+			return errRevoke
+		}
+
+		var err error
+		link, err = u.newEmailVerification(tx, user)
+		return err
+	})
+	if errDB != nil {
+		c.Error(apierror.Internal(errDB))
+		return
+	}
+
+	sendTemplate(u.Mailer, user.Email, "verify_email", mailData{Name: user.Name, Link: link})
+	c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,58 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+	"tusk/events"
+	"tusk/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// heartbeatInterval keeps proxies from closing idle streams.
+const heartbeatInterval = 20 * time.Second
+
+type EventController struct {
+	Hub *events.Hub
+}
+
+// Stream sends task and comment events as server-sent events. Employees
+// only get events about their own tasks.
+func (e *EventController) Stream(c *gin.Context) {
+	subscriber := e.Hub.Subscribe(c.GetInt("userId"), c.GetString("role") == models.RoleAdmin)
+	defer e.Hub.Unsubscribe(subscriber)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	fmt.Fprint(c.Writer, ": connected\n\n")
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": heartbeat\n\n")
+			c.Writer.Flush()
+		case event, ok := <-subscriber.C:
+			if !ok {
+				// dropped for being too slow, or shutting down
+				return
+			}
+			payload, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", event.Type, payload)
+			c.Writer.Flush()
+		}
+	}
+}
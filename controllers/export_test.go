@@ -0,0 +1,7 @@
+package controllers
+
+// RespondDBError exposes respondDBError to the handler tests.
+var RespondDBError = respondDBError
+
+// WithTx exposes withTx to the handler tests.
+var WithTx = withTx
@@ -0,0 +1,54 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"time"
+	"tusk/config"
+	"tusk/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const readinessTimeout = 2 * time.Second
+
+type HealthController struct {
+	DB        *gorm.DB
+	StartedAt time.Time
+}
+
+// Healthz only reports that the process is up; it never touches the DB.
+func (h *HealthController) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":        "ok",
+		"version":       config.Version,
+		"uptimeSeconds": int64(time.Since(h.StartedAt).Seconds()),
+	})
+}
+
+// Readyz pings the database and checks that migrations have created the
+// users table, so a fresh database reports not-ready instead of failing on
+// the first API call.
+func (h *HealthController) Readyz(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), readinessTimeout)
+	defer cancel()
+
+	sqlDB, err := h.DB.DB()
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "error": err.Error()})
+		return
+	}
+
+	if err := sqlDB.PingContext(ctx); err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "error": "database unreachable: " + err.Error()})
+		return
+	}
+
+	if !h.DB.WithContext(ctx).Migrator().HasTable(&models.User{}) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "error": "database migrations have not run"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "ready"})
+}
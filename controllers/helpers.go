@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"tusk/apierror"
+	"tusk/middlewares"
+	"tusk/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+var (
+	errLastAdmin        = errors.New("cannot remove the last admin")
+	errUserHasOpenTasks = errors.New("user still has open tasks")
+	errReassignTarget   = errors.New("reassign target is not an active employee")
+	errStatusChanged    = errors.New("task status changed concurrently")
+	errDepartmentUsed   = errors.New("department still has members")
+	errNoDepartment     = errors.New("manager needs a department")
+	errRequestReviewed  = errors.New("task request is no longer pending")
+)
+
+// cacheLoadTimeout bounds a load of a cached response. The load doesn't
+// run on the context of the request that started it, since every request
+// waiting for the same key would get its error once that client left.
+const cacheLoadTimeout = 5 * time.Second
+
+func cacheLoadContext(c *gin.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.WithoutCancel(c.Request.Context()), cacheLoadTimeout)
+}
+
+// internalError is what clients get for a server side failure; the cause
+// is only logged.
+const internalError = "Something went wrong, please try again later"
+
+// respondDBError answers 503 when the query was cancelled by the request
+// deadline, otherwise it writes the given status and message. Server side
+// failures are logged with the request id.
+func respondDBError(c *gin.Context, err error, status int, message string) {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(c.Request.Context().Err(), context.DeadlineExceeded) {
+		middlewares.Logger(c).Warn("database query timed out", "error", err)
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "database query timed out"})
+		return
+	}
+
+	if status >= 500 {
+		middlewares.Logger(c).Error("database error", "error", err)
+	}
+	c.JSON(status, gin.H{"error": message})
+}
+
+// respondInternal logs err and answers 500 without its details.
+func respondInternal(c *gin.Context, err error) {
+	middlewares.Logger(c).Error("internal error", "error", err)
+	c.JSON(http.StatusInternalServerError, gin.H{"error": internalError})
+}
+
+// dropStream handles a streamed download failing with err. When nothing
+// was sent yet it clears the download headers and returns false, so the
+// caller can still answer with an error. Otherwise it closes the
+// connection, so the client sees a broken transfer instead of a complete
+// looking file.
+func dropStream(c *gin.Context, err error) bool {
+	if !c.Writer.Written() {
+		c.Header("Content-Type", "")
+		c.Header("Content-Disposition", "")
+		return false
+	}
+
+	middlewares.Logger(c).Error("download failed part way", "error", err)
+	c.Abort()
+	conn, _, errHijack := c.Writer.Hijack()
+	if errHijack != nil {
+		panic(http.ErrAbortHandler)
+	}
+	conn.Close()
+	return true
+}
+
+// isDuplicateKey reports a unique constraint violation on any driver.
+func isDuplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.Is(err, gorm.ErrDuplicatedKey) || (errors.As(err, &mysqlErr) && mysqlErr.Number == 1062)
+}
+
+// bindError is the body for a failed ShouldBindJSON in handlers still on
+// the plain {"error": "..."} shape, with readable per-field messages.
+func bindError(err error) gin.H {
+	body := gin.H{"error": apierror.Validation(err).Message}
+	if fields := apierror.FieldMessages(err); len(fields) > 0 {
+		body["fields"] = fields
+	}
+	return body
+}
+
+// formatTime renders a timestamp for API responses: RFC3339 in UTC.
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// requestLocation is the timezone preference of the calling user, used for
+// date-only filters, exports and reports. It is UTC when there is none.
+func requestLocation(c *gin.Context, db *gorm.DB) *time.Location {
+	user := models.User{}
+	db.WithContext(c.Request.Context()).Select("id, timezone").First(&user, c.GetInt("userId"))
+	return user.Location()
+}
+
+// departmentScope is the department a list is limited to. Managers always
+// get their own, set by UserController.ScopeDepartment, whatever they ask
+// for; everyone else may filter with ?departmentId=. A value that isn't a
+// number matches nothing.
+func departmentScope(c *gin.Context) (int, bool) {
+	if departmentId, scoped := c.Get("departmentId"); scoped {
+		return departmentId.(int), true
+	}
+	value := c.Query("departmentId")
+	if value == "" {
+		return 0, false
+	}
+	departmentId, _ := strconv.Atoi(value)
+	return departmentId, true
+}
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
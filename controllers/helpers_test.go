@@ -0,0 +1,49 @@
+package controllers_test
+
+import (
+	"bytes"
+	"mime/multipart"
+	"strconv"
+	"testing"
+	"tusk/models"
+	"tusk/testutil"
+)
+
+func itoa(n int) string {
+	return strconv.Itoa(n)
+}
+
+func createTask(t *testing.T, s *testutil.Server, userId int, status string) models.Task {
+	t.Helper()
+
+	task := models.Task{UserId: userId, Title: "Task of " + status, Status: status}
+	if err := s.DB.Create(&task).Error; err != nil {
+		t.Fatal(err)
+	}
+	return task
+}
+
+func reloadTask(t *testing.T, s *testutil.Server, id int) models.Task {
+	t.Helper()
+
+	task := models.Task{}
+	if err := s.DB.First(&task, id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return task
+}
+
+// multipartBody builds a form with one file field.
+func multipartBody(t *testing.T, field, name string, content []byte) (*bytes.Buffer, string) {
+	t.Helper()
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile(field, name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(content)
+	writer.Close()
+	return body, writer.FormDataContentType()
+}
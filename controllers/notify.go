@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strconv"
+	"time"
+	"tusk/mailer"
+	"tusk/models"
+	"tusk/notifications"
+)
+
+// mailData is what the mailer templates can use.
+type mailData struct {
+	Name     string
+	Task     models.Task
+	Link     string
+	Password string
+	DueDate  string // the task's due date in the recipient's timezone
+}
+
+// sendTemplate renders a mailer template and hands it to m. Mail is best
+// effort, so failures are only logged.
+func sendTemplate(m mailer.Mailer, to, name string, data mailData) {
+	if m == nil {
+		return
+	}
+
+	subject, body, err := mailer.Render(name, data)
+	if err == nil {
+		err = m.Send(to, subject, body)
+	}
+	if err != nil {
+		log.Printf("❌ Failed to send %s mail to %s: %v", name, to, err)
+	}
+}
+
+// notifyStatusChange tells the assignee their work was approved or sent
+// back; the other transitions are made by the assignee themselves.
+func (t *TaskController) notifyStatusChange(task models.Task) {
+	switch task.Status {
+	case models.StatusApproved:
+		t.notifyAssignee(task, "Task approved", task.Title, "")
+	case models.StatusRejected:
+		t.notifyAssignee(task, "Task rejected", task.Title+": "+task.Reason, "task_rejected")
+	}
+}
+
+// notifyAssignee pushes a notification to the task's assignee in the
+// background so the response isn't held up by FCM, and mails the given
+// template when there is one and the email is verified. Failures are only logged; a token FCM no
+// longer knows is cleared.
+func (t *TaskController) notifyAssignee(task models.Task, title, body, mailTemplate string) {
+	if task.UserId == 0 {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		t.deliver(ctx, task, title, body, mailTemplate)
+	}()
+}
+
+// Remind tells the assignee that the task is due soon or overdue, kind
+// being models.ReminderDueSoon or models.ReminderOverdue. Unlike
+// notifyAssignee it returns once the notification is sent, which is what
+// the reminder worker wants.
+func (t *TaskController) Remind(ctx context.Context, task models.Task, kind string) {
+	if task.UserId == 0 {
+		return
+	}
+	if kind == models.ReminderOverdue {
+		t.deliver(ctx, task, "Task overdue", task.Title, "task_overdue")
+		return
+	}
+	t.deliver(ctx, task, "Task due soon", task.Title, "task_due_soon")
+}
+
+func (t *TaskController) deliver(ctx context.Context, task models.Task, title, body, mailTemplate string) {
+	user := models.User{}
+	if err := t.DB.WithContext(ctx).Select("id, name, email, email_verified, timezone, device_token").First(&user, task.UserId).Error; err != nil {
+		return
+	}
+
+	// email yang belum diverifikasi tidak dikirimi notifikasi
+	if mailTemplate != "" && user.EmailVerified {
+		data := mailData{Name: user.Name, Task: task}
+		if task.DueDate != nil {
+			data.DueDate = task.DueDate.In(user.Location()).Format("2006-01-02 15:04 MST")
+		}
+		sendTemplate(t.Mailer, user.Email, mailTemplate, data)
+	}
+	t.push(ctx, user, title, body, map[string]string{"taskId": strconv.Itoa(task.Id), "status": task.Status})
+}
+
+// notifyRequester pushes the outcome of a task request to the Employee who
+// made it, in the background like notifyAssignee.
+func (t *TaskController) notifyRequester(request models.TaskRequest, title, body string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		user := models.User{}
+		if err := t.DB.WithContext(ctx).Select("id, device_token").First(&user, request.RequesterId).Error; err != nil {
+			return
+		}
+
+		data := map[string]string{"taskRequestId": strconv.Itoa(request.Id), "status": request.Status}
+		if request.TaskId != nil {
+			data["taskId"] = strconv.Itoa(*request.TaskId)
+		}
+		t.push(ctx, user, title, body, data)
+	}()
+}
+
+// push sends one notification to user's device, if they have one. A token
+// FCM no longer knows is cleared.
+func (t *TaskController) push(ctx context.Context, user models.User, title, body string, data map[string]string) {
+	if t.Notifier == nil || user.DeviceToken == nil || *user.DeviceToken == "" {
+		return
+	}
+
+	err := t.Notifier.Send(ctx, notifications.Notification{
+		Token: *user.DeviceToken,
+		Title: title,
+		Body:  body,
+		Data:  data,
+	})
+	t.Metrics.Notification(err)
+	if errors.Is(err, notifications.ErrUnregistered) {
+		t.DB.WithContext(ctx).Model(&models.User{}).
+			Where("id=? AND device_token=?", user.Id, *user.DeviceToken).
+			Update("device_token", nil)
+		log.Printf("ℹ️ Cleared unregistered device token of user %d", user.Id)
+		return
+	}
+	if err != nil {
+		log.Printf("⚠️ Push notification to user %d failed: %v", user.Id, err)
+	}
+}
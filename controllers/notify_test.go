@@ -0,0 +1,176 @@
+package controllers_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+	"tusk/controllers"
+	"tusk/mailer"
+	"tusk/models"
+	"tusk/notifications"
+	"tusk/routes"
+	"tusk/testutil"
+)
+
+// settled waits until count stops growing at want, since notifications
+// are sent in the background.
+func settled(t *testing.T, count func() int, want int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for count() < want && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond) // room for one too many to show up
+}
+
+func TestPushNotifications(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t))
+	token := testutil.Token(t, testutil.User(t, s.DB, models.RoleAdmin))
+	employee := testutil.User(t, s.DB, models.RoleEmployee)
+	testutil.Expect(t, s.Do(t, http.MethodPut, routes.Prefix+"/users/me/device-token", testutil.Token(t, employee), map[string]string{"deviceToken": "device-1"}), http.StatusOK)
+	silent := testutil.User(t, s.DB, models.RoleEmployee) // no device token
+	sent := func() int { return len(s.Notifier.Sent()) }
+
+	res := s.Do(t, http.MethodPost, routes.Prefix+"/tasks", token, map[string]interface{}{"title": "Write report", "userId": employee.Id})
+	testutil.Expect(t, res, http.StatusCreated)
+	created := controllers.TaskResponse{}
+	testutil.Decode(t, res, &created)
+	testutil.Expect(t, s.Do(t, http.MethodPost, routes.Prefix+"/tasks", token, map[string]interface{}{"title": "Unheard", "userId": silent.Id}), http.StatusCreated)
+
+	approved := createTask(t, s, employee.Id, models.StatusReview)
+	testutil.Expect(t, s.Do(t, http.MethodPatch, routes.Prefix+"/tasks/"+itoa(approved.Id)+"/approve", token, nil), http.StatusOK)
+	rejected := createTask(t, s, employee.Id, models.StatusReview)
+	testutil.Expect(t, s.Do(t, http.MethodPost, routes.Prefix+"/tasks/"+itoa(rejected.Id)+"/reject", token, map[string]string{"reason": "missing totals"}), http.StatusOK)
+
+	// the assignee's own transitions aren't pushed back to them
+	started := createTask(t, s, employee.Id, models.StatusQueue)
+	testutil.Expect(t, s.Do(t, http.MethodPatch, routes.Prefix+"/tasks/"+itoa(started.Id)+"/status", testutil.Token(t, employee), map[string]string{"status": models.StatusInProgress}), http.StatusOK)
+
+	settled(t, sent, 3)
+	got := map[string]notifications.Notification{}
+	for _, notification := range s.Notifier.Sent() {
+		got[notification.Title] = notification
+	}
+	want := map[string]notifications.Notification{
+		"New task assigned": {Token: "device-1", Title: "New task assigned", Body: "Write report", Data: map[string]string{"taskId": itoa(created.Id), "status": models.StatusQueue}},
+		"Task approved":     {Token: "device-1", Title: "Task approved", Body: approved.Title, Data: map[string]string{"taskId": itoa(approved.Id), "status": models.StatusApproved}},
+		"Task rejected":     {Token: "device-1", Title: "Task rejected", Body: rejected.Title + ": missing totals", Data: map[string]string{"taskId": itoa(rejected.Id), "status": models.StatusRejected}},
+	}
+	if sent() != len(want) || !reflect.DeepEqual(got, want) {
+		t.Errorf("sent %+v\nwant %+v", s.Notifier.Sent(), want)
+	}
+}
+
+func TestPushFailuresDontFailTheRequest(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t))
+	token := testutil.Token(t, testutil.User(t, s.DB, models.RoleAdmin))
+	employee := testutil.User(t, s.DB, models.RoleEmployee, func(u *models.User) {
+		deviceToken := "stale-device"
+		u.DeviceToken = &deviceToken
+	})
+	sent := func() int { return len(s.Notifier.Sent()) }
+	s.Notifier.Err = notifications.ErrUnregistered
+
+	testutil.Expect(t, s.Do(t, http.MethodPost, routes.Prefix+"/tasks", token, map[string]interface{}{"title": "Write report", "userId": employee.Id}), http.StatusCreated)
+	settled(t, sent, 1)
+
+	deadline := time.Now().Add(2 * time.Second)
+	user := models.User{}
+	for {
+		s.DB.First(&user, employee.Id)
+		if user.DeviceToken == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if user.DeviceToken != nil {
+		t.Errorf("device token = %q, want the unregistered token cleared", *user.DeviceToken)
+	}
+
+	// with the token gone nothing more is sent
+	task := createTask(t, s, employee.Id, models.StatusReview)
+	testutil.Expect(t, s.Do(t, http.MethodPatch, routes.Prefix+"/tasks/"+itoa(task.Id)+"/approve", token, nil), http.StatusOK)
+	settled(t, sent, 1)
+	if sent() != 1 {
+		t.Errorf("sent %+v, want only the first attempt", s.Notifier.Sent())
+	}
+}
+
+func TestMailNotifications(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t))
+	token := testutil.Token(t, testutil.User(t, s.DB, models.RoleAdmin))
+	employee := testutil.User(t, s.DB, models.RoleEmployee, func(u *models.User) {
+		u.Name, u.Email, u.Timezone = "Andi", "andi@go.id", "Asia/Jakarta"
+	})
+	unverified := testutil.User(t, s.DB, models.RoleEmployee, func(u *models.User) { u.EmailVerified = false })
+	seen := 0
+	sent := func() int { return len(s.Mailer.Sent()) }
+	only := func(t *testing.T, want mailer.Message) {
+		t.Helper()
+
+		settled(t, sent, seen+1)
+		got := s.Mailer.Sent()[seen:]
+		seen += len(got)
+		if len(got) != 1 || !reflect.DeepEqual(got[0], want) {
+			t.Errorf("sent %q\nwant %q", got, want)
+		}
+	}
+
+	t.Run("task assigned", func(t *testing.T) {
+		dueDate := time.Now().UTC().Add(48 * time.Hour).Truncate(time.Minute)
+		res := s.Do(t, http.MethodPost, routes.Prefix+"/tasks", token, map[string]interface{}{
+			"title": "Write <report>", "description": "Q3 & Q4", "userId": employee.Id, "dueDate": dueDate,
+		})
+		testutil.Expect(t, res, http.StatusCreated)
+
+		jakarta, _ := time.LoadLocation("Asia/Jakarta")
+		only(t, mailer.Message{
+			To:      "andi@go.id",
+			Subject: "New task: Write <report>",
+			Body: "<p>Hi Andi,</p>\n" +
+				"<p>You have been assigned a new task: <strong>Write &lt;report&gt;</strong>.</p>\n" +
+				"<p>Q3 &amp; Q4</p>\n" +
+				"<p>Due: " + dueDate.In(jakarta).Format("2006-01-02 15:04 MST") + "</p>\n" +
+				"<p>Open Tusk to get started.</p>",
+		})
+	})
+
+	t.Run("task rejected", func(t *testing.T) {
+		task := createTask(t, s, employee.Id, models.StatusReview)
+		testutil.Expect(t, s.Do(t, http.MethodPost, routes.Prefix+"/tasks/"+itoa(task.Id)+"/reject", token, map[string]string{"reason": "missing totals"}), http.StatusOK)
+
+		only(t, mailer.Message{
+			To:      "andi@go.id",
+			Subject: "Task rejected: " + task.Title,
+			Body: "<p>Hi Andi,</p>\n" +
+				"<p>Your submission for <strong>" + task.Title + "</strong> was sent back for changes.</p>\n" +
+				"<p>Reason: missing totals</p>\n" +
+				"<p>Please fix it and submit again in Tusk.</p>",
+		})
+	})
+
+	t.Run("password changed", func(t *testing.T) {
+		res := s.Do(t, http.MethodPut, routes.Prefix+"/users/password", testutil.Token(t, employee), map[string]string{
+			"oldPassword": testutil.Password, "newPassword": "teh-manis-7",
+		})
+		testutil.Expect(t, res, http.StatusOK)
+
+		only(t, mailer.Message{
+			To:      "andi@go.id",
+			Subject: "Your Tusk password was changed",
+			Body: "<p>Hi Andi,</p>\n" +
+				"<p>The password of your Tusk account was changed and every other session was signed out.</p>\n" +
+				"<p>If this wasn't you, reset your password right away and contact an admin.</p>",
+		})
+	})
+
+	t.Run("unverified email", func(t *testing.T) {
+		testutil.Expect(t, s.Do(t, http.MethodPost, routes.Prefix+"/tasks", token, map[string]interface{}{"title": "Quiet", "userId": unverified.Id}), http.StatusCreated)
+		settled(t, sent, seen)
+		if got := s.Mailer.Sent()[seen:]; len(got) != 0 {
+			t.Errorf("sent %q to an unverified email", got)
+		}
+	})
+}
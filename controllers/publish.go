@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"tusk/events"
+	"tusk/models"
+)
+
+// publishTask sends a task event to Admins and the assignee.
+func (t *TaskController) publishTask(eventType string, task models.Task) {
+	t.publish(eventType, task, task.UserId)
+}
+
+// publishReassigned sends the update of a reassigned task to the previous
+// assignee too, so it disappears from their view.
+func (t *TaskController) publishReassigned(task models.Task, previousUserId int) {
+	t.publish(events.TaskUpdated, task, task.UserId, previousUserId)
+}
+
+func (t *TaskController) publish(eventType string, task models.Task, userIds ...int) {
+	if t.Events == nil {
+		return
+	}
+
+	t.Events.Publish(events.Event{
+		Type:    eventType,
+		TaskId:  task.Id,
+		Data:    newTaskResponse(task),
+		UserIds: userIds,
+	})
+}
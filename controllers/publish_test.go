@@ -0,0 +1,48 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+	"tusk/events"
+	"tusk/models"
+	"tusk/routes"
+	"tusk/testutil"
+)
+
+func TestOnlyTheReassignmentReachesThePreviousAssignee(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t))
+	token := testutil.Token(t, testutil.User(t, s.DB, models.RoleAdmin))
+	previous := testutil.User(t, s.DB, models.RoleEmployee)
+	next := testutil.User(t, s.DB, models.RoleEmployee)
+	task := createTask(t, s, previous.Id, models.StatusQueue)
+
+	previousEvents := s.Events.Subscribe(previous.Id, false)
+	nextEvents := s.Events.Subscribe(next.Id, false)
+	defer s.Events.Unsubscribe(previousEvents)
+	defer s.Events.Unsubscribe(nextEvents)
+
+	path := routes.Prefix + "/tasks/" + itoa(task.Id)
+	testutil.Expect(t, s.Do(t, http.MethodPatch, path+"/assign", token, map[string]int{"userId": next.Id}), http.StatusOK)
+	testutil.Expect(t, s.Do(t, http.MethodPut, path, token, map[string]string{"title": "Renamed"}), http.StatusOK)
+	testutil.Expect(t, s.Do(t, http.MethodPatch, path+"/status", token, map[string]string{"status": models.StatusInProgress}), http.StatusOK)
+
+	if got := drain(previousEvents); len(got) != 1 || got[0].Type != events.TaskUpdated || got[0].TaskId != task.Id {
+		t.Errorf("previous assignee got %v, want only the reassignment", got)
+	}
+	if got := drain(nextEvents); len(got) != 3 {
+		t.Errorf("new assignee got %d events, want 3", len(got))
+	}
+}
+
+// drain returns the events already published to subscriber.
+func drain(subscriber *events.Subscriber) []events.Event {
+	received := []events.Event{}
+	for {
+		select {
+		case event := <-subscriber.C:
+			received = append(received, event)
+		default:
+			return received
+		}
+	}
+}
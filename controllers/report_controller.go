@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+	"tusk/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+	"gorm.io/gorm"
+)
+
+type ReportController struct {
+	DB *gorm.DB
+}
+
+// MonthlyTasks builds an XLSX report of the tasks created in ?month=YYYY-MM
+// (current month by default): a Summary sheet and a Tasks detail sheet.
+// The month and every time in it are in the caller's timezone.
+func (r *ReportController) MonthlyTasks(c *gin.Context) {
+	location := requestLocation(c, r.DB)
+	now := time.Now().In(location)
+	month := c.DefaultQuery("month", now.Format("2006-01"))
+	start, errMonth := time.ParseInLocation("2006-01", month, location)
+	if errMonth != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "month must be YYYY-MM"})
+		return
+	}
+	end := start.AddDate(0, 1, 0)
+
+	rows, errDB := r.DB.WithContext(c.Request.Context()).
+		Model(&models.Task{}).
+		Select("tasks.id, tasks.title, tasks.status, tasks.user_id, users.name AS assignee, tasks.due_date, tasks.status_changed_at, tasks.created_at").
+		Joins("LEFT JOIN users ON users.id = tasks.user_id").
+		Where("tasks.created_at >= ? AND tasks.created_at < ?", start.UTC(), end.UTC()).
+		Order("tasks.id ASC").
+		Rows()
+	if errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+	defer rows.Close()
+
+	file := excelize.NewFile()
+	defer file.Close()
+	file.SetSheetName("Sheet1", "Summary")
+	if _, err := file.NewSheet("Tasks"); err != nil {
+		respondInternal(c, err)
+		return
+	}
+
+	detail, errStream := file.NewStreamWriter("Tasks")
+	if errStream != nil {
+		respondInternal(c, errStream)
+		return
+	}
+	detail.SetRow("A1", []interface{}{"ID", "Title", "Assignee", "Status", "Created", "Due", "Status Changed", "Overdue"})
+
+	byStatus := map[string]int{}
+	total, approved := 0, 0
+	approvedDays := 0.0
+	for rows.Next() {
+		var row struct {
+			models.Task
+			Assignee string
+		}
+		if err := r.DB.ScanRows(rows, &row); err != nil {
+			respondInternal(c, err)
+			return
+		}
+
+		total++
+		byStatus[row.Status]++
+		if row.Status == models.StatusApproved && row.StatusChangedAt != nil {
+			approved++
+			approvedDays += row.StatusChangedAt.Sub(row.CreatedAt).Hours() / 24
+		}
+
+		dueDate, changedAt := "", ""
+		if row.DueDate != nil {
+			dueDate = row.DueDate.In(location).Format("2006-01-02 15:04:05")
+		}
+		if row.StatusChangedAt != nil {
+			changedAt = row.StatusChangedAt.In(location).Format("2006-01-02 15:04:05")
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, total+1)
+		detail.SetRow(cell, []interface{}{
+			row.Id,
+			row.Title,
+			row.Assignee,
+			row.Status,
+			row.CreatedAt.In(location).Format("2006-01-02 15:04:05"),
+			dueDate,
+			changedAt,
+			row.IsOverdue(now),
+		})
+	}
+	if err := detail.Flush(); err != nil {
+		respondInternal(c, err)
+		return
+	}
+
+	completionRate, averageDays := 0.0, 0.0
+	if total > 0 {
+		completionRate = float64(byStatus[models.StatusApproved]) / float64(total)
+	}
+	if approved > 0 {
+		averageDays = approvedDays / float64(approved)
+	}
+
+	summary, errStream := file.NewStreamWriter("Summary")
+	if errStream != nil {
+		respondInternal(c, errStream)
+		return
+	}
+	summaryRows := [][]interface{}{
+		{"Month", month},
+		{"Total Tasks", total},
+	}
+	for _, status := range models.Statuses {
+		summaryRows = append(summaryRows, []interface{}{status, byStatus[status]})
+	}
+	summaryRows = append(summaryRows,
+		[]interface{}{"Completion Rate", completionRate},
+		[]interface{}{"Average Days to Approved", averageDays},
+	)
+	for i, summaryRow := range summaryRows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		summary.SetRow(cell, summaryRow)
+	}
+	if err := summary.Flush(); err != nil {
+		respondInternal(c, err)
+		return
+	}
+
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Header("Content-Disposition", `attachment; filename="tasks-`+month+`.xlsx"`)
+	c.Status(http.StatusOK)
+	file.Write(c.Writer)
+}
@@ -0,0 +1,125 @@
+package controllers_test
+
+import (
+	"bytes"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+	"tusk/models"
+	"tusk/routes"
+	"tusk/testutil"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestMonthlyTaskReport(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t))
+	admin := testutil.User(t, s.DB, models.RoleAdmin)
+	employee := testutil.User(t, s.DB, models.RoleEmployee, func(u *models.User) { u.Name = "Andi" })
+	token := testutil.Token(t, admin)
+
+	june := time.Date(2024, 6, 10, 9, 0, 0, 0, time.UTC)
+	createdAt := func(task models.Task, at time.Time, updates map[string]interface{}) {
+		updates["created_at"] = at
+		if err := s.DB.Model(&task).UpdateColumns(updates).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	done := createTask(t, s, employee.Id, models.StatusApproved)
+	createdAt(done, june, map[string]interface{}{"status_changed_at": june.Add(48 * time.Hour)})
+	late := createTask(t, s, employee.Id, models.StatusInProgress)
+	createdAt(late, june.Add(time.Hour), map[string]interface{}{"due_date": june.Add(24 * time.Hour)})
+	july := createTask(t, s, employee.Id, models.StatusQueue)
+	createdAt(july, june.AddDate(0, 1, 0), map[string]interface{}{})
+
+	report := func(t *testing.T, query string) *excelize.File {
+		t.Helper()
+
+		res := s.Do(t, http.MethodGet, routes.Prefix+"/reports/tasks"+query, token, nil)
+		testutil.Expect(t, res, http.StatusOK)
+		file, err := excelize.OpenReader(bytes.NewReader(res.Body.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { file.Close() })
+		return file
+	}
+	rows := func(t *testing.T, file *excelize.File, sheet string) [][]string {
+		t.Helper()
+
+		rows, err := file.GetRows(sheet)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rows
+	}
+
+	t.Run("month with tasks", func(t *testing.T) {
+		file := report(t, "?month=2024-06")
+		want := [][]string{
+			{"Month", "2024-06"},
+			{"Total Tasks", "2"},
+			{"Queue", "0"},
+			{"InProgress", "1"},
+			{"Review", "0"},
+			{"Rejected", "0"},
+			{"Approved", "1"},
+			{"Completion Rate", "0.5"},
+			{"Average Days to Approved", "2"},
+		}
+		if got := rows(t, file, "Summary"); !reflect.DeepEqual(got, want) {
+			t.Errorf("summary = %q\nwant %q", got, want)
+		}
+
+		detail := rows(t, file, "Tasks")
+		if len(detail) != 3 {
+			t.Fatalf("detail = %q, want a header and the two June tasks", detail)
+		}
+		if detail[0][0] != "ID" || detail[0][7] != "Overdue" {
+			t.Errorf("header = %q", detail[0])
+		}
+		wantDone := []string{itoa(done.Id), done.Title, "Andi", models.StatusApproved, "2024-06-10 09:00:00", "", "2024-06-12 09:00:00", "FALSE"}
+		if !reflect.DeepEqual(detail[1], wantDone) {
+			t.Errorf("row = %q\nwant %q", detail[1], wantDone)
+		}
+		wantLate := []string{itoa(late.Id), late.Title, "Andi", models.StatusInProgress, "2024-06-10 10:00:00", "2024-06-11 09:00:00", "", "TRUE"}
+		if !reflect.DeepEqual(detail[2], wantLate) {
+			t.Errorf("row = %q\nwant %q", detail[2], wantLate)
+		}
+	})
+
+	t.Run("empty month", func(t *testing.T) {
+		file := report(t, "?month=2023-01")
+		summary := rows(t, file, "Summary")
+		if len(summary) != 9 {
+			t.Fatalf("summary = %q, want every row", summary)
+		}
+		for _, row := range summary[1:] {
+			if row[1] != "0" {
+				t.Errorf("%s = %s, want 0", row[0], row[1])
+			}
+		}
+		if detail := rows(t, file, "Tasks"); len(detail) != 1 {
+			t.Errorf("detail = %q, want only the header", detail)
+		}
+	})
+
+	t.Run("current month by default", func(t *testing.T) {
+		month := time.Now().UTC().Format("2006-01")
+		res := s.Do(t, http.MethodGet, routes.Prefix+"/reports/tasks", token, nil)
+		testutil.Expect(t, res, http.StatusOK)
+		if got := res.Header().Get("Content-Disposition"); got != `attachment; filename="tasks-`+month+`.xlsx"` {
+			t.Errorf("Content-Disposition = %q, want %s", got, month)
+		}
+	})
+
+	t.Run("invalid month", func(t *testing.T) {
+		testutil.Expect(t, s.Do(t, http.MethodGet, routes.Prefix+"/reports/tasks?month=2024-13", token, nil), http.StatusBadRequest)
+		testutil.Expect(t, s.Do(t, http.MethodGet, routes.Prefix+"/reports/tasks?month=june", token, nil), http.StatusBadRequest)
+	})
+
+	t.Run("Admins only", func(t *testing.T) {
+		testutil.Expect(t, s.Do(t, http.MethodGet, routes.Prefix+"/reports/tasks", testutil.Token(t, employee), nil), http.StatusForbidden)
+	})
+}
@@ -0,0 +1,310 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"tusk/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SubtaskController struct {
+	DB *gorm.DB
+}
+
+type CreateSubtaskRequest struct {
+	Title string `json:"title" binding:"required,max=255"`
+}
+
+type UpdateSubtaskRequest struct {
+	Title *string `json:"title" binding:"omitempty,min=1,max=255"`
+	Done  *bool   `json:"done"`
+}
+
+type ReorderSubtasksRequest struct {
+	Ids []int `json:"ids" binding:"required"`
+}
+
+type SubtaskResponse struct {
+	Id       int    `json:"id"`
+	TaskId   int    `json:"taskId"`
+	Title    string `json:"title"`
+	Done     bool   `json:"done"`
+	Position int    `json:"position"`
+}
+
+var errSubtaskSet = errors.New("ids must list every subtask of the task exactly once")
+
+func newSubtaskResponse(subtask models.Subtask) SubtaskResponse {
+	return SubtaskResponse{
+		Id:       subtask.Id,
+		TaskId:   subtask.TaskId,
+		Title:    subtask.Title,
+		Done:     subtask.Done,
+		Position: subtask.Position,
+	}
+}
+
+func (sc *SubtaskController) List(c *gin.Context) {
+	task, ok := sc.findTask(c)
+	if !ok {
+		return
+	}
+
+	subtasks := []models.Subtask{}
+	errDB := sc.DB.WithContext(c.Request.Context()).Where("task_id=?", task.Id).Order("position ASC, id ASC").Find(&subtasks).Error
+	if errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	responses := make([]SubtaskResponse, 0, len(subtasks))
+	for _, subtask := range subtasks {
+		responses = append(responses, newSubtaskResponse(subtask))
+	}
+	c.JSON(http.StatusOK, responses)
+}
+
+// Create appends the subtask at the end of the checklist.
+func (sc *SubtaskController) Create(c *gin.Context) {
+	task, ok := sc.findTask(c)
+	if !ok {
+		return
+	}
+
+	var createReq CreateSubtaskRequest
+	if err := c.ShouldBindJSON(&createReq); err != nil {
+		c.JSON(http.StatusBadRequest, bindError(err))
+		return
+	}
+	title := strings.TrimSpace(createReq.Title)
+	if title == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Request validation failed", "fields": gin.H{"title": "title is required"}})
+		return
+	}
+
+	subtask := models.Subtask{TaskId: task.Id, Title: title}
+	errDB := withTx(c.Request.Context(), sc.DB, func(tx *gorm.DB) error {
+		var last struct{ Position *int }
+		if err := tx.Model(&models.Subtask{}).Select("MAX(position) AS position").Where("task_id=?", task.Id).Scan(&last).Error; err != nil {
+			return err
+		}
+		if last.Position != nil {
+			subtask.Position = *last.Position + 1
+		}
+		return tx.Create(&subtask).Error
+	})
+	if errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	c.JSON(http.StatusCreated, newSubtaskResponse(subtask))
+}
+
+func (sc *SubtaskController) Update(c *gin.Context) {
+	subtask, ok := sc.findSubtask(c)
+	if !ok {
+		return
+	}
+
+	var updateReq UpdateSubtaskRequest
+	if err := c.ShouldBindJSON(&updateReq); err != nil {
+		c.JSON(http.StatusBadRequest, bindError(err))
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if updateReq.Title != nil {
+		updates["title"] = strings.TrimSpace(*updateReq.Title)
+	}
+	if updateReq.Done != nil {
+		updates["done"] = *updateReq.Done
+	}
+	sc.save(c, subtask, updates)
+}
+
+// Toggle flips done, so a checkbox tap needs no body.
+func (sc *SubtaskController) Toggle(c *gin.Context) {
+	subtask, ok := sc.findSubtask(c)
+	if !ok {
+		return
+	}
+
+	sc.save(c, subtask, map[string]interface{}{"done": !subtask.Done})
+}
+
+func (sc *SubtaskController) Delete(c *gin.Context) {
+	subtask, ok := sc.findSubtask(c)
+	if !ok {
+		return
+	}
+
+	if errDB := sc.DB.WithContext(c.Request.Context()).Delete(&subtask).Error; errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	c.JSON(http.StatusOK, "Deleted")
+}
+
+// Reorder takes every subtask id of the task in the new order and saves
+// all positions in one transaction.
+func (sc *SubtaskController) Reorder(c *gin.Context) {
+	task, ok := sc.findTask(c)
+	if !ok {
+		return
+	}
+
+	var reorderReq ReorderSubtasksRequest
+	if err := c.ShouldBindJSON(&reorderReq); err != nil {
+		c.JSON(http.StatusBadRequest, bindError(err))
+		return
+	}
+
+	subtasks := []models.Subtask{}
+	errDB := withTx(c.Request.Context(), sc.DB, func(tx *gorm.DB) error {
+		existing := []int{}
+		if err := tx.Model(&models.Subtask{}).Where("task_id=?", task.Id).Pluck("id", &existing).Error; err != nil {
+			return err
+		}
+		if len(existing) != len(reorderReq.Ids) || len(uniqueIds(reorderReq.Ids)) != len(existing) {
+			return errSubtaskSet
+		}
+		belongs := map[int]bool{}
+		for _, id := range existing {
+			belongs[id] = true
+		}
+
+		for position, id := range reorderReq.Ids {
+			if !belongs[id] {
+				return errSubtaskSet
+			}
+			if err := tx.Model(&models.Subtask{}).Where("id=?", id).Update("position", position).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("task_id=?", task.Id).Order("position ASC").Find(&subtasks).Error
+	})
+	if errors.Is(errDB, errSubtaskSet) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "ids must list every subtask of the task exactly once"})
+		return
+	}
+	if errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	responses := make([]SubtaskResponse, 0, len(subtasks))
+	for _, subtask := range subtasks {
+		responses = append(responses, newSubtaskResponse(subtask))
+	}
+	c.JSON(http.StatusOK, responses)
+}
+
+func (sc *SubtaskController) save(c *gin.Context, subtask models.Subtask, updates map[string]interface{}) {
+	if len(updates) > 0 {
+		if errDB := sc.DB.WithContext(c.Request.Context()).Model(&subtask).Updates(updates).Error; errDB != nil {
+			respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, newSubtaskResponse(subtask))
+}
+
+// findTask loads the task from the :id param and checks the caller is its
+// assignee or an Admin, writing the error response when not.
+func (sc *SubtaskController) findTask(c *gin.Context) (models.Task, bool) {
+	task := models.Task{}
+	if err := sc.DB.WithContext(c.Request.Context()).First(&task, c.Param("id")).Error; err != nil {
+		respondDBError(c, err, http.StatusNotFound, "not found")
+		return task, false
+	}
+
+	if c.GetString("role") != models.RoleAdmin && task.UserId != c.GetInt("userId") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the assignee or an Admin can change this checklist"})
+		return task, false
+	}
+	return task, true
+}
+
+func (sc *SubtaskController) findSubtask(c *gin.Context) (models.Subtask, bool) {
+	subtask := models.Subtask{}
+	task, ok := sc.findTask(c)
+	if !ok {
+		return subtask, false
+	}
+
+	if err := sc.DB.WithContext(c.Request.Context()).Where("task_id=?", task.Id).First(&subtask, c.Param("subtaskId")).Error; err != nil {
+		respondDBError(c, err, http.StatusNotFound, "not found")
+		return subtask, false
+	}
+	return subtask, true
+}
+
+// withSubtaskCounts fills SubtaskTotal and SubtaskDone on the responses
+// with a single grouped query.
+func withSubtaskCounts(db *gorm.DB, responses []TaskResponse) []TaskResponse {
+	if len(responses) == 0 {
+		return responses
+	}
+
+	ids := make([]int, 0, len(responses))
+	for _, response := range responses {
+		ids = append(ids, response.Id)
+	}
+
+	counts := []struct {
+		TaskId int
+		Total  int64
+		Done   int64
+	}{}
+	db.Model(&models.Subtask{}).
+		Select("task_id, count(*) as total, COALESCE(SUM(CASE WHEN done THEN 1 ELSE 0 END), 0) as done").
+		Where("task_id IN ?", ids).
+		Group("task_id").
+		Scan(&counts)
+
+	byTask := make(map[int]int, len(counts))
+	for i, count := range counts {
+		byTask[count.TaskId] = i
+	}
+	for i := range responses {
+		if j, ok := byTask[responses[i].Id]; ok {
+			responses[i].SubtaskTotal = counts[j].Total
+			responses[i].SubtaskDone = counts[j].Done
+		}
+	}
+	return responses
+}
+
+// unfinishedSubtasks counts the open checklist items per task.
+func unfinishedSubtasks(db *gorm.DB, taskIds []int) (map[int]int64, error) {
+	counts := []struct {
+		TaskId int
+		Total  int64
+	}{}
+	errDB := db.Model(&models.Subtask{}).
+		Select("task_id, count(*) as total").
+		Where("task_id IN ? AND done = ?", taskIds, false).
+		Group("task_id").
+		Scan(&counts).Error
+	if errDB != nil {
+		return nil, errDB
+	}
+
+	open := make(map[int]int64, len(counts))
+	for _, count := range counts {
+		open[count.TaskId] = count.Total
+	}
+	return open, nil
+}
+
+// forceApprove reports whether an Admin asked with ?force=true to approve
+// tasks whose checklist isn't finished.
+func forceApprove(c *gin.Context) bool {
+	return c.Query("force") == "true" && c.GetString("role") == models.RoleAdmin
+}
@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"tusk/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type TagController struct {
+	DB *gorm.DB
+}
+
+type CreateTagRequest struct {
+	Name string `json:"name" binding:"required,max=50"`
+}
+
+type TagResponse struct {
+	Id        int    `json:"id"`
+	Name      string `json:"name"`
+	TaskCount int64  `json:"taskCount"`
+	CreatedAt string `json:"createdAt"`
+}
+
+func (tc *TagController) Create(c *gin.Context) {
+	var createReq CreateTagRequest
+	if err := c.ShouldBindJSON(&createReq); err != nil {
+		c.JSON(http.StatusBadRequest, bindError(err))
+		return
+	}
+
+	name := normalizeTag(createReq.Name)
+	if name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Request validation failed", "fields": gin.H{"name": "name is required"}})
+		return
+	}
+
+	tag := models.Tag{Name: name}
+	errDB := tc.DB.WithContext(c.Request.Context()).Create(&tag).Error
+	if isDuplicateKey(errDB) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Tag " + name + " already exists"})
+		return
+	}
+	if errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	c.JSON(http.StatusCreated, TagResponse{Id: tag.Id, Name: tag.Name, CreatedAt: formatTime(tag.CreatedAt)})
+}
+
+// List returns every tag by name with how many tasks carry it.
+func (tc *TagController) List(c *gin.Context) {
+	rows := []struct {
+		models.Tag
+		TaskCount int64
+	}{}
+	errDB := tc.DB.WithContext(c.Request.Context()).Model(&models.Tag{}).
+		Select("tags.id, tags.name, tags.created_at, COUNT(task_tags.task_id) AS task_count").
+		Joins("LEFT JOIN task_tags ON task_tags.tag_id = tags.id").
+		Group("tags.id, tags.name, tags.created_at").
+		Order("tags.name ASC").
+		Scan(&rows).Error
+	if errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	tags := make([]TagResponse, 0, len(rows))
+	for _, row := range rows {
+		tags = append(tags, TagResponse{
+			Id:        row.Id,
+			Name:      row.Name,
+			TaskCount: row.TaskCount,
+			CreatedAt: formatTime(row.CreatedAt),
+		})
+	}
+	c.JSON(http.StatusOK, tags)
+}
+
+// Delete removes the tag from every task first; the tasks stay.
+func (tc *TagController) Delete(c *gin.Context) {
+	tag := models.Tag{}
+	if err := tc.DB.WithContext(c.Request.Context()).First(&tag, c.Param("id")).Error; err != nil {
+		respondDBError(c, err, http.StatusNotFound, "not found")
+		return
+	}
+
+	errDB := withTx(c.Request.Context(), tc.DB, func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM task_tags WHERE tag_id = ?", tag.Id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&tag).Error
+	})
+	if errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	c.JSON(http.StatusOK, "Deleted")
+}
+
+func normalizeTag(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func normalizeTags(names []string) []string {
+	normalized := []string{}
+	for _, name := range names {
+		if name = normalizeTag(name); name != "" {
+			normalized = append(normalized, name)
+		}
+	}
+	return normalized
+}
+
+func uniqueStrings(values []string) []string {
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
+
+// resolveTags loads the tags named or referenced by id. Anything that
+// doesn't exist is returned in unknown instead, since only Admins create tags.
+func resolveTags(db *gorm.DB, names []string, ids []int) ([]models.Tag, []string, error) {
+	tags := []models.Tag{}
+	names = normalizeTags(names)
+	if len(names) == 0 && len(ids) == 0 {
+		return tags, nil, nil
+	}
+
+	if err := db.Where("name IN ? OR id IN ?", names, ids).Find(&tags).Error; err != nil {
+		return nil, nil, err
+	}
+
+	foundNames := map[string]bool{}
+	foundIds := map[int]bool{}
+	for _, tag := range tags {
+		foundNames[tag.Name] = true
+		foundIds[tag.Id] = true
+	}
+	unknown := []string{}
+	for _, name := range names {
+		if !foundNames[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	for _, id := range ids {
+		if !foundIds[id] {
+			unknown = append(unknown, "#"+strconv.Itoa(id))
+		}
+	}
+	return tags, unknown, nil
+}
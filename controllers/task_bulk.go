@@ -0,0 +1,234 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"tusk/events"
+	"tusk/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	bulkAssign    = "assign"
+	bulkSetStatus = "setStatus"
+	bulkDelete    = "delete"
+)
+
+var errTooManySkipped = errors.New("too many tasks skipped")
+
+type BulkTaskRequest struct {
+	Ids    []int  `json:"ids" binding:"required,min=1,dive,min=1"`
+	Action string `json:"action" binding:"required,oneof=assign setStatus delete"`
+	UserId int    `json:"userId" binding:"required_if=Action assign"`
+	Status string `json:"status" binding:"required_if=Action setStatus"`
+}
+
+type BulkSkipped struct {
+	Id     int    `json:"id"`
+	Reason string `json:"reason"`
+}
+
+// Bulk applies one action to many tasks in a single transaction. Tasks that
+// can't take the action are skipped and reported; when more than
+// BulkConfig.MaxFailure of them are skipped nothing is applied at all.
+func (t *TaskController) Bulk(c *gin.Context) {
+	var bulkReq BulkTaskRequest
+	if err := c.ShouldBindJSON(&bulkReq); err != nil {
+		c.JSON(http.StatusBadRequest, bindError(err))
+		return
+	}
+
+	ids := uniqueIds(bulkReq.Ids)
+	if len(ids) > t.BulkConfig.MaxIds {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At most " + strconv.Itoa(t.BulkConfig.MaxIds) + " tasks can be changed at once"})
+		return
+	}
+
+	// assignee is the same for every task, so it's checked once up front
+	assigneeError := ""
+	if bulkReq.Action == bulkAssign {
+		status, message := t.checkAssignee(c, bulkReq.UserId)
+		if status == http.StatusInternalServerError {
+			c.JSON(status, gin.H{"error": message})
+			return
+		}
+		assigneeError = message
+	}
+
+	userId := c.GetInt("userId")
+	affected := []models.Task{}
+	skipped := []BulkSkipped{}
+	attachmentPaths := []string{}
+
+	errTx := withTx(c.Request.Context(), t.DB, func(tx *gorm.DB) error {
+		tasks := []models.Task{}
+		if err := tx.Where("id IN ?", ids).Find(&tasks).Error; err != nil {
+			return err
+		}
+		found := map[int]models.Task{}
+		for _, task := range tasks {
+			found[task.Id] = task
+		}
+
+		open := map[int]int64{}
+		if bulkReq.Action == bulkSetStatus && bulkReq.Status == models.StatusApproved && !forceApprove(c) {
+			var err error
+			if open, err = unfinishedSubtasks(tx, ids); err != nil {
+				return err
+			}
+		}
+
+		// tasks sharing a current status move together, one UPDATE per status
+		byStatus := map[string][]int{}
+		changed := []int{}
+		for _, id := range ids {
+			task, ok := found[id]
+			if !ok {
+				skipped = append(skipped, BulkSkipped{Id: id, Reason: "not found"})
+				continue
+			}
+
+			switch bulkReq.Action {
+			case bulkAssign:
+				if assigneeError != "" {
+					skipped = append(skipped, BulkSkipped{Id: id, Reason: assigneeError})
+					continue
+				}
+				if task.Status == models.StatusApproved {
+					skipped = append(skipped, BulkSkipped{Id: id, Reason: "Task is already " + task.Status + " and can't be reassigned"})
+					continue
+				}
+				if task.UserId != bulkReq.UserId {
+					changed = append(changed, id)
+				}
+			case bulkSetStatus:
+				if errTransition := checkTransition(task, c.GetString("role"), userId, bulkReq.Status); errTransition != nil {
+					skipped = append(skipped, BulkSkipped{Id: id, Reason: errTransition.Message})
+					continue
+				}
+				if open[id] > 0 {
+					skipped = append(skipped, BulkSkipped{Id: id, Reason: "Task still has unfinished subtasks"})
+					continue
+				}
+				byStatus[task.Status] = append(byStatus[task.Status], id)
+			case bulkDelete:
+				changed = append(changed, id)
+			}
+			affected = append(affected, task)
+		}
+
+		if float64(len(skipped)) > t.BulkConfig.MaxFailure*float64(len(ids)) {
+			return errTooManySkipped
+		}
+
+		switch bulkReq.Action {
+		case bulkAssign:
+			if len(changed) == 0 {
+				return nil
+			}
+			// previous_user_id is assigned first so it still sees the old user_id
+			return tx.Model(&models.Task{}).Where("id IN ?", changed).Updates(map[string]interface{}{
+				"previous_user_id": gorm.Expr("user_id"),
+				"user_id":          bulkReq.UserId,
+			}).Error
+		case bulkSetStatus:
+			for from, group := range byStatus {
+				if err := moveTasks(tx, group, from, bulkReq.Status, userId); err != nil {
+					return err
+				}
+			}
+			return nil
+		default:
+			var err error
+			attachmentPaths, err = deleteTasks(tx, changed)
+			return err
+		}
+	})
+	if errors.Is(errTx, errTooManySkipped) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":      "Too many tasks can't take this action, nothing was changed",
+			"rolledBack": true,
+			"affected":   []int{},
+			"skipped":    skipped,
+		})
+		return
+	}
+	if errors.Is(errTx, errStatusChanged) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Task status changed concurrently, please retry"})
+		return
+	}
+	if errTx != nil {
+		respondDBError(c, errTx, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	affectedIds := make([]int, 0, len(affected))
+	for _, task := range affected {
+		affectedIds = append(affectedIds, task.Id)
+	}
+	t.afterBulk(c, bulkReq, affected, attachmentPaths)
+
+	c.JSON(http.StatusOK, gin.H{
+		"rolledBack": false,
+		"affected":   affectedIds,
+		"skipped":    skipped,
+	})
+}
+
+// afterBulk does what the single-task endpoints do once a change is
+// committed: clean up files, notify assignees and publish events.
+func (t *TaskController) afterBulk(c *gin.Context, bulkReq BulkTaskRequest, affected []models.Task, attachmentPaths []string) {
+	if bulkReq.Action == bulkDelete {
+		removeFiles(t.UploadDir, attachmentPaths)
+		for _, task := range affected {
+			if task.Attachment != "" {
+				os.Remove("attachments/" + task.Attachment)
+			}
+		}
+		return
+	}
+
+	ids := make([]int, 0, len(affected))
+	previousUser := map[int]int{}
+	for _, task := range affected {
+		ids = append(ids, task.Id)
+		previousUser[task.Id] = task.UserId
+	}
+
+	tasks := []models.Task{}
+	if err := t.DB.WithContext(c.Request.Context()).Preload("User").Preload("Tags").Where("id IN ?", ids).Find(&tasks).Error; err != nil {
+		return
+	}
+	for _, task := range tasks {
+		if bulkReq.Action == bulkSetStatus {
+			t.notifyStatusChange(task)
+			t.publishTask(events.TaskStatusChanged, task)
+			continue
+		}
+		if previousUser[task.Id] != task.UserId {
+			t.notifyAssignee(task, "New task assigned", task.Title, "task_assigned")
+			t.publishReassigned(task, previousUser[task.Id])
+			continue
+		}
+		t.publishTask(events.TaskUpdated, task)
+	}
+}
+
+// uniqueIds drops repeated ids, keeping them in ascending order.
+func uniqueIds(ids []int) []int {
+	seen := map[int]bool{}
+	unique := []int{}
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	sort.Ints(unique)
+	return unique
+}
@@ -1,215 +1,932 @@
 package controllers
 
 import (
+	"encoding/csv"
+	"errors"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
+	"tusk/config"
+	"tusk/events"
+	"tusk/mailer"
+	"tusk/metrics"
+	"tusk/middlewares"
 	"tusk/models"
+	"tusk/notifications"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
 type TaskController struct {
-	DB *gorm.DB
+	DB             *gorm.DB
+	Assigner       *AutoAssigner
+	ForecastConfig config.ForecastConfig
+	UploadDir      string
+	EvidenceMax    int64
+	Attachments    *AttachmentController // stores files of the legacy submit
+	Notifier       notifications.Notifier
+	Mailer         mailer.Mailer
+	Events         *events.Hub
+	BulkConfig     config.BulkConfig
+	Metrics        *metrics.Metrics
+}
+
+type CreateTaskRequest struct {
+	Title       string     `json:"title" binding:"required,max=255"`
+	Description string     `json:"description"`
+	UserId      int        `json:"userId"`
+	DueDate     *time.Time `json:"dueDate" binding:"omitempty,future"`
+	Estimate    int        `json:"estimate" binding:"min=0"`
+	Priority    string     `json:"priority" binding:"omitempty,priority"`
+	Tags        []string   `json:"tags"`   // tag names
+	TagIds      []int      `json:"tagIds"` // or ids, both may be combined
+	ClientToken string     `json:"clientToken" binding:"max=64"`
+	AutoAssign  bool       `json:"autoAssign"`
+}
+
+// UpdateTaskRequest fields are optional; nil leaves the value unchanged.
+type UpdateTaskRequest struct {
+	Title       *string    `json:"title" binding:"omitempty,min=1,max=255"`
+	Description *string    `json:"description"`
+	DueDate     *time.Time `json:"dueDate"`
+	Estimate    *int       `json:"estimate" binding:"omitempty,min=0"`
+	Priority    *string    `json:"priority" binding:"omitempty,priority"`
+	Tags        *[]string  `json:"tags"` // replaces the task's tags when set
+	TagIds      *[]int     `json:"tagIds"`
+}
+
+type UpdateStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+}
+
+type RejectTaskRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+type AssignTaskRequest struct {
+	UserId int `json:"userId" binding:"required"`
+}
+
+type TaskResponse struct {
+	Id              int           `json:"id"`
+	UserId          int           `json:"userId"`
+	PreviousUserId  *int          `json:"previousUserId"`
+	Title           string        `json:"title"`
+	Description     string        `json:"description"`
+	Status          string        `json:"status"`
+	Priority        string        `json:"priority"`
+	Reason          string        `json:"reason"`
+	Revision        int8          `json:"revision"`
+	DueDate         *string       `json:"dueDate"`
+	IsOverdue       bool          `json:"isOverdue"`
+	Estimate        int           `json:"estimate"`
+	SubmitDate      string        `json:"submitDate"`
+	RejectedDate    string        `json:"rejectedDate"`
+	ApprovedDate    string        `json:"approvedDate"`
+	StatusChangedAt *string       `json:"statusChangedAt"`
+	StatusChangedBy *int          `json:"statusChangedBy"`
+	Attachment      string        `json:"attachment"`
+	EvidencePath    string        `json:"evidencePath"`
+	SubmittedAt     *string       `json:"submittedAt"`
+	SubmitNote      string        `json:"submitNote"`
+	AutoAssigned    bool          `json:"autoAssigned"`
+	Tags            []string      `json:"tags"`
+	CommentCount    int64         `json:"commentCount"`
+	SubtaskTotal    int64         `json:"subtaskTotal"`
+	SubtaskDone     int64         `json:"subtaskDone"`
+	CreatedAt       string        `json:"createdAt"`
+	UpdatedAt       string        `json:"updatedAt"`
+	User            *UserResponse `json:"user,omitempty"`
+}
+
+func newTaskResponse(task models.Task) TaskResponse {
+	response := TaskResponse{
+		Id:              task.Id,
+		UserId:          task.UserId,
+		PreviousUserId:  task.PreviousUserId,
+		Title:           task.Title,
+		Description:     task.Description,
+		Status:          task.Status,
+		Priority:        models.PriorityName(task.Priority),
+		Reason:          task.Reason,
+		Revision:        task.Revision,
+		IsOverdue:       task.IsOverdue(time.Now().UTC()),
+		Estimate:        task.Estimate,
+		SubmitDate:      task.SubmitDate,
+		RejectedDate:    task.RejectedDate,
+		ApprovedDate:    task.ApprovedDate,
+		StatusChangedBy: task.StatusChangedBy,
+		Attachment:      task.Attachment,
+		EvidencePath:    task.EvidencePath,
+		SubmitNote:      task.SubmitNote,
+		AutoAssigned:    task.AutoAssigned,
+		CreatedAt:       formatTime(task.CreatedAt),
+		UpdatedAt:       formatTime(task.UpdatedAt),
+	}
+	response.Tags = make([]string, 0, len(task.Tags))
+	for _, tag := range task.Tags {
+		response.Tags = append(response.Tags, tag.Name)
+	}
+	if task.DueDate != nil {
+		dueDate := formatTime(*task.DueDate)
+		response.DueDate = &dueDate
+	}
+	if task.StatusChangedAt != nil {
+		changedAt := formatTime(*task.StatusChangedAt)
+		response.StatusChangedAt = &changedAt
+	}
+	if task.SubmittedAt != nil {
+		submittedAt := formatTime(*task.SubmittedAt)
+		response.SubmittedAt = &submittedAt
+	}
+	if task.User.Id != 0 {
+		user := newUserResponse(task.User)
+		response.User = &user
+	}
+
+	return response
+}
+
+func newTaskResponses(tasks []models.Task) []TaskResponse {
+	responses := make([]TaskResponse, 0, len(tasks))
+	for _, task := range tasks {
+		responses = append(responses, newTaskResponse(task))
+	}
+	return responses
 }
 
 func (t *TaskController) Create(c *gin.Context) {
-	task := models.Task{}
-	errBindJson := c.ShouldBindJSON(&task)
-	if errBindJson != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": errBindJson.Error()})
+	var createReq CreateTaskRequest
+	if err := c.ShouldBindJSON(&createReq); err != nil {
+		c.JSON(http.StatusBadRequest, bindError(err))
 		return
 	}
 
-	errDB := t.DB.Create(&task).Error
+	autoAssign := createReq.UserId == 0 && t.Assigner.Enabled(createReq.AutoAssign)
+	if createReq.UserId == 0 && !autoAssign {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "userId is required"})
+		return
+	}
+	if !autoAssign {
+		if status, message := t.checkAssignee(c, createReq.UserId); status != 0 {
+			c.JSON(status, gin.H{"error": message})
+			return
+		}
+	}
+
+	// retry with the same clientToken returns the task created the first time
+	var clientToken *string
+	if createReq.ClientToken != "" {
+		clientToken = &createReq.ClientToken
+	}
+	if existing, found := t.findByClientToken(c, createReq.UserId, clientToken); found {
+		c.JSON(http.StatusOK, newTaskResponse(existing))
+		return
+	}
+
+	priority := models.PriorityMedium
+	if createReq.Priority != "" {
+		priority, _ = models.ParsePriority(createReq.Priority)
+	}
+
+	tags, ok := t.resolveTags(c, createReq.Tags, createReq.TagIds)
+	if !ok {
+		return
+	}
+
+	// due dates arrive as RFC3339 with any offset and are stored in UTC
+	if createReq.DueDate != nil {
+		dueDate := createReq.DueDate.UTC()
+		createReq.DueDate = &dueDate
+	}
+	task := models.Task{
+		UserId:      createReq.UserId,
+		Title:       createReq.Title,
+		Description: createReq.Description,
+		Status:      models.StatusQueue,
+		Priority:    priority,
+		Tags:        tags,
+		DueDate:     createReq.DueDate,
+		Estimate:    createReq.Estimate,
+		ClientToken: clientToken,
+	}
+
+	// the task, its tags and its activity entry are written together
+	errDB := withTx(c.Request.Context(), t.DB, func(tx *gorm.DB) error {
+		if autoAssign {
+			return t.Assigner.Create(tx, &task)
+		}
+		return tx.Create(&task).Error
+	})
+	if errors.Is(errDB, errNoAssignee) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": errDB.Error()})
+		return
+	}
 	if errDB != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": errDB.Error()})
+		// a concurrent retry may have won the unique index
+		if existing, found := t.findByClientToken(c, createReq.UserId, clientToken); found {
+			c.JSON(http.StatusOK, newTaskResponse(existing))
+			return
+		}
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
 		return
 	}
 
-	c.JSON(http.StatusCreated, task)
+	t.Metrics.TaskCreated()
+	t.notifyAssignee(task, "New task assigned", task.Title, "task_assigned")
+	t.publishTask(events.TaskCreated, task)
+	c.JSON(http.StatusCreated, newTaskResponse(task))
 }
 
-func (t *TaskController) Delete(c *gin.Context) {
-	id := c.Param("id")
-	task := models.Task{}
+// GetAll lists the filtered tasks. With ?cursor= or ?limit= it returns one
+// page of {data, nextCursor, limit}, newest first; pass nextCursor back as
+// ?cursor= until it is null. Without either the whole list is returned as
+// before.
+func (t *TaskController) GetAll(c *gin.Context) {
+	tasks := []models.Task{}
+
+	// ?sort=priority puts the most urgent first, then the earliest due date,
+	// tasks without one last
+	order := "tasks.created_at DESC, tasks.id DESC"
+	if c.Query("sort") == "priority" {
+		order = "tasks.priority DESC, tasks.due_date IS NULL, tasks.due_date ASC, tasks.id ASC"
+	}
+
+	cursorParam, hasCursor := c.GetQuery("cursor")
+	_, hasLimit := c.GetQuery("limit")
+	if !hasCursor && !hasLimit {
+		errDB := t.filterTasks(c).Preload("User").Preload("Tags").Order(order).Find(&tasks).Error
+		if errDB != nil {
+			respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+			return
+		}
+
+		c.JSON(http.StatusOK, withCounts(t.DB.WithContext(c.Request.Context()), newTaskResponses(tasks)))
+		return
+	}
 
-	if err := t.DB.First(&task, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	if c.Query("sort") == "priority" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort=priority can't be combined with cursor pagination"})
 		return
 	}
 
-	errDB := t.DB.Delete(&models.Task{}, id).Error
+	limit, errLimit := strconv.Atoi(c.Query("limit"))
+	if errLimit != nil || limit < 1 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	query := t.filterTasks(c)
+	if cursorParam != "" {
+		cursor, errCursor := decodeTaskCursor(cursorParam)
+		if errCursor != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor"})
+			return
+		}
+		query = afterTaskCursor(query, cursor)
+	}
+
+	// one extra row tells whether there is a next page
+	errDB := query.Preload("User").Preload("Tags").Order(order).Limit(limit + 1).Find(&tasks).Error
 	if errDB != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": errDB.Error()})
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
 		return
 	}
 
-	if task.Attachment != "" {
-		os.Remove("attachments/" + task.Attachment)
+	var nextCursor *string
+	if len(tasks) > limit {
+		tasks = tasks[:limit]
+		next := encodeTaskCursor(tasks[limit-1])
+		nextCursor = &next
 	}
 
-	c.JSON(http.StatusOK, "Deleted")
+	c.JSON(http.StatusOK, gin.H{
+		"data":       withCounts(t.DB.WithContext(c.Request.Context()), newTaskResponses(tasks)),
+		"nextCursor": nextCursor,
+		"limit":      limit,
+	})
 }
 
-func (t *TaskController) Submit(c *gin.Context) {
-	task := models.Task{}
-	id := c.Param("id")
-	submitDate := c.PostForm("submitDate")
-	file, errFile := c.FormFile("attachment")
-	if errFile != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": errFile.Error()})
+// Export streams the filtered task list as CSV one row at a time.
+func (t *TaskController) Export(c *gin.Context) {
+	if c.DefaultQuery("format", "csv") != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv"})
 		return
 	}
 
-	if err := t.DB.First(&task, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	location := requestLocation(c, t.DB)
+	rows, errDB := t.filterTasks(c).
+		Model(&models.Task{}).
+		Select("tasks.id, tasks.title, tasks.description, tasks.status, tasks.user_id, users.name AS assignee, tasks.due_date, tasks.estimate, tasks.revision, tasks.created_at, tasks.updated_at").
+		Joins("LEFT JOIN users ON users.id = tasks.user_id").
+		Order("tasks.id ASC").
+		Rows()
+	if errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
 		return
 	}
+	defer rows.Close()
 
-	// remove old attachment
-	attachment := task.Attachment
-	fileInfo, _ := os.Stat("attachments/" + attachment)
-	if fileInfo != nil {
-		// found
-		os.Remove("attachments/" + attachment)
-	}
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", `attachment; filename="tasks-`+time.Now().UTC().Format("2006-01-02")+`.csv"`)
+	c.Status(http.StatusOK)
 
-	// create new attaachment
-	attachment = file.Filename
-	errSave := c.SaveUploadedFile(file, "attachments/"+attachment)
-	if errSave != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": errSave.Error()})
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{"id", "title", "description", "status", "userId", "assignee", "dueDate", "estimate", "revision", "createdAt", "updatedAt"})
+	for rows.Next() {
+		var row struct {
+			models.Task
+			Assignee string
+		}
+		if err := t.DB.ScanRows(rows, &row); err != nil {
+			if !dropStream(c, err) {
+				respondDBError(c, err, http.StatusInternalServerError, internalError)
+			}
+			return
+		}
+
+		dueDate := ""
+		if row.DueDate != nil {
+			dueDate = row.DueDate.In(location).Format(time.RFC3339)
+		}
+		writer.Write([]string{
+			strconv.Itoa(row.Id),
+			row.Title,
+			row.Description,
+			row.Status,
+			strconv.Itoa(row.UserId),
+			row.Assignee,
+			dueDate,
+			strconv.Itoa(row.Estimate),
+			strconv.Itoa(int(row.Revision)),
+			row.CreatedAt.In(location).Format(time.RFC3339),
+			row.UpdatedAt.In(location).Format(time.RFC3339),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		if !dropStream(c, err) {
+			respondDBError(c, err, http.StatusInternalServerError, internalError)
+		}
 		return
 	}
+	writer.Flush()
+}
+
+// filterTasks applies the status, userId, departmentId, dueAfter and
+// dueBefore filters shared by the list and the export, within what
+// scopeTasks lets the caller see.
+func (t *TaskController) filterTasks(c *gin.Context) *gorm.DB {
+	query := t.scopeTasks(c)
+
+	if status := c.Query("status"); status != "" {
+		query = query.Where("tasks.status=?", status)
+	}
+	if userId := c.Query("userId"); userId != "" {
+		query = query.Where("tasks.user_id=?", userId)
+	}
+	// tags=a,b needs every tag, anyTag=a,b at least one of them
+	if names := normalizeTags(strings.Split(c.Query("tags"), ",")); len(names) > 0 {
+		query = query.Where("tasks.id IN (?)", t.DB.Table("task_tags").
+			Select("task_tags.task_id").
+			Joins("JOIN tags ON tags.id = task_tags.tag_id").
+			Where("tags.name IN ?", names).
+			Group("task_tags.task_id").
+			Having("COUNT(DISTINCT tags.id) = ?", len(uniqueStrings(names))))
+	}
+	if names := normalizeTags(strings.Split(c.Query("anyTag"), ",")); len(names) > 0 {
+		query = query.Where("tasks.id IN (?)", t.DB.Table("task_tags").
+			Select("task_tags.task_id").
+			Joins("JOIN tags ON tags.id = task_tags.tag_id").
+			Where("tags.name IN ?", names))
+	}
+	if name := c.Query("priority"); name != "" {
+		// an unknown name matches nothing, like an unknown status
+		priority, _ := models.ParsePriority(name)
+		query = query.Where("tasks.priority=?", priority)
+	}
+	// date-only values are days in the caller's timezone
+	location := time.UTC
+	if c.Query("dueAfter") != "" || c.Query("dueBefore") != "" {
+		location = requestLocation(c, t.DB)
+	}
+	if dueAfter, ok := parseDateFilter(c.Query("dueAfter"), location); ok {
+		query = query.Where("tasks.due_date >= ?", dueAfter)
+	}
+	if dueBefore, ok := parseDateFilter(c.Query("dueBefore"), location); ok {
+		// a date without a time covers the whole day
+		if len(c.Query("dueBefore")) == len("2006-01-02") {
+			dueBefore = dueBefore.AddDate(0, 0, 1)
+		}
+		query = query.Where("tasks.due_date < ?", dueBefore)
+	}
+
+	return query
+}
+
+// scopeTasks starts a task query limited to what the caller may see:
+// Employees their own tasks, Managers those of their department's members
+// and Admins everything, or one department with ?departmentId=.
+func (t *TaskController) scopeTasks(c *gin.Context) *gorm.DB {
+	query := t.DB.WithContext(c.Request.Context())
+	if c.GetString("role") == models.RoleEmployee {
+		return query.Where("tasks.user_id = ?", c.GetInt("userId"))
+	}
+	if departmentId, scoped := departmentScope(c); scoped {
+		return query.Where("tasks.user_id IN (?)", t.DB.Model(&models.User{}).Select("id").Where("department_id = ?", departmentId))
+	}
+	return query
+}
 
-	errDB := t.DB.Where("id=?", id).Updates(models.Task{
-		Status:     "Review",
-		SubmitDate: submitDate,
-		Attachment: attachment,
-	}).Error
+// Overdue lists unapproved tasks whose due date has passed, most overdue
+// first. Filter by assignee with ?userId=.
+func (t *TaskController) Overdue(c *gin.Context) {
+	tasks := []models.Task{}
+	query := t.scopeTasks(c).Preload("User").Preload("Tags").
+		Where("status<>? AND due_date IS NOT NULL AND due_date < ?", models.StatusApproved, time.Now().UTC())
+
+	if userId := c.Query("userId"); userId != "" {
+		query = query.Where("user_id=?", userId)
+	}
+
+	errDB := query.Order("due_date ASC").Find(&tasks).Error
 	if errDB != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": errDB.Error()})
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
 		return
 	}
 
-	c.JSON(http.StatusOK, "Submit to Review")
+	c.JSON(http.StatusOK, withCounts(t.DB.WithContext(c.Request.Context()), newTaskResponses(tasks)))
 }
 
-func (t *TaskController) Reject(c *gin.Context) {
+func (t *TaskController) Update(c *gin.Context) {
 	task := models.Task{}
 	id := c.Param("id")
-	reason := c.PostForm("reason")
-	rejectedDate := c.PostForm("rejectedDate")
 
-	if err := t.DB.First(&task, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	var updateReq UpdateTaskRequest
+	if err := c.ShouldBindJSON(&updateReq); err != nil {
+		c.JSON(http.StatusBadRequest, bindError(err))
+		return
+	}
+
+	if err := t.DB.WithContext(c.Request.Context()).First(&task, id).Error; err != nil {
+		respondDBError(c, err, http.StatusNotFound, "not found")
+		return
+	}
+
+	// only fields present in the body are changed
+	updates := map[string]interface{}{}
+	if updateReq.Title != nil {
+		updates["title"] = *updateReq.Title
+	}
+	if updateReq.Description != nil {
+		updates["description"] = *updateReq.Description
+	}
+	if updateReq.DueDate != nil {
+		updates["due_date"] = updateReq.DueDate.UTC()
+	}
+	if updateReq.Estimate != nil {
+		updates["estimate"] = *updateReq.Estimate
+	}
+	if updateReq.Priority != nil {
+		updates["priority"], _ = models.ParsePriority(*updateReq.Priority)
+	}
+
+	var tags []models.Tag
+	if updateReq.Tags != nil || updateReq.TagIds != nil {
+		names, ids := []string{}, []int{}
+		if updateReq.Tags != nil {
+			names = *updateReq.Tags
+		}
+		if updateReq.TagIds != nil {
+			ids = *updateReq.TagIds
+		}
+		var ok bool
+		if tags, ok = t.resolveTags(c, names, ids); !ok {
+			return
+		}
+	}
+
+	errDB := withTx(c.Request.Context(), t.DB, func(tx *gorm.DB) error {
+		if len(updates) > 0 {
+			if err := tx.Model(&task).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		if tags != nil {
+			return tx.Model(&task).Association("Tags").Replace(tags)
+		}
+		return nil
+	})
+	if errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
 		return
 	}
 
-	errDB := t.DB.Where("id=?", id).Updates(models.Task{
-		Status:       "Rejected",
-		Reason:       reason,
-		RejectedDate: rejectedDate,
-	}).Error
+	errDB = t.DB.WithContext(c.Request.Context()).Preload("User").Preload("Tags").First(&task, id).Error
 	if errDB != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": errDB.Error()})
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
 		return
 	}
 
-	c.JSON(http.StatusOK, "Rejected")
+	t.publishTask(events.TaskUpdated, task)
+	c.JSON(http.StatusOK, newTaskResponse(task))
 }
 
-func (t *TaskController) Fix(c *gin.Context) {
+// resolveTags looks up the tags for a create or update, answering 422 when
+// some don't exist.
+func (t *TaskController) resolveTags(c *gin.Context, names []string, ids []int) ([]models.Tag, bool) {
+	tags, unknown, errDB := resolveTags(t.DB.WithContext(c.Request.Context()), names, ids)
+	if errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return nil, false
+	}
+	if len(unknown) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Unknown tags: " + strings.Join(unknown, ", ")})
+		return nil, false
+	}
+	return tags, true
+}
+
+func (t *TaskController) Assign(c *gin.Context) {
+	task := models.Task{}
 	id := c.Param("id")
-	revision, errConv := strconv.Atoi(c.PostForm("revision"))
-	if errConv != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": errConv.Error()})
+
+	var assignReq AssignTaskRequest
+	if err := c.ShouldBindJSON(&assignReq); err != nil {
+		c.JSON(http.StatusBadRequest, bindError(err))
+		return
+	}
+
+	if err := t.DB.WithContext(c.Request.Context()).First(&task, id).Error; err != nil {
+		respondDBError(c, err, http.StatusNotFound, "not found")
+		return
+	}
+
+	if task.Status == models.StatusApproved {
+		c.JSON(http.StatusConflict, gin.H{"error": "Task is already " + task.Status + " and can't be reassigned"})
 		return
 	}
 
-	if err := t.DB.First(&models.Task{}, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	if status, message := t.checkAssignee(c, assignReq.UserId); status != 0 {
+		c.JSON(status, gin.H{"error": message})
 		return
 	}
 
-	errDB := t.DB.Where("id=?", id).Updates(models.Task{
-		Status:   "Queue",
-		Revision: int8(revision),
-	}).Error
+	previousUserId := task.UserId
+	if task.UserId != assignReq.UserId {
+		errDB := t.DB.WithContext(c.Request.Context()).Model(&task).Updates(map[string]interface{}{
+			"user_id":          assignReq.UserId,
+			"previous_user_id": previousUserId,
+		}).Error
+		if errDB != nil {
+			respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+			return
+		}
+		task.UserId = assignReq.UserId
+		t.notifyAssignee(task, "New task assigned", task.Title, "task_assigned")
+	}
+
+	errDB := t.DB.WithContext(c.Request.Context()).Preload("User").Preload("Tags").First(&task, id).Error
 	if errDB != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": errDB.Error()})
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
 		return
 	}
 
-	c.JSON(http.StatusOK, "Fix to Queue")
+	if task.UserId != previousUserId {
+		t.publishReassigned(task, previousUserId)
+	} else {
+		t.publishTask(events.TaskUpdated, task)
+	}
+	c.JSON(http.StatusOK, newTaskResponse(task))
 }
 
-func (t *TaskController) Approve(c *gin.Context) {
-	id := c.Param("id")
-	approvedDate := c.PostForm("approvedDate")
+func (t *TaskController) UpdateStatus(c *gin.Context) {
+	var statusReq UpdateStatusRequest
+	if err := c.ShouldBindJSON(&statusReq); err != nil {
+		c.JSON(http.StatusBadRequest, bindError(err))
+		return
+	}
 
-	if err := t.DB.First(&models.Task{}, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	task, ok := t.transition(c, statusReq.Status, nil, nil)
+	if !ok {
 		return
 	}
+	c.JSON(http.StatusOK, newTaskResponse(task))
+}
+
+// checkSubtasksDone answers 409 and returns false when the task still has
+// unfinished subtasks, unless an Admin forces the approval.
+func (t *TaskController) checkSubtasksDone(c *gin.Context, taskId int) bool {
+	if forceApprove(c) {
+		return true
+	}
 
-	errDB := t.DB.Where("id=?", id).Updates(models.Task{
-		Status:       "Approved",
-		ApprovedDate: approvedDate,
-	}).Error
+	open, errDB := unfinishedSubtasks(t.DB.WithContext(c.Request.Context()), []int{taskId})
 	if errDB != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": errDB.Error()})
-		return
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return false
 	}
+	if open[taskId] > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":              "Task still has unfinished subtasks",
+			"unfinishedSubtasks": open[taskId],
+		})
+		return false
+	}
+	return true
+}
 
-	c.JSON(http.StatusOK, "Approved")
+// checkAssignee returns a non-zero status when userId can't be given a task.
+func (t *TaskController) checkAssignee(c *gin.Context, userId int) (int, string) {
+	user := models.User{}
+	if err := t.DB.WithContext(c.Request.Context()).First(&user, userId).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return http.StatusUnprocessableEntity, "Assigned user not found"
+		}
+		middlewares.Logger(c).Error("database error", "error", err)
+		return http.StatusInternalServerError, internalError
+	}
+	if user.Role != models.RoleEmployee {
+		return http.StatusUnprocessableEntity, "Tasks can only be assigned to an Employee"
+	}
+	if !user.IsActive {
+		return http.StatusUnprocessableEntity, "Assigned user is deactivated"
+	}
+
+	return 0, ""
 }
 
-func (t *TaskController) FindById(c *gin.Context) {
+// findByClientToken finds the task created earlier for userId with
+// clientToken; userId 0 looks among the auto-assigned tasks.
+func (t *TaskController) findByClientToken(c *gin.Context, userId int, clientToken *string) (models.Task, bool) {
 	task := models.Task{}
+	if clientToken == nil {
+		return task, false
+	}
+
+	query := t.DB.WithContext(c.Request.Context()).Where("client_token=?", *clientToken)
+	if userId == 0 {
+		query = query.Where("auto_assigned=?", true)
+	} else {
+		query = query.Where("user_id=?", userId)
+	}
+	errDB := query.First(&task).Error
+	return task, errDB == nil
+}
+
+func (t *TaskController) Delete(c *gin.Context) {
 	id := c.Param("id")
+	task := models.Task{}
 
-	if err := t.DB.First(&models.Task{}, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	if err := t.DB.WithContext(c.Request.Context()).First(&task, id).Error; err != nil {
+		respondDBError(c, err, http.StatusNotFound, "not found")
 		return
 	}
 
-	errDB := t.DB.Preload("User").Find(&task, id).Error
+	var attachmentPaths []string
+	errDB := withTx(c.Request.Context(), t.DB, func(tx *gorm.DB) error {
+		var err error
+		attachmentPaths, err = deleteTasks(tx, []int{task.Id})
+		return err
+	})
 	if errDB != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": errDB.Error()})
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	// files go only once the rows are gone for good
+	removeFiles(t.UploadDir, attachmentPaths)
+	if task.Attachment != "" {
+		os.Remove("attachments/" + task.Attachment)
+	}
+
+	c.JSON(http.StatusOK, "Deleted")
+}
+
+// deleteTasks removes tasks with everything hanging off them and returns
+// the attachment paths to remove from disk after the commit.
+func deleteTasks(tx *gorm.DB, ids []int) ([]string, error) {
+	paths := []string{}
+	if err := tx.Model(&models.Attachment{}).Where("task_id IN ?", ids).Pluck("path", &paths).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("task_id IN ?", ids).Delete(&models.Attachment{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("task_id IN ?", ids).Delete(&models.Comment{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Exec("DELETE FROM task_tags WHERE task_id IN ?", ids).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("task_id IN ?", ids).Delete(&models.Subtask{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("task_id IN ?", ids).Delete(&models.TaskActivity{}).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("task_id IN ?", ids).Delete(&models.TaskReminder{}).Error; err != nil {
+		return nil, err
+	}
+	return paths, tx.Delete(&models.Task{}, ids).Error
+}
+
+// Submit is the older form of SubmitEvidence: it moves the caller's task
+// from InProgress to Review with a file in "attachment", which is stored
+// as a regular attachment.
+func (t *TaskController) Submit(c *gin.Context) {
+	file, mime, ok := t.Attachments.receive(c, "attachment")
+	if !ok {
+		return
+	}
+
+	stored := ""
+	_, ok = t.transition(c, models.StatusReview, map[string]interface{}{
+		"submit_date": time.Now().UTC().Format("2006-01-02"),
+	}, func(tx *gorm.DB, task models.Task) error {
+		attachment, err := t.Attachments.store(c, tx, task, c.GetInt("userId"), file, mime)
+		stored = attachment.Path
+		return err
+	})
+	if !ok {
+		if stored != "" {
+			removeFiles(t.Attachments.Uploads.Dir, []string{stored})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, "Submit to Review")
+}
+
+// SubmitEvidence moves the caller's task to Review with a photo as proof,
+// under the same workflow rules as Submit.
+func (t *TaskController) SubmitEvidence(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, t.EvidenceMax+1<<20)
+	file, errFile := c.FormFile("photo")
+	if errFile != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "photo is required"})
+		return
+	}
+	if file.Size > t.EvidenceMax {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": "photo exceeds the maximum size of " + strconv.FormatInt(t.EvidenceMax, 10) + " bytes"})
+		return
+	}
+
+	mime, errSniff := sniffContentType(file.Open)
+	if errSniff != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errSniff.Error()})
+		return
+	}
+	extensions := map[string]string{"image/jpeg": ".jpg", "image/png": ".png"}
+	extension, ok := extensions[mime]
+	if !ok {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "photo must be a JPEG or PNG image"})
+		return
+	}
+
+	name, errName := randomToken()
+	if errName != nil {
+		respondInternal(c, errName)
+		return
+	}
+	evidencePath := filepath.Join("evidence", name+extension)
+	fullPath := filepath.Join(t.UploadDir, evidencePath)
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		respondInternal(c, err)
+		return
+	}
+	if err := c.SaveUploadedFile(file, fullPath); err != nil {
+		respondInternal(c, err)
+		return
+	}
+
+	// the photo and the status change land together or not at all; older
+	// evidence stays on disk and in task_submissions
+	now := time.Now().UTC()
+	note := c.PostForm("note")
+	task, ok := t.transition(c, models.StatusReview, map[string]interface{}{
+		"evidence_path": evidencePath,
+		"submitted_at":  now,
+		"submit_note":   note,
+		"submit_date":   now.Format("2006-01-02"),
+	}, func(tx *gorm.DB, task models.Task) error {
+		return tx.Create(&models.TaskSubmission{
+			TaskId:       task.Id,
+			UserId:       c.GetInt("userId"),
+			EvidencePath: evidencePath,
+			Note:         note,
+			SubmittedAt:  now,
+		}).Error
+	})
+	if !ok {
+		os.Remove(fullPath)
+		return
+	}
+
+	c.JSON(http.StatusOK, newTaskResponse(task))
+}
+
+// RejectSubmission sends a task in Review back to its assignee with a reason.
+func (t *TaskController) RejectSubmission(c *gin.Context) {
+	var rejectReq RejectTaskRequest
+	if err := c.ShouldBindJSON(&rejectReq); err != nil {
+		c.JSON(http.StatusBadRequest, bindError(err))
+		return
+	}
+
+	task, ok := t.reject(c, rejectReq.Reason)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, newTaskResponse(task))
+}
+
+// reject moves the task to Rejected with a non-blank reason.
+func (t *TaskController) reject(c *gin.Context, reason string) (models.Task, bool) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Request validation failed", "fields": gin.H{"reason": "reason is required"}})
+		return models.Task{}, false
+	}
+
+	return t.transition(c, models.StatusRejected, map[string]interface{}{
+		"reason":        reason,
+		"rejected_date": time.Now().UTC().Format("2006-01-02"),
+		"revision":      gorm.Expr("revision + 1"),
+	}, nil)
+}
+
+// Reject is the older form of RejectSubmission, taking the reason as a
+// form field.
+func (t *TaskController) Reject(c *gin.Context) {
+	if _, ok := t.reject(c, c.PostForm("reason")); !ok {
+		return
+	}
+	c.JSON(http.StatusOK, "Rejected")
+}
+
+// Fix moves a rejected task back into work; the revision was already
+// counted when it was rejected.
+func (t *TaskController) Fix(c *gin.Context) {
+	if _, ok := t.transition(c, models.StatusInProgress, nil, nil); !ok {
+		return
+	}
+	c.JSON(http.StatusOK, "Fix to InProgress")
+}
+
+func (t *TaskController) Approve(c *gin.Context) {
+	_, ok := t.transition(c, models.StatusApproved, map[string]interface{}{
+		"approved_date": time.Now().UTC().Format("2006-01-02"),
+	}, nil)
+	if !ok {
 		return
 	}
+	c.JSON(http.StatusOK, "Approved")
+}
+
+func (t *TaskController) GetByID(c *gin.Context) {
+	task := models.Task{}
+	id := c.Param("id")
 
-	c.JSON(http.StatusOK, task)
+	// a task the caller may not see is reported as missing
+	if err := t.scopeTasks(c).Preload("User").Preload("Tags").First(&task, id).Error; err != nil {
+		respondDBError(c, err, http.StatusNotFound, "not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, withCounts(t.DB.WithContext(c.Request.Context()), []TaskResponse{newTaskResponse(task)})[0])
 }
 
 func (t *TaskController) NeedToBeReview(c *gin.Context) {
 	tasks := []models.Task{}
 
-	errDB := t.DB.Preload("User").Where("status=?", "Review").Order("submit_date ASC").Limit(2).Find(&tasks).Error
+	errDB := t.scopeTasks(c).Preload("User").Preload("Tags").Where("status=?", "Review").Order("submit_date ASC").Limit(2).Find(&tasks).Error
 	if errDB != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": errDB.Error()})
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
 		return
 	}
 
-	c.JSON(http.StatusOK, tasks)
+	c.JSON(http.StatusOK, newTaskResponses(tasks))
 }
 
 func (t *TaskController) ProgressTasks(c *gin.Context) {
 	tasks := []models.Task{}
 	userId := c.Param("userId")
 
-	errDB := t.DB.Where(
+	errDB := t.scopeTasks(c).Where(
 		"(status!=? AND user_id=?) OR (revision!=? AND user_id=?)", "Queue", userId, 0, userId,
 	).Order("updated_at DESC").Limit(5).Find(&tasks).Error
 	if errDB != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": errDB.Error()})
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
 		return
 	}
 
-	c.JSON(http.StatusOK, tasks)
+	c.JSON(http.StatusOK, newTaskResponses(tasks))
 }
 
 func (t *TaskController) Statistic(c *gin.Context) {
@@ -217,9 +934,9 @@ func (t *TaskController) Statistic(c *gin.Context) {
 
 	stat := []map[string]interface{}{}
 
-	errDB := t.DB.Model(models.Task{}).Select("status, count(status) as total").Where("user_id=?", userId).Group("status").Find(&stat).Error
+	errDB := t.scopeTasks(c).Model(models.Task{}).Select("status, count(status) as total").Where("user_id=?", userId).Group("status").Find(&stat).Error
 	if errDB != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": errDB.Error()})
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
 		return
 	}
 
@@ -231,11 +948,179 @@ func (t *TaskController) FindByUserAndStatus(c *gin.Context) {
 	userId := c.Param("userId")
 	status := c.Param("status")
 
-	errDB := t.DB.Where("user_id=? AND status=?", userId, status).Find(&tasks).Error
+	errDB := t.scopeTasks(c).Where("user_id=? AND status=?", userId, status).Find(&tasks).Error
+	if errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	c.JSON(http.StatusOK, newTaskResponses(tasks))
+}
+
+type forecastBucket struct {
+	Period        string `json:"period"`
+	Minutes       int    `json:"minutes"`
+	Overallocated bool   `json:"overallocated"`
+}
+
+type forecastAssignee struct {
+	UserId       int              `json:"userId"`
+	Name         string           `json:"name"`
+	TotalMinutes int              `json:"totalMinutes"`
+	Buckets      []forecastBucket `json:"buckets"`
+}
+
+// Forecast sums the estimates of the open tasks due within the horizon per
+// assignee and day or week, flagging the buckets over capacity. Managers
+// only see their department.
+func (t *TaskController) Forecast(c *gin.Context) {
+	bucket := c.DefaultQuery("bucket", "day")
+	if bucket != "day" && bucket != "week" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "bucket must be day or week"})
+		return
+	}
+
+	horizon := t.ForecastConfig.HorizonDays
+	if days, err := strconv.Atoi(c.Query("days")); err == nil && days > 0 && days <= 366 {
+		horizon = days
+	}
+
+	// capacity is per day; a week bucket holds five working days
+	capacity := t.ForecastConfig.DailyCapacity
+	if minutes, err := strconv.Atoi(c.Query("capacity")); err == nil && minutes > 0 {
+		capacity = minutes
+	}
+	if bucket == "week" {
+		capacity *= 5
+	}
+
+	now := time.Now().UTC()
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, horizon)
+	from := start.Format("2006-01-02")
+	to := end.AddDate(0, 0, -1).Format("2006-01-02")
+
+	rows := []struct {
+		UserId  int
+		Name    string
+		Period  string
+		Minutes int
+	}{}
+
+	period := t.forecastPeriod(bucket)
+	errDB := t.scopeTasks(c).Model(&models.Task{}).
+		Select("tasks.user_id, users.name, "+period+" AS period, SUM(tasks.estimate) AS minutes").
+		Joins("JOIN users ON users.id = tasks.user_id").
+		Where("tasks.status<>? AND tasks.due_date >= ? AND tasks.due_date < ?", models.StatusApproved, start, end).
+		Group("tasks.user_id, users.name, " + period).
+		Order("tasks.user_id, period").
+		Scan(&rows).Error
+	if errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	assignees := []*forecastAssignee{}
+	byUser := map[int]*forecastAssignee{}
+	for _, row := range rows {
+		assignee, ok := byUser[row.UserId]
+		if !ok {
+			assignee = &forecastAssignee{UserId: row.UserId, Name: row.Name, Buckets: []forecastBucket{}}
+			byUser[row.UserId] = assignee
+			assignees = append(assignees, assignee)
+		}
+		assignee.Buckets = append(assignee.Buckets, forecastBucket{
+			Period:        row.Period,
+			Minutes:       row.Minutes,
+			Overallocated: row.Minutes > capacity,
+		})
+		assignee.TotalMinutes += row.Minutes
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"bucket":    bucket,
+		"from":      from,
+		"to":        to,
+		"capacity":  capacity,
+		"assignees": assignees,
+	})
+}
+
+// forecastPeriod is the SQL expression for the YYYY-MM-DD start of the day
+// or week, starting on Monday, of a task's due date in UTC.
+func (t *TaskController) forecastPeriod(bucket string) string {
+	switch t.DB.Dialector.Name() {
+	case "mysql":
+		if bucket == "week" {
+			return "DATE_FORMAT(DATE_SUB(tasks.due_date, INTERVAL WEEKDAY(tasks.due_date) DAY), '%Y-%m-%d')"
+		}
+		return "DATE_FORMAT(tasks.due_date, '%Y-%m-%d')"
+	case "postgres":
+		return "to_char(date_trunc('" + bucket + "', tasks.due_date AT TIME ZONE 'UTC'), 'YYYY-MM-DD')"
+	default:
+		if bucket == "week" {
+			// the coming Sunday, or today on a Sunday, less six days
+			return "date(tasks.due_date, 'weekday 0', '-6 days')"
+		}
+		return "date(tasks.due_date)"
+	}
+}
+
+// summaryGroups maps each status to the group GET /stats counts it in.
+var summaryGroups = map[string]string{
+	models.StatusQueue:      "todo",
+	models.StatusInProgress: "in_progress",
+	models.StatusReview:     "in_progress",
+	models.StatusRejected:   "in_progress",
+	models.StatusApproved:   "done",
+}
+
+// Summary counts the caller's tasks by todo, in_progress and done, the
+// overdue ones and the employees. Admins see everyone, Managers their
+// department and Employees their own tasks.
+func (t *TaskController) Summary(c *gin.Context) {
+	counts := []struct {
+		Status string
+		Total  int64
+	}{}
+	errDB := t.scopeTasks(c).Model(&models.Task{}).Select("status, count(*) as total").Group("status").Scan(&counts).Error
 	if errDB != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": errDB.Error()})
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	// every group is present even when it has no tasks
+	byGroup := map[string]int64{"todo": 0, "in_progress": 0, "done": 0}
+	for _, count := range counts {
+		// a status outside the workflow, e.g. from before it existed, fits
+		// none of the groups
+		if group, ok := summaryGroups[count.Status]; ok {
+			byGroup[group] += count.Total
+		}
+	}
+
+	var overdue int64
+	errDB = t.scopeTasks(c).Model(&models.Task{}).
+		Where("status<>? AND due_date IS NOT NULL AND due_date < ?", models.StatusApproved, time.Now().UTC()).
+		Count(&overdue).Error
+	if errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	employeeQuery := t.DB.WithContext(c.Request.Context()).Model(&models.User{}).Where("role=? AND is_active=?", models.RoleEmployee, true)
+	if departmentId, scoped := departmentScope(c); scoped {
+		employeeQuery = employeeQuery.Where("department_id=?", departmentId)
+	}
+	var employees int64
+	if errDB := employeeQuery.Count(&employees).Error; errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
 		return
 	}
 
-	c.JSON(http.StatusOK, tasks)
+	c.JSON(http.StatusOK, gin.H{
+		"tasks":     byGroup,
+		"overdue":   overdue,
+		"employees": employees,
+	})
 }
@@ -0,0 +1,459 @@
+package controllers_test
+
+import (
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+	"tusk/config"
+	"tusk/controllers"
+	"tusk/models"
+	"tusk/routes"
+	"tusk/testutil"
+)
+
+func TestTaskCRUD(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t))
+	admin := testutil.User(t, s.DB, models.RoleAdmin)
+	employee := testutil.User(t, s.DB, models.RoleEmployee)
+	adminToken, employeeToken := testutil.Token(t, admin), testutil.Token(t, employee)
+	path := routes.Prefix + "/tasks"
+
+	t.Run("create", func(t *testing.T) {
+		res := s.Do(t, http.MethodPost, path, adminToken, map[string]interface{}{"title": "Write report", "userId": employee.Id})
+		testutil.Expect(t, res, http.StatusCreated)
+
+		created := controllers.TaskResponse{}
+		testutil.Decode(t, res, &created)
+		if created.Id == 0 || created.UserId != employee.Id || created.Status != models.StatusQueue {
+			t.Errorf("created = %+v, want a Queue task of %d", created, employee.Id)
+		}
+	})
+
+	t.Run("create validates", func(t *testing.T) {
+		cases := []struct {
+			name   string
+			body   map[string]interface{}
+			status int
+		}{
+			{"no title", map[string]interface{}{"userId": employee.Id}, http.StatusBadRequest},
+			{"no assignee", map[string]interface{}{"title": "Orphan"}, http.StatusBadRequest},
+			{"unknown assignee", map[string]interface{}{"title": "Ghost", "userId": 9999}, http.StatusUnprocessableEntity},
+			{"assignee not an Employee", map[string]interface{}{"title": "Boss", "userId": admin.Id}, http.StatusUnprocessableEntity},
+			{"unknown priority", map[string]interface{}{"title": "Soon", "userId": employee.Id, "priority": "Whenever"}, http.StatusBadRequest},
+		}
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				testutil.Expect(t, s.Do(t, http.MethodPost, path, adminToken, tc.body), tc.status)
+			})
+		}
+	})
+
+	t.Run("employee can't write", func(t *testing.T) {
+		task := createTask(t, s, employee.Id, models.StatusQueue)
+		testutil.Expect(t, s.Do(t, http.MethodPost, path, employeeToken, map[string]interface{}{"title": "Mine", "userId": employee.Id}), http.StatusForbidden)
+		testutil.Expect(t, s.Do(t, http.MethodPut, path+"/"+itoa(task.Id), employeeToken, map[string]interface{}{"title": "Renamed"}), http.StatusForbidden)
+		testutil.Expect(t, s.Do(t, http.MethodDelete, path+"/"+itoa(task.Id), employeeToken, nil), http.StatusForbidden)
+		if got := reloadTask(t, s, task.Id); got.Title != task.Title {
+			t.Errorf("title = %q, want %q", got.Title, task.Title)
+		}
+	})
+
+	t.Run("get by id", func(t *testing.T) {
+		task := createTask(t, s, employee.Id, models.StatusQueue)
+		res := s.Do(t, http.MethodGet, path+"/"+itoa(task.Id), employeeToken, nil)
+		testutil.Expect(t, res, http.StatusOK)
+
+		got := controllers.TaskResponse{}
+		testutil.Decode(t, res, &got)
+		if got.Id != task.Id || got.Title != task.Title {
+			t.Errorf("got %+v, want task %d", got, task.Id)
+		}
+	})
+
+	t.Run("update", func(t *testing.T) {
+		task := createTask(t, s, employee.Id, models.StatusQueue)
+		res := s.Do(t, http.MethodPut, path+"/"+itoa(task.Id), adminToken, map[string]interface{}{"title": "Renamed"})
+		testutil.Expect(t, res, http.StatusOK)
+		if got := reloadTask(t, s, task.Id); got.Title != "Renamed" {
+			t.Errorf("title = %q, want Renamed", got.Title)
+		}
+
+		res = s.Do(t, http.MethodPut, path+"/"+itoa(task.Id), adminToken, map[string]interface{}{"title": ""})
+		testutil.Expect(t, res, http.StatusBadRequest)
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		task := createTask(t, s, employee.Id, models.StatusQueue)
+		testutil.Expect(t, s.Do(t, http.MethodDelete, path+"/"+itoa(task.Id), adminToken, nil), http.StatusOK)
+		testutil.Expect(t, s.Do(t, http.MethodGet, path+"/"+itoa(task.Id), adminToken, nil), http.StatusNotFound)
+	})
+
+	t.Run("missing task", func(t *testing.T) {
+		missing := path + "/9999"
+		testutil.Expect(t, s.Do(t, http.MethodGet, missing, adminToken, nil), http.StatusNotFound)
+		testutil.Expect(t, s.Do(t, http.MethodPut, missing, adminToken, map[string]interface{}{"title": "Nobody"}), http.StatusNotFound)
+		testutil.Expect(t, s.Do(t, http.MethodDelete, missing, adminToken, nil), http.StatusNotFound)
+	})
+}
+
+func TestTaskListsAreScoped(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t))
+	sales, support := models.Department{Name: "Sales"}, models.Department{Name: "Support"}
+	s.DB.Create(&sales)
+	s.DB.Create(&support)
+	inDepartment := func(department models.Department) func(*models.User) {
+		return func(u *models.User) { u.DepartmentId = &department.Id }
+	}
+
+	admin := testutil.User(t, s.DB, models.RoleAdmin)
+	manager := testutil.User(t, s.DB, models.RoleManager, inDepartment(sales))
+	seller := testutil.User(t, s.DB, models.RoleEmployee, inDepartment(sales))
+	colleague := testutil.User(t, s.DB, models.RoleEmployee, inDepartment(sales))
+	helper := testutil.User(t, s.DB, models.RoleEmployee, inDepartment(support))
+
+	sellerTask := createTask(t, s, seller.Id, models.StatusQueue)
+	colleagueTask := createTask(t, s, colleague.Id, models.StatusQueue)
+	helperTask := createTask(t, s, helper.Id, models.StatusQueue)
+	for _, task := range []models.Task{sellerTask, colleagueTask, helperTask} {
+		s.DB.Model(&task).Update("due_date", "2000-01-01 00:00:00")
+	}
+
+	list := func(t *testing.T, path string, user models.User) []int {
+		t.Helper()
+
+		res := s.Do(t, http.MethodGet, routes.Prefix+path, testutil.Token(t, user), nil)
+		testutil.Expect(t, res, http.StatusOK)
+		tasks := []controllers.TaskResponse{}
+		testutil.Decode(t, res, &tasks)
+		ids := []int{}
+		for _, task := range tasks {
+			ids = append(ids, task.Id)
+		}
+		sort.Ints(ids)
+		return ids
+	}
+
+	cases := []struct {
+		name string
+		user models.User
+		want []int
+	}{
+		{"admin sees all", admin, []int{sellerTask.Id, colleagueTask.Id, helperTask.Id}},
+		{"manager sees the department", manager, []int{sellerTask.Id, colleagueTask.Id}},
+		{"employee sees their own", seller, []int{sellerTask.Id}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, path := range []string{"/tasks", "/tasks/overdue"} {
+				if got := list(t, path, tc.user); !equalInts(got, tc.want) {
+					t.Errorf("%s = %v, want %v", path, got, tc.want)
+				}
+			}
+		})
+	}
+
+	t.Run("employee filtering by someone else", func(t *testing.T) {
+		if got := list(t, "/tasks?userId="+itoa(colleague.Id), seller); len(got) != 0 {
+			t.Errorf("got %v, want nothing", got)
+		}
+	})
+
+	t.Run("other people's tasks are missing", func(t *testing.T) {
+		res := s.Do(t, http.MethodGet, routes.Prefix+"/tasks/"+itoa(colleagueTask.Id), testutil.Token(t, seller), nil)
+		testutil.Expect(t, res, http.StatusNotFound)
+		res = s.Do(t, http.MethodGet, routes.Prefix+"/tasks/"+itoa(helperTask.Id), testutil.Token(t, manager), nil)
+		testutil.Expect(t, res, http.StatusNotFound)
+	})
+}
+
+func TestTaskDatabaseErrorsAreNotLeaked(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t))
+	admin := testutil.User(t, s.DB, models.RoleAdmin)
+	token := testutil.Token(t, admin)
+	createTask(t, s, testutil.User(t, s.DB, models.RoleEmployee).Id, models.StatusQueue)
+	// without it loading the tags of a task fails
+	if err := s.DB.Migrator().DropTable("task_tags"); err != nil {
+		t.Fatal(err)
+	}
+
+	res := s.Do(t, http.MethodGet, routes.Prefix+"/tasks", token, nil)
+	testutil.Expect(t, res, http.StatusInternalServerError)
+	if body := res.Body.String(); strings.Contains(body, "task_tags") || strings.Contains(body, "no such table") {
+		t.Errorf("body leaks the database error: %s", body)
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestTaskSummary(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t))
+	admin := testutil.User(t, s.DB, models.RoleAdmin)
+	employee := testutil.User(t, s.DB, models.RoleEmployee)
+	other := testutil.User(t, s.DB, models.RoleEmployee)
+
+	createTask(t, s, employee.Id, models.StatusQueue)
+	createTask(t, s, employee.Id, models.StatusReview)
+	overdue := createTask(t, s, other.Id, models.StatusInProgress)
+	s.DB.Model(&overdue).Update("due_date", "2000-01-01 00:00:00")
+	createTask(t, s, other.Id, models.StatusApproved)
+	legacy := createTask(t, s, other.Id, models.StatusQueue)
+	s.DB.Model(&legacy).UpdateColumn("status", "Archived")
+
+	summary := func(t *testing.T, user models.User) map[string]interface{} {
+		t.Helper()
+
+		res := s.Do(t, http.MethodGet, routes.Prefix+"/stats", testutil.Token(t, user), nil)
+		testutil.Expect(t, res, http.StatusOK)
+		body := map[string]interface{}{}
+		testutil.Decode(t, res, &body)
+		return body
+	}
+
+	t.Run("needs a token", func(t *testing.T) {
+		testutil.Expect(t, s.Do(t, http.MethodGet, routes.Prefix+"/stats", "", nil), http.StatusUnauthorized)
+	})
+
+	t.Run("admin sees everyone", func(t *testing.T) {
+		got := summary(t, admin)
+		want := map[string]interface{}{"todo": 1.0, "in_progress": 2.0, "done": 1.0}
+		if !reflect.DeepEqual(got["tasks"], want) || got["overdue"] != 1.0 || got["employees"] != 2.0 {
+			t.Errorf("summary = %v, want tasks %v, 1 overdue and 2 employees", got, want)
+		}
+	})
+
+	t.Run("employee sees their own", func(t *testing.T) {
+		got := summary(t, employee)
+		want := map[string]interface{}{"todo": 1.0, "in_progress": 1.0, "done": 0.0}
+		if !reflect.DeepEqual(got["tasks"], want) || got["overdue"] != 0.0 {
+			t.Errorf("summary = %v, want tasks %v and nothing overdue", got, want)
+		}
+	})
+
+	t.Run("userId is ignored", func(t *testing.T) {
+		res := s.Do(t, http.MethodGet, routes.Prefix+"/stats?userId="+itoa(other.Id), testutil.Token(t, employee), nil)
+		body := map[string]interface{}{}
+		testutil.Decode(t, res, &body)
+		if want := summary(t, employee); !reflect.DeepEqual(body, want) {
+			t.Errorf("summary = %v, want %v", body, want)
+		}
+	})
+}
+
+func TestTaskForecast(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t))
+	admin := testutil.User(t, s.DB, models.RoleAdmin)
+	employee := testutil.User(t, s.DB, models.RoleEmployee)
+
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Truncate(24 * time.Hour)
+	for _, at := range []time.Time{tomorrow.Add(9 * time.Hour), tomorrow.Add(15 * time.Hour), tomorrow.AddDate(0, 0, 1)} {
+		task := models.Task{UserId: employee.Id, Title: "Estimated", Status: models.StatusQueue, Estimate: 300, DueDate: &at}
+		if err := s.DB.Create(&task).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	forecast := func(t *testing.T, query string) []map[string]interface{} {
+		t.Helper()
+
+		res := s.Do(t, http.MethodGet, routes.Prefix+"/stats/forecast?capacity=480&"+query, testutil.Token(t, admin), nil)
+		testutil.Expect(t, res, http.StatusOK)
+		body := struct {
+			Assignees []struct {
+				UserId  int                      `json:"userId"`
+				Buckets []map[string]interface{} `json:"buckets"`
+			} `json:"assignees"`
+		}{}
+		testutil.Decode(t, res, &body)
+		if len(body.Assignees) != 1 || body.Assignees[0].UserId != employee.Id {
+			t.Fatalf("assignees = %+v, want only %d", body.Assignees, employee.Id)
+		}
+		return body.Assignees[0].Buckets
+	}
+
+	t.Run("needs a manager", func(t *testing.T) {
+		testutil.Expect(t, s.Do(t, http.MethodGet, routes.Prefix+"/stats/forecast", "", nil), http.StatusUnauthorized)
+		testutil.Expect(t, s.Do(t, http.MethodGet, routes.Prefix+"/stats/forecast", testutil.Token(t, employee), nil), http.StatusForbidden)
+	})
+
+	t.Run("per day", func(t *testing.T) {
+		want := []map[string]interface{}{
+			{"period": tomorrow.Format("2006-01-02"), "minutes": 600.0, "overallocated": true},
+			{"period": tomorrow.AddDate(0, 0, 1).Format("2006-01-02"), "minutes": 300.0, "overallocated": false},
+		}
+		if got := forecast(t, "bucket=day"); !reflect.DeepEqual(got, want) {
+			t.Errorf("buckets = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("per week", func(t *testing.T) {
+		got := forecast(t, "bucket=week")
+		// the two days may fall in different weeks
+		minutes := 0.0
+		for _, bucket := range got {
+			period, err := time.Parse("2006-01-02", bucket["period"].(string))
+			if err != nil || period.Weekday() != time.Monday {
+				t.Errorf("period %v is not a Monday", bucket["period"])
+			}
+			minutes += bucket["minutes"].(float64)
+		}
+		if minutes != 900 {
+			t.Errorf("buckets = %v, want 900 minutes in all", got)
+		}
+	})
+}
+
+func TestTaskExportFailingFirstRow(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t))
+	token := testutil.Token(t, testutil.User(t, s.DB, models.RoleAdmin))
+	task := createTask(t, s, testutil.User(t, s.DB, models.RoleEmployee).Id, models.StatusQueue)
+	// a number in created_at doesn't scan into a time, failing the row
+	s.DB.Exec("UPDATE tasks SET created_at = 1.5 WHERE id = ?", task.Id)
+
+	res := s.Do(t, http.MethodGet, routes.Prefix+"/tasks/export", token, nil)
+	testutil.Expect(t, res, http.StatusInternalServerError)
+	if res.Header().Get("Content-Disposition") != "" {
+		t.Errorf("failed export still offered as a download")
+	}
+}
+
+func TestExportsOutliveTheQueryTimeout(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t), func(cfg *config.Config) { cfg.QueryTimeout = time.Nanosecond })
+	token := testutil.Token(t, testutil.User(t, s.DB, models.RoleAdmin))
+	task := createTask(t, s, testutil.User(t, s.DB, models.RoleEmployee).Id, models.StatusQueue)
+
+	for _, path := range []string{"/tasks/export", "/users/export"} {
+		for _, mount := range []string{routes.Prefix, ""} {
+			res := s.Do(t, http.MethodGet, mount+path, token, nil)
+			testutil.Expect(t, res, http.StatusOK)
+			if path == "/tasks/export" && !strings.Contains(res.Body.String(), task.Title) {
+				t.Errorf("%s%s lacks the task:\n%s", mount, path, res.Body.String())
+			}
+		}
+	}
+}
+
+func TestTaskCursorPagination(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t))
+	token := testutil.Token(t, testutil.User(t, s.DB, models.RoleAdmin))
+	employee := testutil.User(t, s.DB, models.RoleEmployee)
+
+	// seven tasks a minute apart, oldest first, with the middle two created
+	// in the same instant so the id breaks the tie
+	base := time.Date(2024, 6, 1, 9, 0, 0, 0, time.UTC)
+	statuses := []string{models.StatusQueue, models.StatusInProgress, models.StatusInProgress, models.StatusQueue, models.StatusInProgress, models.StatusInProgress, models.StatusQueue}
+	ids := []int{}
+	for i, status := range statuses {
+		task := createTask(t, s, employee.Id, status)
+		createdAt := base.Add(time.Duration(i) * time.Minute)
+		if i == 4 {
+			createdAt = base.Add(3 * time.Minute)
+		}
+		s.DB.Model(&task).UpdateColumn("created_at", createdAt)
+		ids = append(ids, task.Id)
+	}
+	newestFirst := func(indexes ...int) []int {
+		want := []int{}
+		for _, i := range indexes {
+			want = append(want, ids[i])
+		}
+		return want
+	}
+
+	type page struct {
+		Data       []controllers.TaskResponse `json:"data"`
+		NextCursor *string                    `json:"nextCursor"`
+		Limit      int                        `json:"limit"`
+	}
+	get := func(t *testing.T, query string) page {
+		t.Helper()
+
+		res := s.Do(t, http.MethodGet, routes.Prefix+"/tasks?"+query, token, nil)
+		testutil.Expect(t, res, http.StatusOK)
+		got := page{}
+		testutil.Decode(t, res, &got)
+		return got
+	}
+	// all follows nextCursor to the end; between calls runs after each page
+	all := func(t *testing.T, query string, between func()) []int {
+		t.Helper()
+
+		got := []int{}
+		cursor := ""
+		for pages := 0; pages < 10; pages++ {
+			next := get(t, query+"&cursor="+cursor)
+			for _, task := range next.Data {
+				got = append(got, task.Id)
+			}
+			if next.NextCursor == nil {
+				return got
+			}
+			cursor = *next.NextCursor
+			if between != nil {
+				between()
+			}
+		}
+		t.Fatal("nextCursor never ran out")
+		return nil
+	}
+
+	t.Run("pages", func(t *testing.T) {
+		first := get(t, "limit=3")
+		if got := []int{first.Data[0].Id, first.Data[1].Id, first.Data[2].Id}; !equalInts(got, newestFirst(6, 5, 4)) || first.NextCursor == nil {
+			t.Errorf("first page = %v, next %v; want %v and a cursor", got, first.NextCursor, newestFirst(6, 5, 4))
+		}
+		if got := all(t, "limit=3", nil); !equalInts(got, newestFirst(6, 5, 4, 3, 2, 1, 0)) {
+			t.Errorf("pages = %v, want %v", got, newestFirst(6, 5, 4, 3, 2, 1, 0))
+		}
+	})
+
+	t.Run("an insert mid-pagination doesn't shift the pages", func(t *testing.T) {
+		inserted := []int{}
+		got := all(t, "limit=2", func() {
+			inserted = append(inserted, createTask(t, s, employee.Id, models.StatusQueue).Id)
+		})
+		if !equalInts(got, newestFirst(6, 5, 4, 3, 2, 1, 0)) {
+			t.Errorf("pages = %v, want %v without repeats or gaps", got, newestFirst(6, 5, 4, 3, 2, 1, 0))
+		}
+		for _, id := range inserted {
+			s.DB.Delete(&models.Task{}, id)
+		}
+	})
+
+	t.Run("composes with filters", func(t *testing.T) {
+		s.DB.Model(&models.Task{}).Where("id IN ?", newestFirst(5, 1)).UpdateColumn("priority", models.PriorityHigh)
+
+		if got := all(t, "limit=1&status=InProgress", nil); !equalInts(got, newestFirst(5, 4, 2, 1)) {
+			t.Errorf("InProgress pages = %v, want %v", got, newestFirst(5, 4, 2, 1))
+		}
+		if got := all(t, "limit=1&status=InProgress&priority=High", nil); !equalInts(got, newestFirst(5, 1)) {
+			t.Errorf("High InProgress pages = %v, want %v", got, newestFirst(5, 1))
+		}
+		if got := all(t, "limit=2&userId="+itoa(employee.Id)+"&status=Queue", nil); !equalInts(got, newestFirst(6, 3, 0)) {
+			t.Errorf("Queue pages = %v, want %v", got, newestFirst(6, 3, 0))
+		}
+	})
+
+	t.Run("limit is capped", func(t *testing.T) {
+		if got := get(t, "limit=1000"); got.Limit != 100 || got.NextCursor != nil || len(got.Data) != len(ids) {
+			t.Errorf("limit = %d, %d tasks, next %v; want 100 and everything", got.Limit, len(got.Data), got.NextCursor)
+		}
+	})
+
+	t.Run("rejected", func(t *testing.T) {
+		testutil.Expect(t, s.Do(t, http.MethodGet, routes.Prefix+"/tasks?cursor=not-a-cursor", token, nil), http.StatusBadRequest)
+		testutil.Expect(t, s.Do(t, http.MethodGet, routes.Prefix+"/tasks?limit=2&sort=priority", token, nil), http.StatusBadRequest)
+	})
+}
@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+	"tusk/models"
+
+	"gorm.io/gorm"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// taskCursor is the sort key of the last task on a page. Clients get it
+// base64 encoded and must treat it as opaque.
+type taskCursor struct {
+	CreatedAt time.Time `json:"c"`
+	Id        int       `json:"i"`
+}
+
+func encodeTaskCursor(task models.Task) string {
+	raw, _ := json.Marshal(taskCursor{CreatedAt: task.CreatedAt, Id: task.Id})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeTaskCursor(value string) (taskCursor, error) {
+	cursor := taskCursor{}
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, errInvalidCursor
+	}
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.Id < 1 {
+		return cursor, errInvalidCursor
+	}
+	return cursor, nil
+}
+
+// afterTaskCursor keeps the tasks that sort after cursor in the list's
+// newest-first (created_at, id) order. The predicate only depends on the
+// cursor row's key, so tasks created while a client pages through the list
+// land before its first page and never shift the later ones.
+func afterTaskCursor(query *gorm.DB, cursor taskCursor) *gorm.DB {
+	return query.Where("tasks.created_at < ? OR (tasks.created_at = ? AND tasks.id < ?)",
+		cursor.CreatedAt, cursor.CreatedAt, cursor.Id)
+}
@@ -0,0 +1,353 @@
+package controllers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"tusk/events"
+	"tusk/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ProposeTaskRequest struct {
+	Title       string     `json:"title" binding:"required,max=255"`
+	Description string     `json:"description"`
+	DueDate     *time.Time `json:"dueDate" binding:"omitempty,future"`
+	Priority    string     `json:"priority" binding:"omitempty,priority"`
+}
+
+// ApproveTaskRequest overrides what the requester suggested; the body may
+// be left out entirely.
+type ApproveTaskRequest struct {
+	UserId   int        `json:"userId"` // assignee, the requester when 0
+	DueDate  *time.Time `json:"dueDate" binding:"omitempty,future"`
+	Priority string     `json:"priority" binding:"omitempty,priority"`
+}
+
+type TaskRequestResponse struct {
+	Id            int     `json:"id"`
+	RequesterId   int     `json:"requesterId"`
+	RequesterName string  `json:"requesterName,omitempty"`
+	Title         string  `json:"title"`
+	Description   string  `json:"description"`
+	Priority      string  `json:"priority"`
+	DueDate       *string `json:"dueDate"`
+	Status        string  `json:"status"`
+	Reason        string  `json:"reason"`
+	ReviewedBy    *int    `json:"reviewedBy"`
+	ReviewedAt    *string `json:"reviewedAt"`
+	TaskId        *int    `json:"taskId"`
+	CreatedAt     string  `json:"createdAt"`
+	UpdatedAt     string  `json:"updatedAt"`
+}
+
+func newTaskRequestResponse(request models.TaskRequest) TaskRequestResponse {
+	response := TaskRequestResponse{
+		Id:            request.Id,
+		RequesterId:   request.RequesterId,
+		RequesterName: request.Requester.Name,
+		Title:         request.Title,
+		Description:   request.Description,
+		Priority:      models.PriorityName(request.Priority),
+		Status:        request.Status,
+		Reason:        request.Reason,
+		ReviewedBy:    request.ReviewedBy,
+		TaskId:        request.TaskId,
+		CreatedAt:     formatTime(request.CreatedAt),
+		UpdatedAt:     formatTime(request.UpdatedAt),
+	}
+	if request.DueDate != nil {
+		dueDate := formatTime(*request.DueDate)
+		response.DueDate = &dueDate
+	}
+	if request.ReviewedAt != nil {
+		reviewedAt := formatTime(*request.ReviewedAt)
+		response.ReviewedAt = &reviewedAt
+	}
+	return response
+}
+
+func newTaskRequestResponses(requests []models.TaskRequest) []TaskRequestResponse {
+	responses := make([]TaskRequestResponse, 0, len(requests))
+	for _, request := range requests {
+		responses = append(responses, newTaskRequestResponse(request))
+	}
+	return responses
+}
+
+var requestStatuses = map[string]bool{
+	models.RequestPending:   true,
+	models.RequestApproved:  true,
+	models.RequestRejected:  true,
+	models.RequestCancelled: true,
+}
+
+// ProposeTask lets an Employee ask for a task; it waits as Pending until an
+// Admin approves or rejects it.
+func (t *TaskController) ProposeTask(c *gin.Context) {
+	var proposeReq ProposeTaskRequest
+	if err := c.ShouldBindJSON(&proposeReq); err != nil {
+		c.JSON(http.StatusBadRequest, bindError(err))
+		return
+	}
+	if strings.TrimSpace(proposeReq.Title) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Request validation failed", "fields": gin.H{"title": "title is required"}})
+		return
+	}
+
+	priority := models.PriorityMedium
+	if proposeReq.Priority != "" {
+		priority, _ = models.ParsePriority(proposeReq.Priority)
+	}
+	if proposeReq.DueDate != nil {
+		dueDate := proposeReq.DueDate.UTC()
+		proposeReq.DueDate = &dueDate
+	}
+
+	request := models.TaskRequest{
+		RequesterId: c.GetInt("userId"),
+		Title:       strings.TrimSpace(proposeReq.Title),
+		Description: proposeReq.Description,
+		Priority:    priority,
+		DueDate:     proposeReq.DueDate,
+		Status:      models.RequestPending,
+	}
+	if err := t.DB.WithContext(c.Request.Context()).Create(&request).Error; err != nil {
+		respondDBError(c, err, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	c.JSON(http.StatusCreated, newTaskRequestResponse(request))
+}
+
+// MyTaskRequests lists the caller's own requests, newest first, optionally
+// only those with ?status=.
+func (t *TaskController) MyTaskRequests(c *gin.Context) {
+	query := t.DB.WithContext(c.Request.Context()).Where("requester_id=?", c.GetInt("userId"))
+	if status := c.Query("status"); status != "" {
+		if !requestStatuses[status] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "status must be Pending, Approved, Rejected or Cancelled"})
+			return
+		}
+		query = query.Where("status=?", status)
+	}
+
+	requests := []models.TaskRequest{}
+	if err := query.Order("created_at DESC, id DESC").Find(&requests).Error; err != nil {
+		respondDBError(c, err, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	c.JSON(http.StatusOK, newTaskRequestResponses(requests))
+}
+
+// PendingTaskRequests lists everyone's pending requests, oldest first, for
+// Admins to work through.
+func (t *TaskController) PendingTaskRequests(c *gin.Context) {
+	requests := []models.TaskRequest{}
+	errDB := t.DB.WithContext(c.Request.Context()).
+		Preload("Requester", func(db *gorm.DB) *gorm.DB { return db.Unscoped().Select("id, name") }).
+		Where("status=?", models.RequestPending).
+		Order("created_at ASC, id ASC").
+		Find(&requests).Error
+	if errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	c.JSON(http.StatusOK, newTaskRequestResponses(requests))
+}
+
+// ApproveTaskRequest turns a pending request into a task, assigned to the
+// requester unless the body names someone else. The task and the request's
+// link to it are written in one transaction, and a request that is no
+// longer pending, approved already included, is a conflict.
+func (t *TaskController) ApproveTaskRequest(c *gin.Context) {
+	var approveReq ApproveTaskRequest
+	if err := c.ShouldBindJSON(&approveReq); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, bindError(err))
+		return
+	}
+
+	request, ok := t.findTaskRequest(c)
+	if !ok {
+		return
+	}
+	if request.Status != models.RequestPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "Task request is already " + request.Status})
+		return
+	}
+
+	assignee := request.RequesterId
+	if approveReq.UserId != 0 {
+		assignee = approveReq.UserId
+	}
+	if status, message := t.checkAssignee(c, assignee); status != 0 {
+		c.JSON(status, gin.H{"error": message})
+		return
+	}
+
+	task := models.Task{
+		UserId:      assignee,
+		Title:       request.Title,
+		Description: request.Description,
+		Status:      models.StatusQueue,
+		Priority:    request.Priority,
+		DueDate:     request.DueDate,
+	}
+	if approveReq.Priority != "" {
+		task.Priority, _ = models.ParsePriority(approveReq.Priority)
+	}
+	if approveReq.DueDate != nil {
+		dueDate := approveReq.DueDate.UTC()
+		task.DueDate = &dueDate
+	}
+
+	now := time.Now().UTC()
+	reviewer := c.GetInt("userId")
+	errTx := withTx(c.Request.Context(), t.DB, func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&request, request.Id).Error; err != nil {
+			return err
+		}
+		if request.Status != models.RequestPending {
+			return errRequestReviewed
+		}
+		if err := tx.Create(&task).Error; err != nil {
+			return err
+		}
+		// the status condition keeps a second approval out where the
+		// database ignores the row lock
+		result := tx.Model(&request).Where("status=?", models.RequestPending).Updates(map[string]interface{}{
+			"status":      models.RequestApproved,
+			"task_id":     task.Id,
+			"reviewed_by": reviewer,
+			"reviewed_at": now,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errRequestReviewed
+		}
+		return nil
+	})
+	if errors.Is(errTx, errRequestReviewed) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Task request was reviewed concurrently, please reload it"})
+		return
+	}
+	if errTx != nil {
+		respondDBError(c, errTx, http.StatusInternalServerError, internalError)
+		return
+	}
+	request.Status = models.RequestApproved
+	request.TaskId = &task.Id
+	request.ReviewedBy = &reviewer
+	request.ReviewedAt = &now
+
+	t.Metrics.TaskCreated()
+	t.notifyRequester(request, "Task request approved", request.Title)
+	if task.UserId != request.RequesterId {
+		t.notifyAssignee(task, "New task assigned", task.Title, "task_assigned")
+	}
+	t.publishTask(events.TaskCreated, task)
+	c.JSON(http.StatusOK, gin.H{
+		"request": newTaskRequestResponse(request),
+		"task":    newTaskResponse(task),
+	})
+}
+
+// RejectTaskRequest closes a pending request; the reason is required and
+// is sent to the requester.
+func (t *TaskController) RejectTaskRequest(c *gin.Context) {
+	var rejectReq RejectTaskRequest
+	if err := c.ShouldBindJSON(&rejectReq); err != nil {
+		c.JSON(http.StatusBadRequest, bindError(err))
+		return
+	}
+	reason := strings.TrimSpace(rejectReq.Reason)
+	if reason == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Request validation failed", "fields": gin.H{"reason": "reason is required"}})
+		return
+	}
+
+	request, ok := t.findTaskRequest(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now().UTC()
+	reviewer := c.GetInt("userId")
+	if !t.closeTaskRequest(c, &request, map[string]interface{}{
+		"status":      models.RequestRejected,
+		"reason":      reason,
+		"reviewed_by": reviewer,
+		"reviewed_at": now,
+	}) {
+		return
+	}
+	request.Status = models.RequestRejected
+	request.Reason = reason
+	request.ReviewedBy = &reviewer
+	request.ReviewedAt = &now
+
+	t.notifyRequester(request, "Task request rejected", request.Title+": "+reason)
+	c.JSON(http.StatusOK, newTaskRequestResponse(request))
+}
+
+// CancelTaskRequest withdraws one of the caller's own pending requests.
+func (t *TaskController) CancelTaskRequest(c *gin.Context) {
+	request, ok := t.findTaskRequest(c)
+	if !ok {
+		return
+	}
+	if request.RequesterId != c.GetInt("userId") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only cancel your own task requests"})
+		return
+	}
+
+	if !t.closeTaskRequest(c, &request, map[string]interface{}{"status": models.RequestCancelled}) {
+		return
+	}
+	request.Status = models.RequestCancelled
+
+	c.JSON(http.StatusOK, newTaskRequestResponse(request))
+}
+
+func (t *TaskController) findTaskRequest(c *gin.Context) (models.TaskRequest, bool) {
+	request := models.TaskRequest{}
+	errDB := t.DB.WithContext(c.Request.Context()).First(&request, c.Param("id")).Error
+	if errors.Is(errDB, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Task request not found"})
+		return request, false
+	}
+	if errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return request, false
+	}
+	return request, true
+}
+
+// closeTaskRequest applies updates only while the request is still
+// pending, answering 409 when it no longer is.
+func (t *TaskController) closeTaskRequest(c *gin.Context, request *models.TaskRequest, updates map[string]interface{}) bool {
+	if request.Status != models.RequestPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "Task request is already " + request.Status})
+		return false
+	}
+
+	result := t.DB.WithContext(c.Request.Context()).Model(request).Where("status=?", models.RequestPending).Updates(updates)
+	if result.Error != nil {
+		respondDBError(c, result.Error, http.StatusInternalServerError, internalError)
+		return false
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "Task request was reviewed concurrently, please reload it"})
+		return false
+	}
+	return true
+}
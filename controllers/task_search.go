@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"html"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"tusk/models"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	minSearchLength = 2
+	snippetRadius   = 60 // characters of context on each side of the first match
+)
+
+type TaskSearchResult struct {
+	TaskResponse
+	Snippet string `json:"snippet"` // HTML-escaped, matches wrapped in <mark>
+}
+
+// Search finds tasks whose title or description contains every word of q,
+// on top of the list filters, paginated with page and limit. MySQL uses
+// the FULLTEXT index and ranks by relevance; other drivers match with LIKE
+// and put title matches first.
+func (t *TaskController) Search(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if utf8.RuneCountInString(q) < minSearchLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q must be at least " + strconv.Itoa(minSearchLength) + " characters"})
+		return
+	}
+	// only letters and digits are kept, so nothing in q can act as a
+	// boolean-mode operator or a LIKE wildcard
+	terms := searchTerms(q)
+	if len(terms) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "q must contain letters or digits"})
+		return
+	}
+
+	page, errPage := strconv.Atoi(c.Query("page"))
+	if errPage != nil || page < 1 {
+		page = 1
+	}
+	limit, errLimit := strconv.Atoi(c.Query("limit"))
+	if errLimit != nil || limit < 1 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	query, rank := t.matchTasks(t.filterTasks(c), terms)
+	// one expression: gorm drops an OrderBy expression merged with more columns
+	rank.SQL += ", tasks.created_at DESC, tasks.id DESC"
+
+	var total int64
+	if errDB := query.Model(&models.Task{}).Count(&total).Error; errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	tasks := []models.Task{}
+	errDB := query.Preload("User").Preload("Tags").
+		Order(clause.OrderBy{Expression: rank}).
+		Offset((page - 1) * limit).
+		Limit(limit).
+		Find(&tasks).Error
+	if errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, internalError)
+		return
+	}
+
+	responses := withCounts(t.DB.WithContext(c.Request.Context()), newTaskResponses(tasks))
+	results := make([]TaskSearchResult, 0, len(responses))
+	for i, response := range responses {
+		results = append(results, TaskSearchResult{
+			TaskResponse: response,
+			Snippet:      highlightSnippet(tasks[i].Description, terms),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data":       results,
+		"total":      total,
+		"page":       page,
+		"limit":      limit,
+		"totalPages": int(math.Ceil(float64(total) / float64(limit))),
+	})
+}
+
+// matchTasks adds the search condition for terms and returns the ORDER BY
+// expression that ranks the matches.
+func (t *TaskController) matchTasks(query *gorm.DB, terms []string) (*gorm.DB, clause.Expr) {
+	if t.DB.Dialector.Name() == "mysql" {
+		// +word* requires every word, each as a prefix
+		against := "+" + strings.Join(terms, "* +") + "*"
+		query = query.Where("MATCH (tasks.title, tasks.description) AGAINST (? IN BOOLEAN MODE)", against)
+		return query, clause.Expr{
+			SQL:                "MATCH (tasks.title, tasks.description) AGAINST (? IN BOOLEAN MODE) DESC",
+			Vars:               []interface{}{against},
+			WithoutParentheses: true,
+		}
+	}
+
+	for _, term := range terms {
+		like := "%" + escapeLike(term) + "%"
+		query = query.Where("LOWER(tasks.title) LIKE ? ESCAPE '!' OR LOWER(tasks.description) LIKE ? ESCAPE '!'", like, like)
+	}
+	like := "%" + escapeLike(strings.Join(terms, " ")) + "%"
+	return query, clause.Expr{
+		SQL:                "CASE WHEN LOWER(tasks.title) LIKE ? ESCAPE '!' THEN 0 ELSE 1 END",
+		Vars:               []interface{}{like},
+		WithoutParentheses: true,
+	}
+}
+
+// searchTerms splits q into lower-case words of letters and digits.
+func searchTerms(q string) []string {
+	terms := []string{}
+	seen := map[string]bool{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(q), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if !seen[word] {
+			seen[word] = true
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+// highlightSnippet cuts the part of text around the first term and wraps
+// every term in it in <mark>. The text itself is HTML-escaped, so the
+// markers are the only markup. Without a match, e.g. when only the title
+// matched, it is the start of text.
+func highlightSnippet(text string, terms []string) string {
+	runes := []rune(strings.Join(strings.Fields(text), " "))
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// the longest term found at each position, 0 for none
+	matchAt := func(i int) int {
+		longest := 0
+		for _, term := range terms {
+			termRunes := []rune(term)
+			if len(termRunes) > longest && i+len(termRunes) <= len(lower) && string(lower[i:i+len(termRunes)]) == term {
+				longest = len(termRunes)
+			}
+		}
+		return longest
+	}
+
+	first := -1
+	for i := range lower {
+		if matchAt(i) > 0 {
+			first = i
+			break
+		}
+	}
+	start, end := 0, min(len(runes), 2*snippetRadius)
+	if first >= 0 {
+		start = max(0, first-snippetRadius)
+		end = min(len(runes), first+snippetRadius)
+	}
+
+	var out strings.Builder
+	if start > 0 {
+		out.WriteString("…")
+	}
+	plain := start
+	for i := start; i < end; {
+		length := matchAt(i)
+		if length == 0 {
+			i++
+			continue
+		}
+		out.WriteString(html.EscapeString(string(runes[plain:i])))
+		out.WriteString("<mark>" + html.EscapeString(string(runes[i:i+length])) + "</mark>")
+		i += length
+		plain = i
+	}
+	if plain < end {
+		out.WriteString(html.EscapeString(string(runes[plain:end])))
+	}
+	if end < len(runes) {
+		out.WriteString("…")
+	}
+	return out.String()
+}
@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+	"tusk/events"
+	"tusk/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// employeeTransitions are the only moves an Employee may make, and only on
+// tasks assigned to them. Admins may make any workflow transition.
+var employeeTransitions = map[string]string{
+	models.StatusQueue:      models.StatusInProgress,
+	models.StatusInProgress: models.StatusReview,
+}
+
+// transitionError explains why a status change was refused.
+type transitionError struct {
+	Status  int
+	Message string
+	Allowed []string
+}
+
+// checkTransition validates moving task to status on behalf of the caller.
+func checkTransition(task models.Task, role string, userId int, to string) *transitionError {
+	if !models.CanTransition(task.Status, to) {
+		return &transitionError{
+			Status:  http.StatusUnprocessableEntity,
+			Message: "Cannot move task from " + task.Status + " to " + to,
+			Allowed: models.NextStatuses(task.Status),
+		}
+	}
+
+	if role == models.RoleAdmin {
+		return nil
+	}
+
+	if task.UserId != userId || employeeTransitions[task.Status] != to {
+		return &transitionError{
+			Status:  http.StatusForbidden,
+			Message: "You are not allowed to move this task from " + task.Status + " to " + to,
+		}
+	}
+
+	return nil
+}
+
+// moveTasks moves every task in ids from one status to another with a single
+// UPDATE. The status condition guards against a concurrent change, so when
+// fewer rows match than ids given it returns errStatusChanged.
+func moveTasks(db *gorm.DB, ids []int, from, to string, userId int) error {
+	result := db.Model(&models.Task{}).
+		Where("id IN ? AND status=?", ids, from).
+		Updates(map[string]interface{}{
+			"status":            to,
+			"status_changed_at": time.Now().UTC(),
+			"status_changed_by": userId,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != int64(len(ids)) {
+		return errStatusChanged
+	}
+	return nil
+}
+
+// transition moves the task in :id to status for the caller, applying the
+// workflow table and role rules, and writes updates alongside the status
+// change. within, when given, runs in the same transaction first. It
+// answers the client itself on failure; on success it notifies and
+// publishes, and returns the reloaded task for the caller to respond with.
+func (t *TaskController) transition(c *gin.Context, to string, updates map[string]interface{}, within func(tx *gorm.DB, task models.Task) error) (models.Task, bool) {
+	task := models.Task{}
+	id := c.Param("id")
+
+	if err := t.DB.WithContext(c.Request.Context()).First(&task, id).Error; err != nil {
+		respondDBError(c, err, http.StatusNotFound, "not found")
+		return task, false
+	}
+
+	userId := c.GetInt("userId")
+	if errTransition := checkTransition(task, c.GetString("role"), userId, to); errTransition != nil {
+		body := gin.H{"error": errTransition.Message}
+		if errTransition.Allowed != nil {
+			body["validNextStatuses"] = errTransition.Allowed
+		}
+		c.JSON(errTransition.Status, body)
+		return task, false
+	}
+
+	if to == models.StatusApproved && !t.checkSubtasksDone(c, task.Id) {
+		return task, false
+	}
+
+	changes := map[string]interface{}{
+		"status":            to,
+		"status_changed_at": time.Now().UTC(),
+		"status_changed_by": userId,
+	}
+	for column, value := range updates {
+		changes[column] = value
+	}
+	errTx := withTx(c.Request.Context(), t.DB, func(tx *gorm.DB) error {
+		if within != nil {
+			if err := within(tx, task); err != nil {
+				return err
+			}
+		}
+		result := tx.Model(&task).Where("status=?", task.Status).Updates(changes)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errStatusChanged
+		}
+		return nil
+	})
+	if errors.Is(errTx, errStatusChanged) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Task status changed concurrently, please retry"})
+		return task, false
+	}
+	if errTx != nil {
+		respondDBError(c, errTx, http.StatusInternalServerError, "Task status could not be changed")
+		return task, false
+	}
+
+	errDB := t.DB.WithContext(c.Request.Context()).Preload("User").Preload("Tags").First(&task, id).Error
+	if errDB != nil {
+		respondDBError(c, errDB, http.StatusInternalServerError, "Task could not be loaded")
+		return task, false
+	}
+
+	t.notifyStatusChange(task)
+	t.publishTask(events.TaskStatusChanged, task)
+	return task, true
+}
@@ -0,0 +1,172 @@
+package controllers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"tusk/models"
+	"tusk/routes"
+	"tusk/testutil"
+)
+
+func TestLegacyStatusRoutesFollowWorkflow(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t))
+	admin := testutil.User(t, s.DB, models.RoleAdmin)
+	employee := testutil.User(t, s.DB, models.RoleEmployee)
+	adminToken, employeeToken := testutil.Token(t, admin), testutil.Token(t, employee)
+
+	t.Run("employee can't approve", func(t *testing.T) {
+		task := createTask(t, s, employee.Id, models.StatusQueue)
+		res := s.Do(t, http.MethodPatch, routes.Prefix+"/tasks/"+itoa(task.Id)+"/approve", employeeToken, nil)
+		testutil.Expect(t, res, http.StatusForbidden)
+		if got := reloadTask(t, s, task.Id).Status; got != models.StatusQueue {
+			t.Errorf("status = %s, want Queue", got)
+		}
+	})
+
+	t.Run("employee can't reject", func(t *testing.T) {
+		task := createTask(t, s, employee.Id, models.StatusReview)
+		res := s.Do(t, http.MethodPatch, routes.Prefix+"/tasks/"+itoa(task.Id)+"/reject", employeeToken, strings.NewReader("reason=no"))
+		testutil.Expect(t, res, http.StatusForbidden)
+	})
+
+	t.Run("approve skips no status", func(t *testing.T) {
+		task := createTask(t, s, employee.Id, models.StatusQueue)
+		res := s.Do(t, http.MethodPatch, routes.Prefix+"/tasks/"+itoa(task.Id)+"/approve", adminToken, nil)
+		testutil.Expect(t, res, http.StatusUnprocessableEntity)
+		if !strings.Contains(res.Body.String(), "validNextStatuses") {
+			t.Errorf("body lacks validNextStatuses: %s", res.Body.String())
+		}
+	})
+
+	t.Run("approve records the change", func(t *testing.T) {
+		task := createTask(t, s, employee.Id, models.StatusReview)
+		res := s.Do(t, http.MethodPatch, routes.Prefix+"/tasks/"+itoa(task.Id)+"/approve", adminToken, nil)
+		testutil.Expect(t, res, http.StatusOK)
+
+		got := reloadTask(t, s, task.Id)
+		if got.Status != models.StatusApproved || got.StatusChangedAt == nil || got.StatusChangedBy == nil || *got.StatusChangedBy != admin.Id {
+			t.Errorf("task = %+v, want Approved with status_changed_at and by %d", got, admin.Id)
+		}
+	})
+
+	t.Run("reject needs a reason", func(t *testing.T) {
+		task := createTask(t, s, employee.Id, models.StatusReview)
+		req := httptest.NewRequest(http.MethodPatch, routes.Prefix+"/tasks/"+itoa(task.Id)+"/reject", strings.NewReader("reason=+"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Authorization", "Bearer "+adminToken)
+		res := httptest.NewRecorder()
+		s.Router.ServeHTTP(res, req)
+		testutil.Expect(t, res, http.StatusBadRequest)
+	})
+
+	t.Run("fix goes back to InProgress", func(t *testing.T) {
+		task := createTask(t, s, employee.Id, models.StatusRejected)
+		res := s.Do(t, http.MethodPatch, routes.Prefix+"/tasks/"+itoa(task.Id)+"/fix", employeeToken, nil)
+		testutil.Expect(t, res, http.StatusForbidden)
+
+		res = s.Do(t, http.MethodPatch, routes.Prefix+"/tasks/"+itoa(task.Id)+"/fix", adminToken, nil)
+		testutil.Expect(t, res, http.StatusOK)
+		if got := reloadTask(t, s, task.Id).Status; got != models.StatusInProgress {
+			t.Errorf("status = %s, want InProgress", got)
+		}
+	})
+
+	t.Run("submit stores an attachment", func(t *testing.T) {
+		task := createTask(t, s, employee.Id, models.StatusInProgress)
+		body, contentType := multipartBody(t, "attachment", "../../etc/report.pdf", []byte("%PDF-1.4 evidence"))
+		req := httptest.NewRequest(http.MethodPatch, routes.Prefix+"/tasks/"+itoa(task.Id)+"/submit", body)
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Authorization", "Bearer "+employeeToken)
+		res := httptest.NewRecorder()
+		s.Router.ServeHTTP(res, req)
+		testutil.Expect(t, res, http.StatusOK)
+
+		got := reloadTask(t, s, task.Id)
+		if got.Status != models.StatusReview || got.StatusChangedBy == nil {
+			t.Errorf("task = %+v, want Review with status_changed_by", got)
+		}
+		attachment := models.Attachment{}
+		if err := s.DB.Where("task_id = ?", task.Id).First(&attachment).Error; err != nil {
+			t.Fatal(err)
+		}
+		if attachment.FileName != "report.pdf" || strings.Contains(attachment.Path, "/") || strings.Contains(attachment.Path, "report") {
+			t.Errorf("attachment = %+v, want a random path and the base name", attachment)
+		}
+	})
+
+	t.Run("submit only from InProgress", func(t *testing.T) {
+		task := createTask(t, s, employee.Id, models.StatusQueue)
+		body, contentType := multipartBody(t, "attachment", "report.pdf", []byte("%PDF-1.4 evidence"))
+		req := httptest.NewRequest(http.MethodPatch, routes.Prefix+"/tasks/"+itoa(task.Id)+"/submit", body)
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Authorization", "Bearer "+employeeToken)
+		res := httptest.NewRecorder()
+		s.Router.ServeHTTP(res, req)
+		testutil.Expect(t, res, http.StatusUnprocessableEntity)
+
+		var count int64
+		s.DB.Model(&models.Attachment{}).Where("task_id = ?", task.Id).Count(&count)
+		if count != 0 {
+			t.Errorf("%d attachments stored for a refused submit", count)
+		}
+	})
+}
+
+func TestSubmitEvidenceFollowsWorkflow(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t))
+	employee := testutil.User(t, s.DB, models.RoleEmployee)
+	token := testutil.Token(t, employee)
+	png := []byte("\x89PNG\r\n\x1a\n evidence")
+
+	submit := func(t *testing.T, task models.Task) *httptest.ResponseRecorder {
+		t.Helper()
+
+		body, contentType := multipartBody(t, "photo", "proof.png", png)
+		req := httptest.NewRequest(http.MethodPost, routes.Prefix+"/tasks/"+itoa(task.Id)+"/submit", body)
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Authorization", "Bearer "+token)
+		res := httptest.NewRecorder()
+		s.Router.ServeHTTP(res, req)
+		return res
+	}
+	submissions := func(task models.Task) int64 {
+		var count int64
+		s.DB.Model(&models.TaskSubmission{}).Where("task_id = ?", task.Id).Count(&count)
+		return count
+	}
+
+	t.Run("from InProgress", func(t *testing.T) {
+		task := createTask(t, s, employee.Id, models.StatusInProgress)
+		testutil.Expect(t, submit(t, task), http.StatusOK)
+
+		got := reloadTask(t, s, task.Id)
+		if got.Status != models.StatusReview || got.EvidencePath == "" || got.StatusChangedBy == nil || *got.StatusChangedBy != employee.Id {
+			t.Errorf("task = %+v, want Review with the evidence", got)
+		}
+		if n := submissions(task); n != 1 {
+			t.Errorf("%d submissions stored, want 1", n)
+		}
+	})
+
+	// Rejected goes back through InProgress, as with PATCH /submit
+	for _, status := range []string{models.StatusRejected, models.StatusQueue, models.StatusApproved} {
+		t.Run("not from "+status, func(t *testing.T) {
+			task := createTask(t, s, employee.Id, status)
+			res := submit(t, task)
+			testutil.Expect(t, res, http.StatusUnprocessableEntity)
+			if !strings.Contains(res.Body.String(), "validNextStatuses") {
+				t.Errorf("body lacks validNextStatuses: %s", res.Body.String())
+			}
+			if got := reloadTask(t, s, task.Id).Status; got != status || submissions(task) != 0 {
+				t.Errorf("status = %s with %d submissions, want it left at %s", got, submissions(task), status)
+			}
+		})
+	}
+
+	t.Run("not someone else's", func(t *testing.T) {
+		task := createTask(t, s, testutil.User(t, s.DB, models.RoleEmployee).Id, models.StatusInProgress)
+		testutil.Expect(t, submit(t, task), http.StatusForbidden)
+	})
+}
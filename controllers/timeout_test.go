@@ -0,0 +1,51 @@
+package controllers_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+	"tusk/config"
+	"tusk/controllers"
+	"tusk/routes"
+	"tusk/testutil"
+
+	"github.com/gin-gonic/gin"
+)
+
+// slowQuery counts forever, until its context is cancelled.
+const slowQuery = "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT count(*) FROM n"
+
+func TestSlowQueriesAnswer503(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t), func(cfg *config.Config) {
+		cfg.QueryTimeout = 50 * time.Millisecond
+	})
+	slow := func(c *gin.Context) error {
+		var count int64
+		return s.DB.WithContext(c.Request.Context()).Raw(slowQuery).Scan(&count).Error
+	}
+	s.Router.GET(routes.Prefix+"/slow/plain", func(c *gin.Context) {
+		if err := slow(c); err != nil {
+			controllers.RespondDBError(c, err, http.StatusInternalServerError, "failed")
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	s.Router.GET(routes.Prefix+"/slow/envelope", func(c *gin.Context) {
+		if err := slow(c); err != nil {
+			c.Error(err)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+
+	for name, path := range map[string]string{"respondDBError": "/slow/plain", "apierror": "/slow/envelope"} {
+		t.Run(name, func(t *testing.T) {
+			started := time.Now()
+			res := s.Do(t, http.MethodGet, routes.Prefix+path, "", nil)
+			testutil.Expect(t, res, http.StatusServiceUnavailable)
+			if elapsed := time.Since(started); elapsed > 5*time.Second {
+				t.Errorf("query ran %s, want it cancelled after 50ms", elapsed)
+			}
+		})
+	}
+}
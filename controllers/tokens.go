@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+	"tusk/models"
+
+	"gorm.io/gorm"
+)
+
+var (
+	errRefreshTokenReused = errors.New("refresh token already rotated")
+	errResetTokenUsed     = errors.New("reset token already used")
+
+	errVerificationInvalid = errors.New("verification token already used or for another email")
+)
+
+// randomToken returns a URL-safe random string with 256 bits of entropy.
+func randomToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueRefreshToken stores a new refresh token for the user and returns the
+// plain value. An empty familyId starts a new chain.
+func issueRefreshToken(db *gorm.DB, userId int, familyId string, expiry time.Duration) (string, error) {
+	token, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	if familyId == "" {
+		if familyId, err = randomToken(); err != nil {
+			return "", err
+		}
+	}
+
+	refreshToken := models.RefreshToken{
+		UserId:    userId,
+		TokenHash: hashToken(token),
+		FamilyId:  familyId,
+		ExpiresAt: time.Now().UTC().Add(expiry),
+	}
+	if err := db.Create(&refreshToken).Error; err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// revokeAllTokens bumps the user's token version, invalidating every access
+// token issued so far, and revokes their refresh tokens.
+func revokeAllTokens(db *gorm.DB, userId int) error {
+	errDB := db.Model(&models.User{}).Where("id = ?", userId).
+		Update("token_version", gorm.Expr("token_version + 1")).Error
+	if errDB != nil {
+		return errDB
+	}
+	return db.Model(&models.RefreshToken{}).
+		Where("user_id = ? AND revoked = ?", userId, false).
+		Update("revoked", true).Error
+}
@@ -0,0 +1,17 @@
+package controllers
+
+import (
+	"context"
+	"tusk/cache"
+
+	"gorm.io/gorm"
+)
+
+// withTx runs fn in a transaction bound to ctx, so a cancelled request
+// rolls it back. It commits when fn returns nil and rolls back when fn
+// returns an error or panics; the panic is re-raised after the rollback.
+// Every write of a multi-step flow must go through tx. The cached
+// responses its writes touch are dropped once it has committed.
+func withTx(ctx context.Context, db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	return cache.Transaction(db.WithContext(ctx), fn)
+}
@@ -0,0 +1,152 @@
+package controllers_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"tusk/controllers"
+	"tusk/models"
+	"tusk/routes"
+	"tusk/testutil"
+
+	"gorm.io/gorm"
+)
+
+var errForced = errors.New("forced failure")
+
+// failWrites makes the creates and deletes on table fail while the
+// returned switch is on, after the earlier steps of a flow have run.
+func failWrites(t *testing.T, db *gorm.DB, table string) *atomic.Bool {
+	t.Helper()
+
+	failing := &atomic.Bool{}
+	fail := func(db *gorm.DB) {
+		if failing.Load() && db.Statement.Table == table {
+			db.AddError(errForced)
+		}
+	}
+	if err := db.Callback().Create().Before("gorm:create").Register("test:fail_create", fail); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Callback().Delete().Before("gorm:delete").Register("test:fail_delete", fail); err != nil {
+		t.Fatal(err)
+	}
+	return failing
+}
+
+func TestTaskCreateFailingMidWay(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t))
+	token := testutil.Token(t, testutil.User(t, s.DB, models.RoleAdmin))
+	employee := testutil.User(t, s.DB, models.RoleEmployee)
+	s.DB.Create(&models.Tag{Name: "finance"})
+	failWrites(t, s.DB, "task_activities").Store(true)
+
+	// the task and its tags go in before the activity entry fails
+	res := s.Do(t, http.MethodPost, routes.Prefix+"/tasks", token, map[string]interface{}{
+		"title": "Write report", "userId": employee.Id, "tags": []string{"finance"},
+	})
+	testutil.Expect(t, res, http.StatusInternalServerError)
+
+	for table, model := range map[string]interface{}{"tasks": &models.Task{}, "task_activities": &models.TaskActivity{}} {
+		var n int64
+		s.DB.Model(model).Count(&n)
+		if n != 0 {
+			t.Errorf("%d rows left in %s", n, table)
+		}
+	}
+	var links int64
+	s.DB.Table("task_tags").Count(&links)
+	if links != 0 {
+		t.Errorf("%d task_tags rows left", links)
+	}
+}
+
+func TestDeleteUserFailingMidWay(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t))
+	token := testutil.Token(t, testutil.User(t, s.DB, models.RoleAdmin))
+	employee := testutil.User(t, s.DB, models.RoleEmployee)
+	colleague := testutil.User(t, s.DB, models.RoleEmployee)
+	task := createTask(t, s, employee.Id, models.StatusInProgress)
+	failWrites(t, s.DB, "users").Store(true)
+
+	// the open task is reassigned before the soft delete fails
+	res := s.Do(t, http.MethodDelete, routes.Prefix+"/users/"+itoa(employee.Id)+"?reassignTo="+itoa(colleague.Id), token, nil)
+	testutil.Expect(t, res, http.StatusInternalServerError)
+
+	if got := reloadTask(t, s, task.Id); got.UserId != employee.Id || got.PreviousUserId != nil {
+		t.Errorf("task = user %d, previous %v; want it left with %d", got.UserId, got.PreviousUserId, employee.Id)
+	}
+	if err := s.DB.First(&models.User{}, employee.Id).Error; err != nil {
+		t.Errorf("user is gone: %v", err)
+	}
+}
+
+func TestWithTxRollsBackOnPanic(t *testing.T) {
+	db := testutil.DB(t)
+
+	func() {
+		defer func() {
+			if recovered := recover(); recovered != "boom" {
+				t.Errorf("recovered %v, want the panic re-raised", recovered)
+			}
+		}()
+		controllers.WithTx(context.Background(), db, func(tx *gorm.DB) error {
+			if err := tx.Create(&models.Tag{Name: "finance"}).Error; err != nil {
+				t.Fatal(err)
+			}
+			panic("boom")
+		})
+	}()
+
+	var n int64
+	db.Model(&models.Tag{}).Count(&n)
+	if n != 0 {
+		t.Errorf("%d tags committed by a panicking transaction", n)
+	}
+}
+
+func TestWithTxRollsBackOnCancel(t *testing.T) {
+	db := testutil.DB(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err := controllers.WithTx(ctx, db, func(tx *gorm.DB) error {
+		if err := tx.Create(&models.Tag{Name: "finance"}).Error; err != nil {
+			return err
+		}
+		cancel()
+		return tx.Create(&models.Tag{Name: "legal"}).Error
+	})
+	if err == nil {
+		t.Fatal("committed after the context was cancelled")
+	}
+	var n int64
+	db.Model(&models.Tag{}).Count(&n)
+	if n != 0 {
+		t.Errorf("%d tags committed by a cancelled transaction", n)
+	}
+}
+
+func TestActivityFailureRollsBackTaskChange(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t))
+	token := testutil.Token(t, testutil.User(t, s.DB, models.RoleAdmin))
+	employee := testutil.User(t, s.DB, models.RoleEmployee)
+	colleague := testutil.User(t, s.DB, models.RoleEmployee)
+	task := createTask(t, s, employee.Id, models.StatusQueue)
+	failWrites(t, s.DB, "task_activities").Store(true)
+
+	// Assign writes outside an explicit transaction
+	res := s.Do(t, http.MethodPatch, routes.Prefix+"/tasks/"+itoa(task.Id)+"/assign", token, map[string]int{"userId": colleague.Id})
+	testutil.Expect(t, res, http.StatusInternalServerError)
+	if got := reloadTask(t, s, task.Id); got.UserId != employee.Id || got.PreviousUserId != nil {
+		t.Errorf("task = user %d, previous %v; want it left with %d", got.UserId, got.PreviousUserId, employee.Id)
+	}
+
+	if err := s.DB.Model(&task).Update("status", models.StatusInProgress).Error; !errors.Is(err, errForced) {
+		t.Errorf("update error = %v, want the activity failure", err)
+	}
+	if got := reloadTask(t, s, task.Id).Status; got != models.StatusQueue {
+		t.Errorf("status = %s, want the change rolled back", got)
+	}
+}
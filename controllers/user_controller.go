@@ -1,55 +1,170 @@
 package controllers
 
 import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"log"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
+	"time"
+	"tusk/apierror"
+	"tusk/cache"
+	"tusk/config"
+	"tusk/mailer"
+	"tusk/metrics"
+	"tusk/middlewares"
 	"tusk/models"
+	"tusk/passwordpolicy"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type UserController struct {
-	DB *gorm.DB
+	DB             *gorm.DB
+	JWTSecret      string
+	TokenExpiry    time.Duration
+	RefreshExpiry  time.Duration
+	Mailer         mailer.Mailer
+	MailConfigured bool // SMTP aktif; kalau tidak, mail hanya masuk log
+	ResetURL       string
+	VerifyURL      string
+	Lockout        config.LockoutConfig
+	BcryptCost     int
+	Denylist       middlewares.Denylist
+	Cache          *cache.Cache[gin.H] // daftar employee; nil berarti tanpa cache
+	Avatars        config.AvatarConfig
+	Metrics        *metrics.Metrics
 }
 
+const passwordResetExpiry = 30 * time.Minute
+
 // Request structs untuk input yang aman
 type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
 }
 
+type RefreshRequest struct {
+	RefreshToken string `json:"refreshToken" binding:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refreshToken"`
+}
+
+// SkipVerification menandai email langsung terverifikasi, untuk akun yang
+// emailnya sudah dipastikan Admin
 type CreateUserRequest struct {
-	Name     string `json:"name" binding:"required"`
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Name             string `json:"name" binding:"required"`
+	Email            string `json:"email" binding:"required,email"`
+	Password         string `json:"password" binding:"required"`
+	SkipVerification bool   `json:"skipVerification"`
+	DepartmentId     *int   `json:"departmentId"`
+}
+
+// Field kosong (nil) tidak diubah. DepartmentId hanya boleh diubah Admin.
+type UpdateUserRequest struct {
+	Name         *string `json:"name" binding:"omitempty,min=1,max=255"`
+	Email        *string `json:"email" binding:"omitempty,email,max=50"`
+	Timezone     *string `json:"timezone" binding:"omitempty,eq=|timezone"` // "" kembali ke UTC
+	DepartmentId *int    `json:"departmentId"`                              // 0 melepas dari department
+}
+
+type ChangeRoleRequest struct {
+	Role string `json:"role" binding:"required,role"`
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"oldPassword" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required"`
+}
+
+type DeviceTokenRequest struct {
+	DeviceToken string `json:"deviceToken" binding:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required"`
 }
 
 // Response structs untuk output yang aman (tanpa password)
 type UserResponse struct {
-	Id        int    `json:"id"`
-	Role      string `json:"role"`
-	Name      string `json:"name"`
-	Email     string `json:"email"`
-	CreatedAt string `json:"createdAt"`
-	UpdatedAt string `json:"updatedAt"`
+	Id            int     `json:"id"`
+	Role          string  `json:"role"`
+	Name          string  `json:"name"`
+	Email         string  `json:"email"`
+	EmailVerified bool    `json:"emailVerified"`
+	Timezone      string  `json:"timezone"`
+	DepartmentId  *int    `json:"departmentId"`
+	AvatarUrl     *string `json:"avatarUrl"` // null kalau belum ada avatar
+	IsActive      bool    `json:"isActive"`
+	CreatedAt     string  `json:"createdAt"`
+	UpdatedAt     string  `json:"updatedAt"`
+}
+
+func newUserResponse(user models.User) UserResponse {
+	return UserResponse{
+		Id:            user.Id,
+		Role:          user.Role,
+		Name:          user.Name,
+		Email:         user.Email,
+		EmailVerified: user.EmailVerified,
+		Timezone:      user.Timezone,
+		DepartmentId:  user.DepartmentId,
+		AvatarUrl:     avatarURL(user),
+		IsActive:      user.IsActive,
+		CreatedAt:     formatTime(user.CreatedAt),
+		UpdatedAt:     formatTime(user.UpdatedAt),
+	}
 }
 
+// userResponseColumns adalah kolom yang dibaca newUserResponse, untuk query
+// yang tidak memuat semua kolom users
+const userResponseColumns = "id, name, email, role, is_active, email_verified, timezone, department_id, avatar_path, created_at, updated_at"
+
 func (u *UserController) Login(c *gin.Context) {
 	var loginReq LoginRequest
 
 	// Bind dan validasi input
 	if err := c.ShouldBindJSON(&loginReq); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apierror.Validation(err))
 		return
 	}
 
 	var user models.User
 	// Cari user berdasarkan email
-	errDB := u.DB.Where("email = ?", loginReq.Email).First(&user).Error
+	errDB := u.DB.WithContext(c.Request.Context()).Where("LOWER(email) = ?", normalizeEmail(loginReq.Email)).First(&user).Error
 	if errDB != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Email or Password is Wrong"})
+		u.Metrics.Login(false)
+		c.Error(apierror.Unauthorized(apierror.CodeInvalidCredentials, "Email or Password is Wrong").Wrap(errDB))
+		return
+	}
+
+	// Akun nonaktif atau terkunci: tolak tanpa menjalankan bcrypt
+	if !user.IsActive {
+		u.Metrics.Login(false)
+		c.Error(apierror.Forbidden(apierror.CodeAccountDeactivated, "Account is deactivated"))
+		return
+	}
+	now := time.Now().UTC()
+	if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		remaining := user.LockedUntil.Sub(now)
+		retryAfter := int(remaining.Seconds()) + 1
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		u.Metrics.Login(false)
+		c.Error(apierror.New(http.StatusLocked, apierror.CodeAccountLocked, "Account is locked due to too many failed login attempts").
+			WithDetails(gin.H{"retryAfterSeconds": retryAfter}))
 		return
 	}
 
@@ -59,100 +174,863 @@ func (u *UserController) Login(c *gin.Context) {
 		[]byte(loginReq.Password),
 	)
 	if errHash != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Email or Password is Wrong"})
+		u.recordFailedLogin(&user, now)
+		u.Metrics.Login(false)
+		c.Error(apierror.Unauthorized(apierror.CodeInvalidCredentials, "Email or Password is Wrong"))
+		return
+	}
+
+	// Login berhasil: reset hitungan gagal
+	if user.FailedAttempts > 0 || user.LockedUntil != nil {
+		u.DB.Model(&user).Updates(map[string]interface{}{
+			"failed_attempts": 0,
+			"last_failed_at":  nil,
+			"locked_until":    nil,
+		})
+	}
+
+	// Hash lama dengan cost lebih rendah diganti selagi password asli ada
+	u.rehashPassword(c, user, loginReq.Password)
+
+	// Buat access token; selama MustChangePassword token hanya berlaku
+	// untuk ganti password
+	token, errToken := middlewares.GenerateToken(u.JWTSecret, user.Id, user.Role, user.TokenVersion, user.MustChangePassword, u.TokenExpiry)
+	if errToken != nil {
+		c.Error(apierror.Internal(errToken))
+		return
+	}
+
+	refreshToken, errRefresh := issueRefreshToken(u.DB, user.Id, "", u.RefreshExpiry)
+	if errRefresh != nil {
+		c.Error(apierror.Internal(errRefresh))
 		return
 	}
 
 	// Return user data tanpa password
-	userResponse := UserResponse{
-		Id:        user.Id,
-		Role:      user.Role,
-		Name:      user.Name,
-		Email:     user.Email,
-		CreatedAt: user.CreatedAt.Format("2006-01-02 15:04:05"),
-		UpdatedAt: user.UpdatedAt.Format("2006-01-02 15:04:05"),
+	userResponse := newUserResponse(user)
+
+	u.Metrics.Login(true)
+	c.JSON(http.StatusOK, gin.H{
+		"message":            "Login successful",
+		"token":              token,
+		"refreshToken":       refreshToken,
+		"user":               userResponse,
+		"mustChangePassword": user.MustChangePassword,
+		"emailVerified":      user.EmailVerified,
+	})
+}
+
+// rehashPassword menyimpan ulang hash password dengan BcryptCost saat ini
+// kalau hash lama dibuat dengan cost yang lebih rendah.
+func (u *UserController) rehashPassword(c *gin.Context, user models.User, password string) {
+	cost, err := bcrypt.Cost([]byte(user.Password))
+	if err != nil || cost >= u.BcryptCost {
+		return
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), u.BcryptCost)
+	if err == nil {
+		err = u.DB.Model(&user).Update("password", string(hashed)).Error
+	}
+	if err != nil {
+		middlewares.Logger(c).Warn("password rehash failed", "user_id", user.Id, "error", err)
+	}
+}
+
+// checkPassword menjalankan password policy dan melaporkan pelanggarannya
+// sebagai field error, sama seperti aturan binding.
+func checkPassword(field, password, email string) *apierror.Error {
+	violation := passwordpolicy.Check(password, email)
+	if violation == nil {
+		return nil
+	}
+	return apierror.BadRequest(apierror.CodeValidationFailed, "Request validation failed").WithDetails(apierror.FieldError{
+		Field:   field,
+		Rule:    violation.Rule,
+		Message: field + " " + violation.Message,
+	})
+}
+
+// recordFailedLogin menambah hitungan gagal dan mengunci akun bila sudah
+// mencapai batas dalam satu window
+func (u *UserController) recordFailedLogin(user *models.User, now time.Time) {
+	attempts := user.FailedAttempts + 1
+	if user.LastFailedAt == nil || now.Sub(*user.LastFailedAt) > u.Lockout.Window {
+		attempts = 1
+	}
+
+	updates := map[string]interface{}{
+		"failed_attempts": attempts,
+		"last_failed_at":  now,
+	}
+	if attempts >= u.Lockout.MaxAttempts {
+		updates["locked_until"] = now.Add(u.Lockout.Duration)
+		updates["failed_attempts"] = 0
+	}
+
+	u.DB.Model(user).Updates(updates)
+}
+
+func (u *UserController) Unlock(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apierror.BadRequest(apierror.CodeInvalidId, "Invalid user ID"))
+		return
+	}
+
+	var user models.User
+	if u.DB.First(&user, id).Error != nil {
+		c.Error(apierror.NotFound(apierror.CodeUserNotFound, "User not found"))
+		return
+	}
+
+	errDB := u.DB.Model(&user).Updates(map[string]interface{}{
+		"failed_attempts": 0,
+		"last_failed_at":  nil,
+		"locked_until":    nil,
+	}).Error
+	if errDB != nil {
+		c.Error(apierror.Internal(errDB))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unlocked successfully"})
+}
+
+// Deactivate menonaktifkan akun tanpa menghapus histori. Semua token
+// dicabut sehingga sesi yang masih berjalan langsung berhenti.
+func (u *UserController) Deactivate(c *gin.Context) {
+	u.setActive(c, false)
+}
+
+// Activate mengaktifkan kembali akun yang dinonaktifkan.
+func (u *UserController) Activate(c *gin.Context) {
+	u.setActive(c, true)
+}
+
+func (u *UserController) setActive(c *gin.Context, active bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apierror.BadRequest(apierror.CodeInvalidId, "Invalid user ID"))
+		return
+	}
+
+	if !active && id == c.GetInt("userId") {
+		c.Error(apierror.Forbidden(apierror.CodeSelfDeactivation, "You cannot deactivate your own account"))
+		return
+	}
+
+	var user models.User
+	if u.DB.First(&user, id).Error != nil {
+		c.Error(apierror.NotFound(apierror.CodeUserNotFound, "User not found"))
+		return
+	}
+
+	errDB := withTx(c.Request.Context(), u.DB, func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Update("is_active", active).Error; err != nil {
+			return err
+		}
+		if active {
+			return nil
+		}
+		return revokeAllTokens(tx, user.Id)
+	})
+	if errDB != nil {
+		c.Error(apierror.Internal(errDB))
+		return
+	}
+
+	message := "User activated successfully"
+	if !active {
+		message = "User deactivated successfully"
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message, "user": newUserResponse(user)})
+}
+
+func (u *UserController) Refresh(c *gin.Context) {
+	var refreshReq RefreshRequest
+	if err := c.ShouldBindJSON(&refreshReq); err != nil {
+		c.Error(apierror.Validation(err))
+		return
+	}
+
+	var stored models.RefreshToken
+	if u.DB.Preload("User").Where("token_hash = ?", hashToken(refreshReq.RefreshToken)).First(&stored).Error != nil {
+		c.Error(apierror.Unauthorized(apierror.CodeInvalidToken, "Invalid refresh token"))
+		return
+	}
+
+	if stored.Revoked {
+		// Token lama dipakai ulang: cabut seluruh rantai token dari login ini
+		u.DB.Model(&models.RefreshToken{}).Where("family_id = ?", stored.FamilyId).Update("revoked", true)
+		c.Error(apierror.Unauthorized(apierror.CodeInvalidToken, "Refresh token reuse detected"))
+		return
+	}
+
+	if time.Now().UTC().After(stored.ExpiresAt) {
+		c.Error(apierror.Unauthorized(apierror.CodeInvalidToken, "Refresh token expired"))
+		return
+	}
+
+	if !stored.User.IsActive {
+		c.Error(apierror.Forbidden(apierror.CodeAccountDeactivated, "Account is deactivated"))
+		return
+	}
+
+	// Rotasi: token lama dicabut, token baru satu keluarga diterbitkan
+	var newRefreshToken string
+	errTx := withTx(c.Request.Context(), u.DB, func(tx *gorm.DB) error {
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND revoked = ?", stored.Id, false).
+			Update("revoked", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errRefreshTokenReused
+		}
+
+		var err error
+		newRefreshToken, err = issueRefreshToken(tx, stored.UserId, stored.FamilyId, u.RefreshExpiry)
+		return err
+	})
+	if errors.Is(errTx, errRefreshTokenReused) {
+		u.DB.Model(&models.RefreshToken{}).Where("family_id = ?", stored.FamilyId).Update("revoked", true)
+		c.Error(apierror.Unauthorized(apierror.CodeInvalidToken, "Refresh token reuse detected"))
+		return
+	}
+	if errTx != nil {
+		c.Error(apierror.Internal(errTx))
+		return
+	}
+
+	token, errToken := middlewares.GenerateToken(u.JWTSecret, stored.User.Id, stored.User.Role, stored.User.TokenVersion, stored.User.MustChangePassword, u.TokenExpiry)
+	if errToken != nil {
+		c.Error(apierror.Internal(errToken))
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Login successful",
-		"user":    userResponse,
+		"token":        token,
+		"refreshToken": newRefreshToken,
 	})
 }
 
+// Logout mencabut access token yang dipakai request ini dan, kalau dikirim,
+// seluruh rantai refresh token-nya.
+func (u *UserController) Logout(c *gin.Context) {
+	var logoutReq LogoutRequest
+	if err := c.ShouldBindJSON(&logoutReq); err != nil && !errors.Is(err, io.EOF) {
+		c.Error(apierror.Validation(err))
+		return
+	}
+
+	userId := c.GetInt("userId")
+	if logoutReq.RefreshToken != "" {
+		var stored models.RefreshToken
+		errDB := u.DB.Where("token_hash = ? AND user_id = ?", hashToken(logoutReq.RefreshToken), userId).First(&stored).Error
+		if errDB == nil {
+			errDB = u.DB.Model(&models.RefreshToken{}).Where("family_id = ?", stored.FamilyId).Update("revoked", true).Error
+		}
+		if errDB != nil && !errors.Is(errDB, gorm.ErrRecordNotFound) {
+			c.Error(apierror.Internal(errDB))
+			return
+		}
+	}
+
+	claims := middlewares.TokenClaims(c)
+	if claims != nil && claims.ID != "" && claims.ExpiresAt != nil {
+		if err := u.Denylist.Add(c.Request.Context(), claims.ID, claims.ExpiresAt.Time); err != nil {
+			c.Error(apierror.Internal(err))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// LogoutAll membuat semua token user ini tidak berlaku lagi, di semua
+// perangkat, termasuk token yang dipakai request ini.
+func (u *UserController) LogoutAll(c *gin.Context) {
+	if err := revokeAllTokens(u.DB, c.GetInt("userId")); err != nil {
+		c.Error(apierror.Internal(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out from all devices"})
+}
+
+// CheckTokenVersion is a middlewares.TokenCheck that rejects access tokens
+// issued before the user's last logout-all or password change, and tokens
+// of deleted users.
+func (u *UserController) CheckTokenVersion(c *gin.Context, claims *middlewares.Claims) error {
+	versions := []int{}
+	errDB := u.DB.WithContext(c.Request.Context()).Model(&models.User{}).
+		Where("id = ?", claims.UserId).
+		Pluck("token_version", &versions).Error
+	if errDB != nil {
+		return errDB
+	}
+	if len(versions) == 0 || versions[0] != claims.TokenVersion {
+		return middlewares.ErrTokenRevoked
+	}
+	return nil
+}
+
+// ScopeDepartment is a middlewares.TokenCheck that pins Managers to their
+// own department, read fresh from the database so a move takes effect on
+// the next request. A Manager without a department sees nobody.
+func (u *UserController) ScopeDepartment(c *gin.Context, claims *middlewares.Claims) error {
+	if claims.Role != models.RoleManager {
+		return nil
+	}
+	departments := []*int{}
+	errDB := u.DB.WithContext(c.Request.Context()).Model(&models.User{}).
+		Where("id = ?", claims.UserId).
+		Pluck("department_id", &departments).Error
+	if errDB != nil {
+		return errDB
+	}
+	departmentId := 0
+	if len(departments) > 0 && departments[0] != nil {
+		departmentId = *departments[0]
+	}
+	c.Set("departmentId", departmentId)
+	return nil
+}
+
 func (u *UserController) CreateAccount(c *gin.Context) {
 	var createReq CreateUserRequest
 
 	// Bind dan validasi input
 	if err := c.ShouldBindJSON(&createReq); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		c.Error(apierror.Validation(err))
 		return
 	}
 
-	// Cek apakah email sudah ada
+	// Cek cepat apakah email sudah ada. User yang sudah dihapus (soft delete)
+	// ikut dicek: emailnya tidak bisa dipakai ulang supaya restore selalu aman.
+	// Unique index tetap jadi penjaga utama saat ada signup bersamaan.
+	email := normalizeEmail(createReq.Email)
 	var existingUser models.User
-	if u.DB.Where("email = ?", createReq.Email).First(&existingUser).Error == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Email already exists"})
+	if u.DB.Unscoped().Where("LOWER(email) = ?", email).First(&existingUser).Error == nil {
+		c.Error(apierror.Conflict(apierror.CodeEmailTaken, "Email already exists"))
+		return
+	}
+
+	if errPolicy := checkPassword("password", createReq.Password, email); errPolicy != nil {
+		c.Error(errPolicy)
+		return
+	}
+	if createReq.DepartmentId != nil && !u.checkDepartment(c, *createReq.DepartmentId) {
 		return
 	}
 
 	// Hash password
-	hashedPasswordBytes, err := bcrypt.GenerateFromPassword([]byte(createReq.Password), bcrypt.DefaultCost)
+	hashedPasswordBytes, err := bcrypt.GenerateFromPassword([]byte(createReq.Password), u.BcryptCost)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		c.Error(apierror.Internal(err))
 		return
 	}
 
-	// Buat user baru
+	// Buat user baru, beserta token verifikasi kalau emailnya belum dipastikan
 	newUser := models.User{
-		Name:     createReq.Name,
-		Email:    createReq.Email,
-		Password: string(hashedPasswordBytes),
-		Role:     "Employee",
+		Name:          createReq.Name,
+		Email:         email,
+		Password:      string(hashedPasswordBytes),
+		Role:          models.RoleEmployee,
+		IsActive:      true,
+		EmailVerified: createReq.SkipVerification,
+		DepartmentId:  createReq.DepartmentId,
 	}
 
-	errDB := u.DB.Create(&newUser).Error
+	var link string
+	errDB := withTx(c.Request.Context(), u.DB, func(tx *gorm.DB) error {
+		if err := tx.Create(&newUser).Error; err != nil {
+			return err
+		}
+		if newUser.EmailVerified {
+			return nil
+		}
+		var err error
+		link, err = u.newEmailVerification(tx, newUser)
+		return err
+	})
+	if isDuplicateKey(errDB) {
+		c.Error(apierror.Conflict(apierror.CodeEmailTaken, "Email already exists"))
+		return
+	}
 	if errDB != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": errDB.Error()})
+		c.Error(apierror.Internal(errDB))
 		return
 	}
 
-	// Return response tanpa password
-	userResponse := UserResponse{
-		Id:        newUser.Id,
-		Role:      newUser.Role,
-		Name:      newUser.Name,
-		Email:     newUser.Email,
-		CreatedAt: newUser.CreatedAt.Format("2006-01-02 15:04:05"),
-		UpdatedAt: newUser.UpdatedAt.Format("2006-01-02 15:04:05"),
+	if link != "" {
+		sendTemplate(u.Mailer, newUser.Email, "verify_email", mailData{Name: newUser.Name, Link: link})
 	}
 
+	// Return response tanpa password
+	userResponse := newUserResponse(newUser)
+
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "User created successfully",
 		"user":    userResponse,
 	})
 }
 
-func (u *UserController) Delete(c *gin.Context) {
-	idParam := c.Param("id")
+// checkDepartment memastikan department ada, kalau tidak menulis 422
+func (u *UserController) checkDepartment(c *gin.Context, departmentId int) bool {
+	var found int64
+	errDB := u.DB.WithContext(c.Request.Context()).Model(&models.Department{}).Where("id = ?", departmentId).Count(&found).Error
+	if errDB != nil {
+		c.Error(apierror.Internal(errDB))
+		return false
+	}
+	if found == 0 {
+		c.Error(apierror.New(http.StatusUnprocessableEntity, apierror.CodeDepartmentNotFound, "Department not found"))
+		return false
+	}
+	return true
+}
 
-	// Validasi ID
-	id, err := strconv.Atoi(idParam)
+func (u *UserController) UpdateMe(c *gin.Context) {
+	u.updateUser(c, c.GetInt("userId"))
+}
+
+// SetDeviceToken menyimpan token FCM dari aplikasi mobile untuk push notification
+func (u *UserController) SetDeviceToken(c *gin.Context) {
+	var tokenReq DeviceTokenRequest
+	if errBindJson := c.ShouldBindJSON(&tokenReq); errBindJson != nil {
+		c.Error(apierror.Validation(errBindJson))
+		return
+	}
+
+	errDB := u.DB.WithContext(c.Request.Context()).Model(&models.User{}).
+		Where("id = ?", c.GetInt("userId")).
+		Update("device_token", tokenReq.DeviceToken).Error
+	if errDB != nil {
+		c.Error(apierror.Internal(errDB))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Device token updated"})
+}
+
+func (u *UserController) Update(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.Error(apierror.BadRequest(apierror.CodeInvalidId, "Invalid user ID"))
+		return
+	}
+
+	u.updateUser(c, id)
+}
+
+func (u *UserController) updateUser(c *gin.Context, id int) {
+	var updateReq UpdateUserRequest
+
+	// Bind dan validasi input
+	if err := c.ShouldBindJSON(&updateReq); err != nil {
+		c.Error(apierror.Validation(err))
 		return
 	}
 
-	// Cek apakah user ada
 	var user models.User
 	if u.DB.First(&user, id).Error != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		c.Error(apierror.NotFound(apierror.CodeUserNotFound, "User not found"))
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if updateReq.Name != nil {
+		updates["name"] = strings.TrimSpace(*updateReq.Name)
+	}
+	if updateReq.Timezone != nil {
+		updates["timezone"] = *updateReq.Timezone
+	}
+	if updateReq.DepartmentId != nil {
+		// Pindah department mengubah apa yang dilihat Manager, jadi hanya Admin
+		if c.GetString("role") != models.RoleAdmin {
+			c.Error(apierror.Forbidden(apierror.CodeForbidden, "Only an Admin can change departments"))
+			return
+		}
+		if *updateReq.DepartmentId == 0 {
+			if user.Role == models.RoleManager {
+				c.Error(apierror.New(http.StatusUnprocessableEntity, apierror.CodeManagerWithoutDepartment, "A Manager must belong to a department"))
+				return
+			}
+			updates["department_id"] = nil
+		} else {
+			if !u.checkDepartment(c, *updateReq.DepartmentId) {
+				return
+			}
+			updates["department_id"] = *updateReq.DepartmentId
+		}
+	}
+	if updateReq.Email != nil && normalizeEmail(*updateReq.Email) != user.Email {
+		// Email harus tetap unik tanpa membedakan huruf besar/kecil
+		email := normalizeEmail(*updateReq.Email)
+		var taken int64
+		u.DB.Unscoped().Model(&models.User{}).
+			Where("LOWER(email) = ? AND id <> ?", email, user.Id).
+			Count(&taken)
+		if taken > 0 {
+			c.Error(apierror.Conflict(apierror.CodeEmailTaken, "Email already exists"))
+			return
+		}
+		// Email baru harus diverifikasi ulang; token untuk email lama ikut mati
+		updates["email"] = email
+		updates["email_verified"] = false
+	}
+
+	var link string
+	if len(updates) > 0 {
+		errDB := withTx(c.Request.Context(), u.DB, func(tx *gorm.DB) error {
+			if err := tx.Model(&user).Updates(updates).Error; err != nil {
+				return err
+			}
+			if _, changed := updates["email"]; !changed {
+				return nil
+			}
+			var err error
+			link, err = u.newEmailVerification(tx, user)
+			return err
+		})
+		if isDuplicateKey(errDB) {
+			c.Error(apierror.Conflict(apierror.CodeEmailTaken, "Email already exists"))
+			return
+		}
+		if errDB != nil {
+			c.Error(apierror.Internal(errDB))
+			return
+		}
+	}
+	if link != "" {
+		sendTemplate(u.Mailer, user.Email, "verify_email", mailData{Name: user.Name, Link: link})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User updated successfully",
+		"user":    newUserResponse(user),
+	})
+}
+
+func (u *UserController) ChangeRole(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apierror.BadRequest(apierror.CodeInvalidId, "Invalid user ID"))
 		return
 	}
 
-	// Hapus user
-	errDB := u.DB.Delete(&user).Error
+	var roleReq ChangeRoleRequest
+	if err := c.ShouldBindJSON(&roleReq); err != nil {
+		c.Error(apierror.Validation(err))
+		return
+	}
+
+	// Admin tidak boleh mengubah role dirinya sendiri
+	actorId := c.GetInt("userId")
+	if id == actorId {
+		c.Error(apierror.Forbidden(apierror.CodeForbidden, "You cannot change your own role"))
+		return
+	}
+
+	var user models.User
+	var oldRole string
+	errTx := withTx(c.Request.Context(), u.DB, func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, id).Error; err != nil {
+			return err
+		}
+		oldRole = user.Role
+
+		// Jangan sampai tidak ada Admin aktif tersisa
+		if user.Role == models.RoleAdmin && roleReq.Role != models.RoleAdmin {
+			// baris dikunci lewat Pluck, Postgres menolak FOR UPDATE pada count(*)
+			var otherAdmins []int
+			errAdmins := tx.Model(&models.User{}).
+				Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("role = ? AND is_active = ? AND id <> ?", models.RoleAdmin, true, user.Id).
+				Pluck("id", &otherAdmins).Error
+			if errAdmins != nil {
+				return errAdmins
+			}
+			if len(otherAdmins) == 0 {
+				return errLastAdmin
+			}
+		}
+
+		// Manager hanya melihat department-nya, jadi harus punya satu
+		if roleReq.Role == models.RoleManager && user.DepartmentId == nil {
+			return errNoDepartment
+		}
+
+		if err := tx.Model(&user).Update("role", roleReq.Role).Error; err != nil {
+			return err
+		}
+		// Token lama masih membawa role lama, jadi semuanya dicabut
+		return revokeAllTokens(tx, user.Id)
+	})
+	if errors.Is(errTx, gorm.ErrRecordNotFound) {
+		c.Error(apierror.NotFound(apierror.CodeUserNotFound, "User not found"))
+		return
+	}
+	if errors.Is(errTx, errLastAdmin) {
+		c.Error(apierror.Conflict(apierror.CodeLastAdmin, "Cannot demote the last Admin"))
+		return
+	}
+	if errors.Is(errTx, errNoDepartment) {
+		c.Error(apierror.New(http.StatusUnprocessableEntity, apierror.CodeManagerWithoutDepartment, "Assign a department before making the user a Manager"))
+		return
+	}
+	if errTx != nil {
+		c.Error(apierror.Internal(errTx))
+		return
+	}
+
+	log.Printf("ℹ️ User %d changed role of user %d from %s to %s", actorId, user.Id, oldRole, user.Role)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Role updated successfully",
+		"user":    newUserResponse(user),
+	})
+}
+
+func (u *UserController) ChangePassword(c *gin.Context) {
+	var passwordReq ChangePasswordRequest
+
+	// Bind dan validasi input
+	if err := c.ShouldBindJSON(&passwordReq); err != nil {
+		c.Error(apierror.Validation(err))
+		return
+	}
+
+	// User id selalu dari token, bukan dari body
+	var user models.User
+	if u.DB.First(&user, c.GetInt("userId")).Error != nil {
+		c.Error(apierror.NotFound(apierror.CodeUserNotFound, "User not found"))
+		return
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(passwordReq.OldPassword)) != nil {
+		c.Error(apierror.Unauthorized(apierror.CodeInvalidCredentials, "Old password is wrong"))
+		return
+	}
+
+	if passwordReq.NewPassword == passwordReq.OldPassword {
+		c.Error(apierror.BadRequest(apierror.CodeValidationFailed, "New password must be different from the old password"))
+		return
+	}
+
+	if errPolicy := checkPassword("newPassword", passwordReq.NewPassword, user.Email); errPolicy != nil {
+		c.Error(errPolicy)
+		return
+	}
+
+	hashedPasswordBytes, err := bcrypt.GenerateFromPassword([]byte(passwordReq.NewPassword), u.BcryptCost)
+	if err != nil {
+		c.Error(apierror.Internal(err))
+		return
+	}
+
+	// Simpan hash baru dan cabut semua token yang masih aktif
+	errDB := withTx(c.Request.Context(), u.DB, func(tx *gorm.DB) error {
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"password":             string(hashedPasswordBytes),
+			"must_change_password": false,
+		}).Error; err != nil {
+			return err
+		}
+		return revokeAllTokens(tx, user.Id)
+	})
 	if errDB != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": errDB.Error()})
+		c.Error(apierror.Internal(errDB))
+		return
+	}
+
+	sendTemplate(u.Mailer, user.Email, "password_changed", mailData{Name: user.Name})
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully"})
+}
+
+func (u *UserController) ForgotPassword(c *gin.Context) {
+	var forgotReq ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&forgotReq); err != nil {
+		c.Error(apierror.Validation(err))
+		return
+	}
+
+	// Response selalu sama supaya endpoint ini tidak bisa dipakai mengecek email
+	response := gin.H{"message": "If the email is registered, a reset link has been sent"}
+
+	var user models.User
+	if u.DB.Where("LOWER(email) = ?", normalizeEmail(forgotReq.Email)).First(&user).Error != nil {
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		c.Error(apierror.Internal(err))
+		return
+	}
+
+	reset := models.PasswordReset{
+		UserId:    user.Id,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().UTC().Add(passwordResetExpiry),
+	}
+	if errDB := u.DB.Create(&reset).Error; errDB != nil {
+		c.Error(apierror.Internal(errDB))
+		return
+	}
+
+	// Masuk antrean mail supaya waktu response tidak membocorkan apa-apa
+	link := u.ResetURL + "?token=" + url.QueryEscape(token)
+	sendTemplate(u.Mailer, user.Email, "password_reset", mailData{Name: user.Name, Link: link})
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (u *UserController) ResetPassword(c *gin.Context) {
+	var resetReq ResetPasswordRequest
+	if err := c.ShouldBindJSON(&resetReq); err != nil {
+		c.Error(apierror.Validation(err))
+		return
+	}
+
+	var reset models.PasswordReset
+	if u.DB.Where("token_hash = ?", hashToken(resetReq.Token)).First(&reset).Error != nil ||
+		reset.UsedAt != nil || time.Now().UTC().After(reset.ExpiresAt) {
+		c.Error(apierror.BadRequest(apierror.CodeInvalidToken, "Reset token is invalid or expired"))
+		return
+	}
+
+	var user models.User
+	if u.DB.First(&user, reset.UserId).Error != nil {
+		c.Error(apierror.BadRequest(apierror.CodeInvalidToken, "Reset token is invalid or expired"))
+		return
+	}
+	if errPolicy := checkPassword("newPassword", resetReq.NewPassword, user.Email); errPolicy != nil {
+		c.Error(errPolicy)
+		return
+	}
+
+	hashedPasswordBytes, err := bcrypt.GenerateFromPassword([]byte(resetReq.NewPassword), u.BcryptCost)
+	if err != nil {
+		c.Error(apierror.Internal(err))
+		return
+	}
+
+	errDB := withTx(c.Request.Context(), u.DB, func(tx *gorm.DB) error {
+		// Tandai terpakai dulu; kalau sudah dipakai request lain, batalkan
+		result := tx.Model(&reset).Where("used_at IS NULL").Update("used_at", time.Now().UTC())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errResetTokenUsed
+		}
+
+		if err := tx.Model(&models.User{}).Where("id = ?", reset.UserId).Updates(map[string]interface{}{
+			"password":             string(hashedPasswordBytes),
+			"must_change_password": false,
+		}).Error; err != nil {
+			return err
+		}
+		return revokeAllTokens(tx, reset.UserId)
+	})
+	if errors.Is(errDB, errResetTokenUsed) {
+		c.Error(apierror.BadRequest(apierror.CodeInvalidToken, "Reset token is invalid or expired"))
+		return
+	}
+	if errDB != nil {
+		c.Error(apierror.Internal(errDB))
+		return
+	}
+
+	sendTemplate(u.Mailer, user.Email, "password_changed", mailData{Name: user.Name})
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset"})
+}
+
+func (u *UserController) Delete(c *gin.Context) {
+	idParam := c.Param("id")
+
+	// Validasi ID
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		c.Error(apierror.BadRequest(apierror.CodeInvalidId, "Invalid user ID"))
+		return
+	}
+
+	// ?reassignTo=<id> memindahkan task yang masih terbuka ke employee lain
+	reassignTo := 0
+	if value := c.Query("reassignTo"); value != "" {
+		reassignTo, err = strconv.Atoi(value)
+		if err != nil || reassignTo == id {
+			c.Error(apierror.BadRequest(apierror.CodeInvalidId, "Invalid reassignTo user ID"))
+			return
+		}
+	}
+
+	// Cek, pindahkan task, lalu hapus dalam satu transaksi supaya tidak ada
+	// task yang tertinggal tanpa pemilik kalau salah satu langkah gagal
+	var user models.User
+	var openTasks, reassigned int64
+	errTx := withTx(c.Request.Context(), u.DB, func(tx *gorm.DB) error {
+		if err := tx.First(&user, id).Error; err != nil {
+			return err
+		}
+
+		if reassignTo != 0 {
+			target := models.User{}
+			if err := tx.Where("role = ? AND is_active = ?", models.RoleEmployee, true).First(&target, reassignTo).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return errReassignTarget
+				}
+				return err
+			}
+			result := tx.Model(&models.Task{}).
+				Where("user_id = ? AND status <> ?", user.Id, models.StatusApproved).
+				Updates(map[string]interface{}{
+					"previous_user_id": gorm.Expr("user_id"),
+					"user_id":          target.Id,
+				})
+			if result.Error != nil {
+				return result.Error
+			}
+			reassigned = result.RowsAffected
+		}
+
+		// User dengan task yang masih terbuka tidak bisa dihapus
+		if err := tx.Model(&models.Task{}).
+			Where("user_id = ? AND status <> ?", user.Id, models.StatusApproved).
+			Count(&openTasks).Error; err != nil {
+			return err
+		}
+		if openTasks > 0 {
+			return errUserHasOpenTasks
+		}
+
+		// Soft delete: riwayat task tetap utuh, sesi yang aktif dicabut
+		if err := tx.Delete(&user).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked = ?", user.Id, false).
+			Update("revoked", true).Error
+	})
+	switch {
+	case errors.Is(errTx, gorm.ErrRecordNotFound):
+		c.Error(apierror.NotFound(apierror.CodeUserNotFound, "User not found"))
+		return
+	case errors.Is(errTx, errReassignTarget):
+		c.Error(apierror.New(http.StatusUnprocessableEntity, apierror.CodeUserNotFound, "Tasks can only be reassigned to an active Employee"))
+		return
+	case errors.Is(errTx, errUserHasOpenTasks):
+		c.Error(apierror.Conflict(apierror.CodeUserHasOpenTasks, "User still has open tasks, reassign them first").
+			WithDetails(gin.H{"blockingTasks": openTasks}))
+		return
+	case errTx != nil:
+		c.Error(apierror.Internal(errTx))
 		return
 	}
 
@@ -163,37 +1041,369 @@ func (u *UserController) Delete(c *gin.Context) {
 			"name":  user.Name,
 			"email": user.Email,
 		},
+		"reassignedTasks": reassigned,
 	})
 }
 
-func (u *UserController) GetEmployee(c *gin.Context) {
+func (u *UserController) GetDeleted(c *gin.Context) {
 	var users []models.User
 
-	errDB := u.DB.Select("id, name, email, role, created_at, updated_at").
-		Where("role = ?", "Employee").
+	errDB := u.DB.Unscoped().
+		Select(userResponseColumns + ", deleted_at").
+		Where("deleted_at IS NOT NULL").
+		Order("deleted_at DESC").
 		Find(&users).Error
-
 	if errDB != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": errDB.Error()})
+		c.Error(apierror.Internal(errDB))
 		return
 	}
 
-	// Convert ke response format
-	var userResponses []UserResponse
+	deletedUsers := []gin.H{}
 	for _, user := range users {
-		userResponses = append(userResponses, UserResponse{
-			Id:        user.Id,
-			Role:      user.Role,
-			Name:      user.Name,
-			Email:     user.Email,
-			CreatedAt: user.CreatedAt.Format("2006-01-02 15:04:05"),
-			UpdatedAt: user.UpdatedAt.Format("2006-01-02 15:04:05"),
+		deletedUsers = append(deletedUsers, gin.H{
+			"user":      newUserResponse(user),
+			"deletedAt": formatTime(user.DeletedAt.Time),
 		})
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":   "Employees retrieved successfully",
-		"count":     len(userResponses),
-		"employees": userResponses,
+		"message": "Deleted users retrieved successfully",
+		"count":   len(deletedUsers),
+		"users":   deletedUsers,
 	})
 }
+
+func (u *UserController) Restore(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apierror.BadRequest(apierror.CodeInvalidId, "Invalid user ID"))
+		return
+	}
+
+	var user models.User
+	if u.DB.Unscoped().Where("deleted_at IS NOT NULL").First(&user, id).Error != nil {
+		c.Error(apierror.NotFound(apierror.CodeUserNotFound, "Deleted user not found"))
+		return
+	}
+
+	errDB := u.DB.Unscoped().Model(&user).Update("deleted_at", nil).Error
+	if errDB != nil {
+		c.Error(apierror.Internal(errDB))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User restored successfully",
+		"user":    newUserResponse(user),
+	})
+}
+
+type UserTaskSummary struct {
+	Id      int     `json:"id"`
+	Title   string  `json:"title"`
+	Status  string  `json:"status"`
+	DueDate *string `json:"dueDate"`
+}
+
+type UserDetailResponse struct {
+	UserResponse
+	Tasks      []UserTaskSummary `json:"tasks"`
+	TaskCounts map[string]int64  `json:"taskCounts"`
+}
+
+const defaultTaskLimit = 50
+
+// GetByID mengembalikan satu user beserta task-nya. Employee hanya boleh
+// melihat dirinya sendiri. Kolom password tidak pernah di-select.
+func (u *UserController) GetByID(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(apierror.BadRequest(apierror.CodeInvalidId, "Invalid user ID"))
+		return
+	}
+	if c.GetString("role") != models.RoleAdmin && c.GetInt("userId") != id {
+		c.Error(apierror.Forbidden(apierror.CodeForbidden, "You can only view your own account"))
+		return
+	}
+
+	status := c.Query("status")
+	if status != "" && !models.ValidStatus(status) {
+		c.Error(apierror.BadRequest(apierror.CodeValidationFailed, "Invalid status filter").
+			WithDetails(apierror.FieldError{Field: "status", Rule: "oneof", Message: "status must be one of " + strings.Join(models.Statuses, ", ")}))
+		return
+	}
+	taskLimit, errLimit := strconv.Atoi(c.Query("taskLimit"))
+	if errLimit != nil || taskLimit < 1 {
+		taskLimit = defaultTaskLimit
+	}
+	if taskLimit > maxPageLimit {
+		taskLimit = maxPageLimit
+	}
+
+	db := u.DB.WithContext(c.Request.Context())
+
+	var user models.User
+	errDB := db.Select(userResponseColumns).
+		Preload("Tasks", func(tx *gorm.DB) *gorm.DB {
+			tx = tx.Select("id, user_id, title, status, due_date").Order("created_at DESC, id DESC").Limit(taskLimit)
+			if status != "" {
+				tx = tx.Where("status = ?", status)
+			}
+			return tx
+		}).
+		First(&user, id).Error
+	if errors.Is(errDB, gorm.ErrRecordNotFound) {
+		c.Error(apierror.NotFound(apierror.CodeUserNotFound, "User not found"))
+		return
+	}
+	if errDB != nil {
+		c.Error(apierror.Internal(errDB))
+		return
+	}
+
+	var counts []struct {
+		Status string
+		Total  int64
+	}
+	errCount := db.Model(&models.Task{}).
+		Select("status, COUNT(*) AS total").
+		Where("user_id = ?", id).
+		Group("status").
+		Scan(&counts).Error
+	if errCount != nil {
+		c.Error(apierror.Internal(errCount))
+		return
+	}
+
+	// Semua status selalu ada supaya client tidak perlu cek key yang hilang
+	taskCounts := map[string]int64{}
+	for _, status := range models.Statuses {
+		taskCounts[status] = 0
+	}
+	for _, count := range counts {
+		taskCounts[count.Status] = count.Total
+	}
+
+	tasks := make([]UserTaskSummary, 0, len(user.Tasks))
+	for _, task := range user.Tasks {
+		summary := UserTaskSummary{Id: task.Id, Title: task.Title, Status: task.Status}
+		if task.DueDate != nil {
+			dueDate := formatTime(*task.DueDate)
+			summary.DueDate = &dueDate
+		}
+		tasks = append(tasks, summary)
+	}
+
+	c.JSON(http.StatusOK, UserDetailResponse{
+		UserResponse: newUserResponse(user),
+		Tasks:        tasks,
+		TaskCounts:   taskCounts,
+	})
+}
+
+// Kolom yang boleh dipakai untuk sorting daftar user
+var userSortColumns = map[string]string{
+	"name":       "name",
+	"email":      "email",
+	"created_at": "created_at",
+	"createdAt":  "created_at",
+}
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+func (u *UserController) GetEmployee(c *gin.Context) {
+	// Parameter pagination, nilai tidak valid kembali ke default
+	page, errPage := strconv.Atoi(c.Query("page"))
+	if errPage != nil || page < 1 {
+		page = 1
+	}
+	limit, errLimit := strconv.Atoi(c.Query("limit"))
+	if errLimit != nil || limit < 1 {
+		limit = defaultPageLimit
+	}
+	if limit > maxPageLimit {
+		limit = maxPageLimit
+	}
+
+	sortColumn, ok := userSortColumns[c.Query("sort")]
+	if !ok {
+		sortColumn = "created_at"
+	}
+	direction := "ASC"
+	if strings.EqualFold(c.Query("order"), "desc") {
+		direction = "DESC"
+	}
+
+	// Hasil di-cache per query string, invalidasi lewat callback GORM saat users berubah.
+	// Scope department Manager tidak ada di query string, jadi ikut jadi key.
+	key := "employees:" + c.Request.URL.RawQuery
+	if departmentId, scoped := c.Get("departmentId"); scoped {
+		key = "employees:department=" + strconv.Itoa(departmentId.(int)) + ":" + c.Request.URL.RawQuery
+	}
+	response, errDB := u.Cache.Get(key, func() (gin.H, error) {
+		ctx, cancel := cacheLoadContext(c)
+		defer cancel()
+
+		var total int64
+		errCount := u.filterUsers(c).
+			WithContext(ctx).
+			Model(&models.User{}).
+			Count(&total).Error
+		if errCount != nil {
+			return nil, errCount
+		}
+
+		users := []models.User{}
+		errFind := u.filterUsers(c).
+			WithContext(ctx).
+			Select(userResponseColumns).
+			Order(sortColumn + " " + direction).
+			Order("id " + direction).
+			Offset((page - 1) * limit).
+			Limit(limit).
+			Find(&users).Error
+		if errFind != nil {
+			return nil, errFind
+		}
+
+		// Convert ke response format
+		userResponses := []UserResponse{}
+		for _, user := range users {
+			userResponses = append(userResponses, newUserResponse(user))
+		}
+
+		return gin.H{
+			"message":    "Employees retrieved successfully",
+			"count":      len(userResponses),
+			"total":      total,
+			"page":       page,
+			"limit":      limit,
+			"totalPages": (total + int64(limit) - 1) / int64(limit),
+			"employees":  userResponses,
+		}, nil
+	})
+	if errDB != nil {
+		c.Error(apierror.Internal(errDB))
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (u *UserController) Export(c *gin.Context) {
+	if c.DefaultQuery("format", "csv") != "csv" {
+		c.Error(apierror.BadRequest(apierror.CodeBadRequest, "format must be csv"))
+		return
+	}
+
+	location := requestLocation(c, u.DB)
+	rows, errDB := u.filterUsers(c).
+		WithContext(c.Request.Context()).
+		Model(&models.User{}).
+		Select("id, name, email, role, created_at").
+		Order("id ASC").
+		Rows()
+	if errDB != nil {
+		c.Error(apierror.Internal(errDB))
+		return
+	}
+	defer rows.Close()
+
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", `attachment; filename="employees-`+time.Now().UTC().Format("2006-01-02")+`.csv"`)
+	c.Status(http.StatusOK)
+
+	// Tulis per baris langsung ke response, tanpa menampung seluruh tabel
+	writer := csv.NewWriter(c.Writer)
+	writer.Write([]string{"id", "name", "email", "role", "createdAt"})
+	for rows.Next() {
+		var user models.User
+		if err := u.DB.ScanRows(rows, &user); err != nil {
+			if !dropStream(c, err) {
+				c.Error(apierror.Internal(err))
+			}
+			return
+		}
+
+		writer.Write([]string{
+			strconv.Itoa(user.Id),
+			user.Name,
+			user.Email,
+			user.Role,
+			user.CreatedAt.In(location).Format(time.RFC3339),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		if !dropStream(c, err) {
+			c.Error(apierror.Internal(err))
+		}
+		return
+	}
+	writer.Flush()
+}
+
+// filterUsers menerapkan filter role, departmentId, q, createdAfter,
+// createdBefore dan includeInactive yang sama untuk list dan export.
+// Manager selalu dibatasi ke department-nya sendiri.
+func (u *UserController) filterUsers(c *gin.Context) *gorm.DB {
+	query := u.DB
+
+	if departmentId, scoped := departmentScope(c); scoped {
+		query = query.Where("department_id = ?", departmentId)
+	}
+
+	role := c.DefaultQuery("role", models.RoleEmployee)
+	if role != "all" {
+		query = query.Where("role = ?", role)
+	}
+
+	// User nonaktif tidak muncul di pilihan assignee kecuali diminta
+	if c.Query("includeInactive") != "true" {
+		query = query.Where("is_active = ?", true)
+	}
+
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		like := "%" + escapeLike(strings.ToLower(q)) + "%"
+		query = query.Where("LOWER(name) LIKE ? ESCAPE '!' OR LOWER(email) LIKE ? ESCAPE '!'", like, like)
+	}
+
+	location := time.UTC
+	if c.Query("createdAfter") != "" || c.Query("createdBefore") != "" {
+		location = requestLocation(c, u.DB)
+	}
+	if createdAfter, ok := parseDateFilter(c.Query("createdAfter"), location); ok {
+		query = query.Where("created_at >= ?", createdAfter)
+	}
+	if createdBefore, ok := parseDateFilter(c.Query("createdBefore"), location); ok {
+		// Tanggal tanpa jam mencakup seluruh hari tersebut
+		if len(c.Query("createdBefore")) == len("2006-01-02") {
+			createdBefore = createdBefore.AddDate(0, 0, 1)
+		}
+		query = query.Where("created_at < ?", createdBefore)
+	}
+
+	return query
+}
+
+// escapeLike meng-escape wildcard LIKE supaya % dan _ dicari apa adanya
+func escapeLike(value string) string {
+	return strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(value)
+}
+
+// parseDateFilter menerima YYYY-MM-DD, dibaca di zona waktu location, atau
+// RFC3339. Hasilnya dalam UTC seperti yang tersimpan di database.
+func parseDateFilter(value string, location *time.Location) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	if date, err := time.ParseInLocation("2006-01-02", value, location); err == nil {
+		return date.UTC(), true
+	}
+	if date, err := time.Parse(time.RFC3339, value); err == nil {
+		return date.UTC(), true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,615 @@
+package controllers_test
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"tusk/config"
+	"tusk/controllers"
+	"tusk/models"
+	"tusk/ratelimit"
+	"tusk/routes"
+	"tusk/testutil"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestUserResponsesCarryEveryField(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t))
+	department := models.Department{Name: "Sales"}
+	s.DB.Create(&department)
+	admin := testutil.User(t, s.DB, models.RoleAdmin)
+	employee := testutil.User(t, s.DB, models.RoleEmployee, func(u *models.User) {
+		u.Timezone = "Asia/Jakarta"
+		u.DepartmentId = &department.Id
+		u.AvatarPath = "andi.png"
+	})
+	token := testutil.Token(t, admin)
+
+	check := func(t *testing.T, got controllers.UserResponse) {
+		t.Helper()
+
+		if !got.IsActive || !got.EmailVerified || got.Timezone != "Asia/Jakarta" ||
+			got.DepartmentId == nil || *got.DepartmentId != department.Id || got.AvatarUrl == nil {
+			t.Errorf("user = %+v, want every column of %d", got, employee.Id)
+		}
+	}
+
+	t.Run("by id", func(t *testing.T) {
+		res := s.Do(t, http.MethodGet, routes.Prefix+"/users/"+itoa(employee.Id), token, nil)
+		testutil.Expect(t, res, http.StatusOK)
+		got := controllers.UserDetailResponse{}
+		testutil.Decode(t, res, &got)
+		check(t, got.UserResponse)
+	})
+
+	t.Run("deleted", func(t *testing.T) {
+		testutil.Expect(t, s.Do(t, http.MethodDelete, routes.Prefix+"/users/"+itoa(employee.Id), token, nil), http.StatusOK)
+
+		res := s.Do(t, http.MethodGet, routes.Prefix+"/users/deleted", token, nil)
+		testutil.Expect(t, res, http.StatusOK)
+		got := struct {
+			Users []struct {
+				User controllers.UserResponse `json:"user"`
+			} `json:"users"`
+		}{}
+		testutil.Decode(t, res, &got)
+		if len(got.Users) != 1 {
+			t.Fatalf("deleted users = %+v, want one", got.Users)
+		}
+		check(t, got.Users[0].User)
+	})
+}
+
+func TestChangeRoleRevokesTokens(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t))
+	admin := testutil.User(t, s.DB, models.RoleAdmin)
+	employee := testutil.User(t, s.DB, models.RoleEmployee)
+	oldToken := testutil.Token(t, employee)
+
+	res := s.Do(t, http.MethodPatch, routes.Prefix+"/users/"+itoa(employee.Id)+"/role", testutil.Token(t, admin), map[string]string{"role": models.RoleAdmin})
+	testutil.Expect(t, res, http.StatusOK)
+
+	testutil.Expect(t, s.Do(t, http.MethodGet, routes.Prefix+"/tasks", oldToken, nil), http.StatusUnauthorized)
+	promoted := models.User{}
+	s.DB.First(&promoted, employee.Id)
+	if promoted.TokenVersion != employee.TokenVersion+1 {
+		t.Errorf("token version = %d, want %d", promoted.TokenVersion, employee.TokenVersion+1)
+	}
+}
+
+func TestDemoteAdmin(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t))
+	admin := testutil.User(t, s.DB, models.RoleAdmin)
+	other := testutil.User(t, s.DB, models.RoleAdmin)
+
+	res := s.Do(t, http.MethodPatch, routes.Prefix+"/users/"+itoa(other.Id)+"/role", testutil.Token(t, admin), map[string]string{"role": models.RoleEmployee})
+	testutil.Expect(t, res, http.StatusOK)
+
+	demoted := models.User{}
+	s.DB.First(&demoted, other.Id)
+	if demoted.Role != models.RoleEmployee {
+		t.Errorf("role = %s, want Employee", demoted.Role)
+	}
+}
+
+func TestImportNearTheRowLimit(t *testing.T) {
+	// a password is hashed for every row, which takes longer than this
+	s := testutil.NewServer(t, testutil.DB(t), func(cfg *config.Config) { cfg.QueryTimeout = 100 * time.Millisecond })
+	token := testutil.Token(t, testutil.User(t, s.DB, models.RoleAdmin))
+
+	csv := strings.Builder{}
+	csv.WriteString("name,email\n")
+	for i := 0; i < 1000; i++ {
+		fmt.Fprintf(&csv, "Employee %d,import%d@go.id\n", i, i)
+	}
+	body, contentType := multipartBody(t, "file", "employees.csv", []byte(csv.String()))
+	req := httptest.NewRequest(http.MethodPost, routes.Prefix+"/users/import", body)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Authorization", "Bearer "+token)
+	res := httptest.NewRecorder()
+	s.Router.ServeHTTP(res, req)
+	testutil.Expect(t, res, http.StatusOK)
+
+	got := struct {
+		Created int `json:"created"`
+	}{}
+	testutil.Decode(t, res, &got)
+	var users int64
+	s.DB.Model(&models.User{}).Where("email LIKE ?", "import%").Count(&users)
+	if got.Created != 1000 || users != 1000 {
+		t.Errorf("created %d, %d users stored; want 1000", got.Created, users)
+	}
+}
+
+func TestExportFailingPartWay(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t))
+	token := testutil.Token(t, testutil.User(t, s.DB, models.RoleAdmin))
+	server := httptest.NewServer(s.Router)
+	defer server.Close()
+
+	// a number in created_at doesn't scan into a time, failing the row
+	broken := testutil.User(t, s.DB, models.RoleEmployee)
+	breakRow := func(t *testing.T) {
+		t.Helper()
+
+		if err := s.DB.Exec("UPDATE users SET created_at = 1.5 WHERE id = ?", broken.Id).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	export := func(t *testing.T) (*http.Response, error) {
+		t.Helper()
+
+		req, _ := http.NewRequest(http.MethodGet, server.URL+routes.Prefix+"/users/export", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		res, err := server.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		_, err = io.ReadAll(res.Body)
+		return res, err
+	}
+
+	t.Run("before anything was sent", func(t *testing.T) {
+		breakRow(t)
+		res, err := export(t)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.StatusCode != http.StatusInternalServerError || res.Header.Get("Content-Disposition") != "" {
+			t.Errorf("status = %d with %q, want 500 without a download", res.StatusCode, res.Header.Get("Content-Disposition"))
+		}
+	})
+
+	t.Run("after the first rows were sent", func(t *testing.T) {
+		// enough rows before the broken one to fill the csv writer's buffer
+		for i := 0; i < 100; i++ {
+			testutil.User(t, s.DB, models.RoleEmployee)
+		}
+		s.DB.Exec("UPDATE users SET id = id + 1000 WHERE id = ?", broken.Id)
+		broken.Id += 1000
+		breakRow(t)
+
+		res, err := export(t)
+		if res.StatusCode != http.StatusOK || err == nil {
+			t.Errorf("status = %d, read error %v, want a broken transfer", res.StatusCode, err)
+		}
+	})
+}
+
+func TestAdminRoutesNeedAnAdmin(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t))
+	admin := testutil.User(t, s.DB, models.RoleAdmin)
+	employee := testutil.User(t, s.DB, models.RoleEmployee)
+	target := testutil.User(t, s.DB, models.RoleEmployee)
+	path := routes.Prefix + "/users/" + itoa(target.Id)
+
+	testutil.Expect(t, s.Do(t, http.MethodDelete, path, "", nil), http.StatusUnauthorized)
+	testutil.Expect(t, s.Do(t, http.MethodDelete, path, testutil.Token(t, employee), nil), http.StatusForbidden)
+	if err := s.DB.First(&models.User{}, target.Id).Error; err != nil {
+		t.Fatalf("user deleted by an Employee: %v", err)
+	}
+
+	newUser := map[string]string{"name": "Citra", "email": "citra@go.id", "password": "kopi-susu-9", "role": models.RoleEmployee}
+	testutil.Expect(t, s.Do(t, http.MethodPost, routes.Prefix+"/users", testutil.Token(t, employee), newUser), http.StatusForbidden)
+	testutil.Expect(t, s.Do(t, http.MethodGet, routes.Prefix+"/users/Employee", testutil.Token(t, employee), nil), http.StatusForbidden)
+
+	adminToken := testutil.Token(t, admin)
+	testutil.Expect(t, s.Do(t, http.MethodGet, routes.Prefix+"/users/Employee", adminToken, nil), http.StatusOK)
+	testutil.Expect(t, s.Do(t, http.MethodPost, routes.Prefix+"/users", adminToken, newUser), http.StatusCreated)
+	testutil.Expect(t, s.Do(t, http.MethodDelete, path, adminToken, nil), http.StatusOK)
+}
+
+func TestEmployeeSearch(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t))
+	token := testutil.Token(t, testutil.User(t, s.DB, models.RoleAdmin, func(u *models.User) { u.Name = "Hana" }))
+	named := func(name, email string) func(*models.User) {
+		return func(u *models.User) { u.Name, u.Email = name, email }
+	}
+	testutil.User(t, s.DB, models.RoleEmployee, named("Andi", "andi@go.id"))
+	testutil.User(t, s.DB, models.RoleEmployee, named("Hasan", "hasan@go.id"))
+	testutil.User(t, s.DB, models.RoleEmployee, named("Budi", "budi@go.id"))
+	testutil.User(t, s.DB, models.RoleEmployee, named("Sari", "sari_50%@go.id"))
+
+	search := func(t *testing.T, query string) ([]string, int) {
+		t.Helper()
+
+		res := s.Do(t, http.MethodGet, routes.Prefix+"/users/Employee?sort=name&"+query, token, nil)
+		testutil.Expect(t, res, http.StatusOK)
+		body := struct {
+			Total     int                        `json:"total"`
+			Employees []controllers.UserResponse `json:"employees"`
+		}{}
+		testutil.Decode(t, res, &body)
+		names := []string{}
+		for _, employee := range body.Employees {
+			names = append(names, employee.Name)
+		}
+		return names, body.Total
+	}
+
+	cases := []struct {
+		query string
+		want  []string
+	}{
+		{"q=an", []string{"Andi", "Hasan"}},
+		{"q=AN", []string{"Andi", "Hasan"}},
+		{"q=budi%40go", []string{"Budi"}},
+		{"q=%25", []string{"Sari"}},
+		{"q=_", []string{"Sari"}},
+		{"q=an&role=all", []string{"Andi", "Hana", "Hasan"}},
+		{"q=an&limit=1&page=2", []string{"Hasan"}},
+		{"q=an&createdAfter=2999-01-01", []string{}},
+		{"q=an&createdBefore=2999-01-01", []string{"Andi", "Hasan"}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.query, func(t *testing.T) {
+			if got, _ := search(t, tc.query); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	t.Run("total counts every page", func(t *testing.T) {
+		if _, total := search(t, "q=an&limit=1"); total != 2 {
+			t.Errorf("total = %d, want 2", total)
+		}
+	})
+}
+
+func TestLoginLockout(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t), func(cfg *config.Config) {
+		cfg.Lockout = config.LockoutConfig{MaxAttempts: 5, Window: 15 * time.Minute, Duration: 15 * time.Minute}
+		cfg.RateLimit.Login = ratelimit.Rate{Limit: 100, Period: time.Minute}
+	})
+	admin := testutil.User(t, s.DB, models.RoleAdmin)
+	employee := testutil.User(t, s.DB, models.RoleEmployee)
+	login := func(t *testing.T, password string) *httptest.ResponseRecorder {
+		t.Helper()
+		return s.Do(t, http.MethodPost, routes.Prefix+"/users/login", "", map[string]string{"email": employee.Email, "password": password})
+	}
+
+	t.Run("success resets the count", func(t *testing.T) {
+		for i := 0; i < 4; i++ {
+			testutil.Expect(t, login(t, "wrong-password"), http.StatusUnauthorized)
+		}
+		testutil.Expect(t, login(t, testutil.Password), http.StatusOK)
+		for i := 0; i < 4; i++ {
+			testutil.Expect(t, login(t, "wrong-password"), http.StatusUnauthorized)
+		}
+		testutil.Expect(t, login(t, testutil.Password), http.StatusOK)
+	})
+
+	t.Run("sixth attempt is locked out", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			testutil.Expect(t, login(t, "wrong-password"), http.StatusUnauthorized)
+		}
+		res := login(t, testutil.Password)
+		testutil.Expect(t, res, http.StatusLocked)
+		if retryAfter, _ := strconv.Atoi(res.Header().Get("Retry-After")); retryAfter < 1 || retryAfter > 15*60+1 {
+			t.Errorf("Retry-After = %q, want the remaining lockout", res.Header().Get("Retry-After"))
+		}
+	})
+
+	t.Run("expires", func(t *testing.T) {
+		s.DB.Model(&employee).Update("locked_until", time.Now().UTC().Add(-time.Second))
+		testutil.Expect(t, login(t, testutil.Password), http.StatusOK)
+	})
+
+	t.Run("admin unlocks early", func(t *testing.T) {
+		for i := 0; i < 5; i++ {
+			login(t, "wrong-password")
+		}
+		testutil.Expect(t, login(t, testutil.Password), http.StatusLocked)
+
+		path := routes.Prefix + "/users/" + itoa(employee.Id) + "/unlock"
+		testutil.Expect(t, s.Do(t, http.MethodPost, path, testutil.Token(t, employee), nil), http.StatusForbidden)
+		testutil.Expect(t, s.Do(t, http.MethodPost, path, testutil.Token(t, admin), nil), http.StatusOK)
+		testutil.Expect(t, login(t, testutil.Password), http.StatusOK)
+	})
+}
+
+func TestDeleteUser(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t))
+	token := testutil.Token(t, testutil.User(t, s.DB, models.RoleAdmin))
+	employee := testutil.User(t, s.DB, models.RoleEmployee)
+	colleague := testutil.User(t, s.DB, models.RoleEmployee)
+	manager := testutil.User(t, s.DB, models.RoleManager)
+	queued := createTask(t, s, employee.Id, models.StatusQueue)
+	inProgress := createTask(t, s, employee.Id, models.StatusInProgress)
+	approved := createTask(t, s, employee.Id, models.StatusApproved)
+	path := routes.Prefix + "/users/" + itoa(employee.Id)
+	login := func(t *testing.T) *httptest.ResponseRecorder {
+		t.Helper()
+		return s.Do(t, http.MethodPost, routes.Prefix+"/users/login", "", map[string]string{"email": employee.Email, "password": testutil.Password})
+	}
+
+	t.Run("open tasks block it", func(t *testing.T) {
+		res := s.Do(t, http.MethodDelete, path, token, nil)
+		testutil.Expect(t, res, http.StatusConflict)
+		body := struct {
+			Error struct {
+				Code    string `json:"code"`
+				Details []struct {
+					BlockingTasks int `json:"blockingTasks"`
+				} `json:"details"`
+			} `json:"error"`
+		}{}
+		testutil.Decode(t, res, &body)
+		if body.Error.Code != "USER_HAS_OPEN_TASKS" || len(body.Error.Details) != 1 || body.Error.Details[0].BlockingTasks != 2 {
+			t.Errorf("error = %+v, want 2 blocking tasks", body.Error)
+		}
+		testutil.Expect(t, login(t), http.StatusOK)
+	})
+
+	t.Run("only to an active Employee", func(t *testing.T) {
+		testutil.Expect(t, s.Do(t, http.MethodDelete, path+"?reassignTo="+itoa(manager.Id), token, nil), http.StatusUnprocessableEntity)
+		testutil.Expect(t, s.Do(t, http.MethodDelete, path+"?reassignTo="+itoa(employee.Id), token, nil), http.StatusBadRequest)
+		if got := reloadTask(t, s, queued.Id).UserId; got != employee.Id {
+			t.Errorf("task moved to %d by a refused delete", got)
+		}
+	})
+
+	t.Run("reassigns the open tasks", func(t *testing.T) {
+		res := s.Do(t, http.MethodDelete, path+"?reassignTo="+itoa(colleague.Id), token, nil)
+		testutil.Expect(t, res, http.StatusOK)
+		body := struct {
+			ReassignedTasks int `json:"reassignedTasks"`
+		}{}
+		testutil.Decode(t, res, &body)
+		if body.ReassignedTasks != 2 {
+			t.Errorf("reassigned = %d, want 2", body.ReassignedTasks)
+		}
+
+		for _, id := range []int{queued.Id, inProgress.Id} {
+			got := reloadTask(t, s, id)
+			if got.UserId != colleague.Id || got.PreviousUserId == nil || *got.PreviousUserId != employee.Id {
+				t.Errorf("task %d = user %d, previous %v; want %d from %d", id, got.UserId, got.PreviousUserId, colleague.Id, employee.Id)
+			}
+		}
+		if got := reloadTask(t, s, approved.Id).UserId; got != employee.Id {
+			t.Errorf("approved task moved to %d, want it kept in the history of %d", got, employee.Id)
+		}
+	})
+
+	t.Run("is soft", func(t *testing.T) {
+		deleted := models.User{}
+		if err := s.DB.Unscoped().First(&deleted, employee.Id).Error; err != nil || !deleted.DeletedAt.Valid {
+			t.Fatalf("user = %+v, %v; want the row kept with deleted_at", deleted, err)
+		}
+		testutil.Expect(t, login(t), http.StatusUnauthorized)
+
+		res := s.Do(t, http.MethodGet, routes.Prefix+"/users/Employee", token, nil)
+		testutil.Expect(t, res, http.StatusOK)
+		employees := struct {
+			Employees []controllers.UserResponse `json:"employees"`
+		}{}
+		testutil.Decode(t, res, &employees)
+		for _, got := range employees.Employees {
+			if got.Id == employee.Id {
+				t.Errorf("deleted user %d listed as an employee", employee.Id)
+			}
+		}
+	})
+
+	t.Run("keeps the email taken", func(t *testing.T) {
+		res := s.Do(t, http.MethodPost, routes.Prefix+"/users", token, map[string]string{
+			"name": "Andi", "email": employee.Email, "password": "teh-manis-7",
+		})
+		testutil.Expect(t, res, http.StatusConflict)
+	})
+
+	t.Run("restore", func(t *testing.T) {
+		testutil.Expect(t, s.Do(t, http.MethodPost, path+"/restore", token, nil), http.StatusOK)
+		testutil.Expect(t, login(t), http.StatusOK)
+		testutil.Expect(t, s.Do(t, http.MethodPost, path+"/restore", token, nil), http.StatusNotFound)
+	})
+}
+
+func TestCreateAccountEmailIsUnique(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t), func(cfg *config.Config) {
+		cfg.RateLimit.CreateAccount = ratelimit.Rate{Limit: 100, Period: time.Minute}
+	})
+	token := testutil.Token(t, testutil.User(t, s.DB, models.RoleAdmin))
+	create := func(t *testing.T, email string) *httptest.ResponseRecorder {
+		return s.Do(t, http.MethodPost, routes.Prefix+"/users", token, map[string]interface{}{
+			"name": "Foo", "email": email, "password": "teh-manis-7", "skipVerification": true,
+		})
+	}
+
+	t.Run("concurrent creates", func(t *testing.T) {
+		emails := []string{"foo@go.id", "Foo@go.id", "FOO@GO.ID", "foo@Go.Id", "fOo@go.id", "foo@go.id"}
+		codes := make([]int, len(emails))
+		var wg sync.WaitGroup
+		for i, email := range emails {
+			wg.Add(1)
+			go func(i int, email string) {
+				defer wg.Done()
+				res := create(t, email)
+				codes[i] = res.Code
+				if res.Code != http.StatusCreated && !strings.Contains(res.Body.String(), "Email already exists") {
+					t.Errorf("status = %d, want 201 or the friendly 409\n%s", res.Code, res.Body.String())
+				}
+			}(i, email)
+		}
+		wg.Wait()
+
+		created := 0
+		for _, code := range codes {
+			if code == http.StatusCreated {
+				created++
+			}
+		}
+		var rows int64
+		s.DB.Unscoped().Model(&models.User{}).Where("LOWER(email) = ?", "foo@go.id").Count(&rows)
+		if created != 1 || rows != 1 {
+			t.Errorf("statuses = %v, %d rows; want exactly one created", codes, rows)
+		}
+	})
+
+	t.Run("the index holds without the check", func(t *testing.T) {
+		err := s.DB.Create(&models.User{Name: "Foo", Email: "foo@go.id", Password: "x", Role: models.RoleEmployee}).Error
+		if err == nil {
+			t.Error("inserted a second foo@go.id")
+		}
+	})
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t))
+	admin := testutil.User(t, s.DB, models.RoleAdmin)
+	employee := testutil.User(t, s.DB, models.RoleEmployee, func(u *models.User) { u.Email = "andi.pratama@go.id" })
+	violation := func(t *testing.T, res *httptest.ResponseRecorder, field, rule string) {
+		t.Helper()
+
+		testutil.Expect(t, res, http.StatusBadRequest)
+		body := struct {
+			Error struct {
+				Details []struct {
+					Field string `json:"field"`
+					Rule  string `json:"rule"`
+				} `json:"details"`
+			} `json:"error"`
+		}{}
+		testutil.Decode(t, res, &body)
+		if details := body.Error.Details; len(details) != 1 || details[0].Field != field || details[0].Rule != rule {
+			t.Errorf("details = %+v, want %s on %s", details, rule, field)
+		}
+	}
+
+	t.Run("create account", func(t *testing.T) {
+		res := s.Do(t, http.MethodPost, routes.Prefix+"/users", testutil.Token(t, admin), map[string]string{
+			"name": "Budi", "email": "budi@go.id", "password": "password123",
+		})
+		violation(t, res, "password", "not_common")
+	})
+
+	t.Run("change password", func(t *testing.T) {
+		res := s.Do(t, http.MethodPut, routes.Prefix+"/users/password", testutil.Token(t, employee), map[string]string{
+			"oldPassword": testutil.Password, "newPassword": "Andi.Pratama",
+		})
+		violation(t, res, "newPassword", "not_email")
+	})
+
+	t.Run("login rehashes a cheaper hash", func(t *testing.T) {
+		s.Users.BcryptCost = bcrypt.MinCost + 1
+		res := s.Do(t, http.MethodPost, routes.Prefix+"/users/login", "", map[string]string{"email": employee.Email, "password": testutil.Password})
+		testutil.Expect(t, res, http.StatusOK)
+
+		user := models.User{}
+		s.DB.First(&user, employee.Id)
+		if cost, _ := bcrypt.Cost([]byte(user.Password)); cost != bcrypt.MinCost+1 {
+			t.Errorf("cost = %d, want %d", cost, bcrypt.MinCost+1)
+		}
+		if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(testutil.Password)) != nil {
+			t.Error("the new hash doesn't match the password")
+		}
+	})
+}
+
+func TestLogout(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t), func(cfg *config.Config) {
+		cfg.RateLimit.Login = ratelimit.Rate{Limit: 100, Period: time.Minute}
+	})
+	employee := testutil.User(t, s.DB, models.RoleEmployee)
+	type session struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refreshToken"`
+	}
+	login := func(t *testing.T) session {
+		t.Helper()
+
+		res := s.Do(t, http.MethodPost, routes.Prefix+"/users/login", "", map[string]string{"email": employee.Email, "password": testutil.Password})
+		testutil.Expect(t, res, http.StatusOK)
+		got := session{}
+		testutil.Decode(t, res, &got)
+		return got
+	}
+	tasks := func(t *testing.T, token string) int {
+		t.Helper()
+		return s.Do(t, http.MethodGet, routes.Prefix+"/tasks", token, nil).Code
+	}
+	refresh := func(t *testing.T, refreshToken string) int {
+		t.Helper()
+		return s.Do(t, http.MethodPost, routes.Prefix+"/auth/refresh", "", map[string]string{"refreshToken": refreshToken}).Code
+	}
+
+	t.Run("revokes the token and its refresh token", func(t *testing.T) {
+		current, other := login(t), login(t)
+		testutil.Expect(t, s.Do(t, http.MethodPost, routes.Prefix+"/auth/logout", current.Token, map[string]string{"refreshToken": current.RefreshToken}), http.StatusOK)
+
+		if got := tasks(t, current.Token); got != http.StatusUnauthorized {
+			t.Errorf("logged out token: status = %d, want 401", got)
+		}
+		if got := refresh(t, current.RefreshToken); got != http.StatusUnauthorized {
+			t.Errorf("logged out refresh token: status = %d, want 401", got)
+		}
+		if got := tasks(t, other.Token); got != http.StatusOK {
+			t.Errorf("other session: status = %d, want 200", got)
+		}
+	})
+
+	t.Run("everywhere", func(t *testing.T) {
+		current, other := login(t), login(t)
+		testutil.Expect(t, s.Do(t, http.MethodPost, routes.Prefix+"/auth/logout-all", current.Token, nil), http.StatusOK)
+
+		for _, session := range []session{current, other} {
+			if got := tasks(t, session.Token); got != http.StatusUnauthorized {
+				t.Errorf("token after logout-all: status = %d, want 401", got)
+			}
+			if got := refresh(t, session.RefreshToken); got != http.StatusUnauthorized {
+				t.Errorf("refresh token after logout-all: status = %d, want 401", got)
+			}
+		}
+		if got := tasks(t, login(t).Token); got != http.StatusOK {
+			t.Errorf("new login: status = %d, want 200", got)
+		}
+	})
+}
+
+func TestOwnerMustChangePassword(t *testing.T) {
+	s := testutil.NewServer(t, testutil.DB(t))
+	cfg := testutil.Config(t)
+	cfg.BcryptCost = bcrypt.MinCost
+	cfg.Owner = config.OwnerConfig{Email: "owner@tusk.id", Password: "kopi-susu-9"}
+	if err := config.CreateOwnerAccount(s.DB, cfg); err != nil {
+		t.Fatal(err)
+	}
+	login := func(t *testing.T, password string) (string, bool) {
+		t.Helper()
+
+		res := s.Do(t, http.MethodPost, routes.Prefix+"/users/login", "", map[string]string{"email": "owner@tusk.id", "password": password})
+		testutil.Expect(t, res, http.StatusOK)
+		body := struct {
+			Token              string `json:"token"`
+			MustChangePassword bool   `json:"mustChangePassword"`
+		}{}
+		testutil.Decode(t, res, &body)
+		return body.Token, body.MustChangePassword
+	}
+
+	token, mustChange := login(t, "kopi-susu-9")
+	if !mustChange {
+		t.Fatal("first login doesn't ask for a password change")
+	}
+	if code := s.Do(t, http.MethodGet, routes.Prefix+"/tasks", token, nil).Code; code != http.StatusForbidden {
+		t.Errorf("status = %d before changing the password, want 403", code)
+	}
+	res := s.Do(t, http.MethodPut, routes.Prefix+"/users/password", token, map[string]string{"oldPassword": "kopi-susu-9", "newPassword": "teh-manis-7"})
+	testutil.Expect(t, res, http.StatusOK)
+
+	token, mustChange = login(t, "teh-manis-7")
+	if mustChange {
+		t.Error("still asked for a password change after changing it")
+	}
+	testutil.Expect(t, s.Do(t, http.MethodGet, routes.Prefix+"/tasks", token, nil), http.StatusOK)
+}
@@ -0,0 +1,258 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"tusk/apierror"
+	"tusk/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+const (
+	maxImportSize = 5 << 20
+	maxImportRows = 1000
+	importBatch   = 100
+)
+
+type importRow struct {
+	Name  string `json:"name" binding:"required,max=50"`
+	Email string `json:"email" binding:"required,email,max=50"`
+}
+
+type ImportResult struct {
+	Line     int    `json:"line"`
+	Email    string `json:"email"`
+	Status   string `json:"status"` // created, valid (dry run) or skipped
+	Id       int    `json:"id,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+	Password string `json:"password,omitempty"`
+}
+
+// Import membuat banyak Employee sekaligus dari CSV dengan kolom name dan
+// email. Baris yang tidak valid dilewati dan dilaporkan per baris;
+// ?dryRun=true hanya memvalidasi, ?skipVerification=true menandai semua
+// email langsung terverifikasi.
+func (u *UserController) Import(c *gin.Context) {
+	dryRun := c.Query("dryRun") == "true"
+	skipVerification := c.Query("skipVerification") == "true"
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	file, errFile := c.FormFile("file")
+	if errFile != nil {
+		c.Error(apierror.BadRequest(apierror.CodeBadRequest, "file is required"))
+		return
+	}
+	opened, errOpen := file.Open()
+	if errOpen != nil {
+		c.Error(apierror.Internal(errOpen))
+		return
+	}
+	defer opened.Close()
+
+	// Seluruh file dibaca dan dicek dulu sebelum menyentuh database
+	rows, errParse := parseImportCSV(opened)
+	if errParse != nil {
+		c.Error(apierror.BadRequest(apierror.CodeBadRequest, errParse.Error()))
+		return
+	}
+
+	results := make([]ImportResult, len(rows))
+	seen := map[string]bool{}
+	emails := []string{}
+	for i, row := range rows {
+		row.Email = normalizeEmail(row.Email)
+		row.Name = strings.TrimSpace(row.Name)
+		rows[i] = row
+		results[i] = ImportResult{Line: i + 2, Email: row.Email, Status: "valid"}
+
+		if err := binding.Validator.ValidateStruct(row); err != nil {
+			results[i].Status = "skipped"
+			results[i].Reason = joinFieldMessages(apierror.FieldMessages(err))
+			continue
+		}
+		if seen[row.Email] {
+			results[i].Status = "skipped"
+			results[i].Reason = "email appears more than once in the file"
+			continue
+		}
+		seen[row.Email] = true
+		emails = append(emails, row.Email)
+	}
+
+	// Email user yang sudah dihapus juga tidak bisa dipakai ulang
+	existing := []string{}
+	if len(emails) > 0 {
+		errDB := u.DB.WithContext(c.Request.Context()).Unscoped().Model(&models.User{}).
+			Where("LOWER(email) IN ?", emails).
+			Pluck("LOWER(email)", &existing).Error
+		if errDB != nil {
+			c.Error(apierror.Internal(errDB))
+			return
+		}
+	}
+	taken := map[string]bool{}
+	for _, email := range existing {
+		taken[email] = true
+	}
+
+	newUsers := []models.User{}
+	passwords := []string{}
+	indexes := []int{}
+	for i, row := range rows {
+		if results[i].Status != "valid" {
+			continue
+		}
+		if taken[row.Email] {
+			results[i].Status = "skipped"
+			results[i].Reason = "email already exists"
+			continue
+		}
+		if dryRun {
+			continue
+		}
+
+		password, errPassword := randomToken()
+		if errPassword != nil {
+			c.Error(apierror.Internal(errPassword))
+			return
+		}
+		password = password[:12]
+		hashed, errHash := bcrypt.GenerateFromPassword([]byte(password), u.BcryptCost)
+		if errHash != nil {
+			c.Error(apierror.Internal(errHash))
+			return
+		}
+
+		newUsers = append(newUsers, models.User{
+			Name:          row.Name,
+			Email:         row.Email,
+			Password:      string(hashed),
+			Role:          models.RoleEmployee,
+			IsActive:      true,
+			EmailVerified: skipVerification,
+		})
+		passwords = append(passwords, password)
+		indexes = append(indexes, i)
+	}
+
+	links := make([]string, len(newUsers))
+	if len(newUsers) > 0 {
+		errDB := withTx(c.Request.Context(), u.DB, func(tx *gorm.DB) error {
+			if err := tx.CreateInBatches(&newUsers, importBatch).Error; err != nil {
+				return err
+			}
+			if skipVerification {
+				return nil
+			}
+			for j, user := range newUsers {
+				var err error
+				if links[j], err = u.newEmailVerification(tx, user); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if isDuplicateKey(errDB) {
+			c.Error(apierror.Conflict(apierror.CodeEmailTaken, "An email in the file was registered while importing, please retry"))
+			return
+		}
+		if errDB != nil {
+			c.Error(apierror.Internal(errDB))
+			return
+		}
+	}
+
+	created := 0
+	for j, user := range newUsers {
+		result := &results[indexes[j]]
+		result.Status = "created"
+		result.Id = user.Id
+		created++
+
+		// Password awal dikirim lewat email kalau SMTP aktif; kalau tidak,
+		// dikembalikan ke Admin supaya bisa dibagikan manual
+		if u.MailConfigured {
+			sendTemplate(u.Mailer, user.Email, "account_created", mailData{Name: user.Name, Password: passwords[j], Link: links[j]})
+		} else {
+			result.Password = passwords[j]
+		}
+	}
+
+	skipped := 0
+	for _, result := range results {
+		if result.Status == "skipped" {
+			skipped++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"dryRun":  dryRun,
+		"created": created,
+		"skipped": skipped,
+		"rows":    results,
+	})
+}
+
+// parseImportCSV reads every row up front so a broken file is rejected as a
+// whole. The header must contain name and email, in any order.
+func parseImportCSV(reader io.Reader) ([]importRow, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("file is empty")
+	}
+	if err != nil {
+		return nil, errors.New("file is not valid CSV: " + err.Error())
+	}
+
+	nameCol, emailCol := -1, -1
+	for i, column := range header {
+		switch strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))) {
+		case "name":
+			nameCol = i
+		case "email":
+			emailCol = i
+		}
+	}
+	if nameCol < 0 || emailCol < 0 {
+		return nil, errors.New("header must contain name and email columns")
+	}
+
+	rows := []importRow{}
+	for {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, errors.New("file is not valid CSV: " + err.Error())
+		}
+		if len(rows) == maxImportRows {
+			return nil, errors.New("file has more than 1000 rows")
+		}
+		rows = append(rows, importRow{Name: record[nameCol], Email: record[emailCol]})
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("file has no rows")
+	}
+	return rows, nil
+}
+
+func joinFieldMessages(messages map[string]string) string {
+	list := make([]string, 0, len(messages))
+	for _, message := range messages {
+		list = append(list, message)
+	}
+	sort.Strings(list)
+	return strings.Join(list, "; ")
+}
@@ -0,0 +1,111 @@
+package events
+
+import "sync"
+
+// Event types published on the hub.
+const (
+	TaskCreated       = "task.created"
+	TaskUpdated       = "task.updated"
+	TaskStatusChanged = "task.status_changed"
+	CommentCreated    = "comment.created"
+)
+
+// Event is a change pushed to connected clients. UserIds are the assignees
+// allowed to see it; Admins see everything.
+type Event struct {
+	Type    string      `json:"type"`
+	TaskId  int         `json:"taskId"`
+	Data    interface{} `json:"data"`
+	UserIds []int       `json:"-"`
+}
+
+// Subscriber receives events on C until it is closed, either by
+// Unsubscribe or because it fell too far behind.
+type Subscriber struct {
+	C      chan Event
+	userId int
+	admin  bool
+}
+
+func (s *Subscriber) wants(event Event) bool {
+	if s.admin {
+		return true
+	}
+	for _, userId := range event.UserIds {
+		if userId == s.userId {
+			return true
+		}
+	}
+	return false
+}
+
+// Hub fans events out to subscribers. Publishing never blocks: a
+// subscriber whose buffer is full is dropped so its client reconnects.
+type Hub struct {
+	mu          sync.Mutex
+	subscribers map[*Subscriber]struct{}
+	buffer      int
+	closed      bool
+}
+
+func NewHub(buffer int) *Hub {
+	return &Hub{subscribers: map[*Subscriber]struct{}{}, buffer: buffer}
+}
+
+// Subscribe registers a client. The returned subscriber's channel is
+// already closed when the hub is shutting down.
+func (h *Hub) Subscribe(userId int, admin bool) *Subscriber {
+	subscriber := &Subscriber{C: make(chan Event, h.buffer), userId: userId, admin: admin}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		close(subscriber.C)
+		return subscriber
+	}
+	h.subscribers[subscriber] = struct{}{}
+	return subscriber
+}
+
+func (h *Hub) Unsubscribe(subscriber *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.remove(subscriber)
+}
+
+func (h *Hub) Publish(event Event) {
+	if h == nil {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for subscriber := range h.subscribers {
+		if !subscriber.wants(event) {
+			continue
+		}
+		select {
+		case subscriber.C <- event:
+		default:
+			h.remove(subscriber)
+		}
+	}
+}
+
+// Close disconnects every subscriber so open streams end before the
+// server shuts down.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for subscriber := range h.subscribers {
+		h.remove(subscriber)
+	}
+}
+
+func (h *Hub) remove(subscriber *Subscriber) {
+	if _, ok := h.subscribers[subscriber]; ok {
+		delete(h.subscribers, subscriber)
+		close(subscriber.C)
+	}
+}
@@ -0,0 +1,260 @@
+// Package idempotency makes create endpoints safe to retry. A client sends
+// an Idempotency-Key header; the first request with a key runs and its
+// response is stored, and a retry with the same key and body gets that
+// response back instead of creating the resource again.
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"sync"
+	"time"
+	"tusk/apierror"
+	"tusk/middlewares"
+	"tusk/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	// Header is the request header carrying the key.
+	Header = "Idempotency-Key"
+	// ReplayedHeader is set on responses served from a stored request.
+	ReplayedHeader = "Idempotency-Replayed"
+
+	maxKeyLength = 255
+	pollEvery    = 100 * time.Millisecond
+)
+
+// Store keeps the keys in the idempotency_keys table, so every instance of
+// the API sees the same ones. A background sweep deletes expired keys.
+type Store struct {
+	db   *gorm.DB
+	ttl  time.Duration
+	wait time.Duration
+	now  func() time.Time
+	stop chan struct{}
+	once sync.Once
+}
+
+// NewStore keeps keys for ttl and sweeps expired ones every cleanupEvery.
+// A retry that arrives while the first request is still running waits up
+// to wait for its response.
+func NewStore(db *gorm.DB, ttl, wait, cleanupEvery time.Duration) *Store {
+	s := &Store{
+		db:   db,
+		ttl:  ttl,
+		wait: wait,
+		now:  func() time.Time { return time.Now().UTC() },
+		stop: make(chan struct{}),
+	}
+
+	go func() {
+		ticker := time.NewTicker(cleanupEvery)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if _, err := s.Purge(context.Background()); err != nil {
+					log.Printf("⚠️ Idempotency key cleanup failed: %v", err)
+				}
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+
+	return s
+}
+
+// Purge deletes the expired keys and returns how many there were.
+func (s *Store) Purge(ctx context.Context) (int64, error) {
+	result := s.db.WithContext(ctx).Where("expires_at < ?", s.now()).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
+
+// Close stops the background sweep.
+func (s *Store) Close() {
+	s.once.Do(func() { close(s.stop) })
+}
+
+var errKeyTaken = errors.New("idempotency key already exists")
+
+// Middleware makes the route idempotent for requests carrying the header.
+// Keys belong to the authenticated user and to scope, so it must run after
+// JWTAuth. Only successful responses are stored: when the handler fails the
+// key is released and the client may retry with it. If the store is
+// unavailable the request fails rather than risk running twice.
+func (s *Store) Middleware(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(Header)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxKeyLength {
+			c.Error(apierror.BadRequest(apierror.CodeBadRequest, "Idempotency-Key must be at most 255 characters"))
+			c.Abort()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.Error(apierror.BadRequest(apierror.CodeBadRequest, "Request body could not be read"))
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		hash := sha256.Sum256(body)
+
+		row := models.IdempotencyKey{
+			UserId:      c.GetInt("userId"),
+			Scope:       scope,
+			RequestKey:  key,
+			RequestHash: hex.EncodeToString(hash[:]),
+		}
+		stored, err := s.claim(c, &row)
+		var apiErr *apierror.Error
+		switch {
+		case errors.As(err, &apiErr):
+			c.Error(apiErr)
+			c.Abort()
+		case err != nil:
+			c.Error(apierror.Internal(err))
+			c.Abort()
+		case stored != nil:
+			c.Header(ReplayedHeader, "true")
+			c.Data(stored.StatusCode, stored.ContentType, []byte(stored.Response))
+			c.Abort()
+		default:
+			s.run(c, row)
+		}
+	}
+}
+
+// claim inserts row for this request. When the key is already there it
+// returns the stored response, waiting for it if the first request is
+// still running, or an *apierror.Error for a different body.
+func (s *Store) claim(c *gin.Context, row *models.IdempotencyKey) (*models.IdempotencyKey, error) {
+	ctx := c.Request.Context()
+	deadline := s.now().Add(s.wait)
+	for {
+		row.ExpiresAt = s.now().Add(s.ttl)
+		errInsert := s.insert(ctx, row)
+		if errInsert == nil {
+			return nil, nil
+		}
+		if !errors.Is(errInsert, errKeyTaken) {
+			return nil, errInsert
+		}
+
+		existing := models.IdempotencyKey{}
+		errDB := s.db.WithContext(ctx).
+			Where("user_id = ? AND scope = ? AND request_key = ?", row.UserId, row.Scope, row.RequestKey).
+			First(&existing).Error
+		if errors.Is(errDB, gorm.ErrRecordNotFound) {
+			continue // released or purged since the insert, try again
+		}
+		if errDB != nil {
+			return nil, errDB
+		}
+
+		switch {
+		case existing.ExpiresAt.Before(s.now()):
+			// expired but not swept yet; the key is free again
+			errDelete := s.db.WithContext(ctx).Where("id = ? AND expires_at < ?", existing.Id, s.now()).Delete(&models.IdempotencyKey{}).Error
+			if errDelete != nil {
+				return nil, errDelete
+			}
+			continue
+		case existing.RequestHash != row.RequestHash:
+			return nil, apierror.Conflict(apierror.CodeIdempotencyKeyReused, "Idempotency-Key was already used for a different request")
+		case existing.CompletedAt != nil:
+			return &existing, nil
+		case !s.now().Before(deadline):
+			c.Header("Retry-After", "1")
+			return nil, apierror.Conflict(apierror.CodeIdempotencyInProgress, "A request with this Idempotency-Key is still in progress")
+		}
+
+		select {
+		case <-time.After(pollEvery):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// insert relies on the unique index so that of two concurrent requests
+// with one key exactly one gets the row.
+func (s *Store) insert(ctx context.Context, row *models.IdempotencyKey) error {
+	row.Id = 0
+	result := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(row)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errKeyTaken
+	}
+	return nil
+}
+
+// run calls the handler and stores its response, or releases the key if it
+// didn't succeed, panics included.
+func (s *Store) run(c *gin.Context, row models.IdempotencyKey) {
+	recorder := &responseRecorder{ResponseWriter: c.Writer}
+	c.Writer = recorder
+
+	completed := false
+	defer func() {
+		c.Writer = recorder.ResponseWriter
+		if completed {
+			return
+		}
+		// the request context may be done by now
+		if err := s.db.Delete(&models.IdempotencyKey{}, row.Id).Error; err != nil {
+			middlewares.Logger(c).Warn("idempotency key release failed", "key", row.RequestKey, "error", err)
+		}
+	}()
+
+	c.Next()
+
+	status := recorder.Status()
+	if !recorder.Written() || status < 200 || status >= 300 {
+		return
+	}
+	now := s.now()
+	errDB := s.db.Model(&models.IdempotencyKey{}).Where("id = ?", row.Id).Updates(map[string]interface{}{
+		"status_code":  status,
+		"content_type": recorder.Header().Get("Content-Type"),
+		"response":     recorder.body.String(),
+		"completed_at": now,
+	}).Error
+	if errDB != nil {
+		middlewares.Logger(c).Error("idempotency response not stored", "key", row.RequestKey, "error", errDB)
+		return
+	}
+	completed = true
+}
+
+// responseRecorder copies the response body while it is written.
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(data string) (int, error) {
+	r.body.WriteString(data)
+	return r.ResponseWriter.WriteString(data)
+}
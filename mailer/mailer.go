@@ -0,0 +1,121 @@
+package mailer
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net/smtp"
+	"strings"
+	"sync"
+)
+
+// Mailer sends a single HTML email. Implementations must be safe for
+// concurrent use.
+type Mailer interface {
+	Send(to, subject, htmlBody string) error
+}
+
+// SMTPMailer delivers mail through an SMTP server with PLAIN auth. With TLS
+// set it connects over implicit TLS (port 465); otherwise STARTTLS is used
+// when the server offers it.
+type SMTPMailer struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	TLS      bool
+}
+
+func (m *SMTPMailer) Send(to, subject, htmlBody string) error {
+	addr := fmt.Sprintf("%s:%d", m.Host, m.Port)
+
+	var auth smtp.Auth
+	if m.Username != "" {
+		auth = smtp.PlainAuth("", m.Username, m.Password, m.Host)
+	}
+
+	message := strings.Join([]string{
+		"From: " + m.From,
+		"To: " + to,
+		"Subject: " + subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/html; charset=UTF-8",
+		"",
+		htmlBody,
+	}, "\r\n")
+
+	if m.TLS {
+		return m.sendTLS(addr, auth, to, []byte(message))
+	}
+	return smtp.SendMail(addr, auth, m.From, []string{to}, []byte(message))
+}
+
+func (m *SMTPMailer) sendTLS(addr string, auth smtp.Auth, to string, message []byte) error {
+	conn, err := tls.Dial("tcp", addr, &tls.Config{ServerName: m.Host})
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(m.From); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(message); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// LogMailer only logs outgoing mail; used when SMTP isn't configured.
+type LogMailer struct{}
+
+func (LogMailer) Send(to, subject, htmlBody string) error {
+	log.Printf("📧 Mail to %s: %s\n%s", to, subject, htmlBody)
+	return nil
+}
+
+// Fake records every message instead of sending it, for tests.
+type Fake struct {
+	mu   sync.Mutex
+	sent []Message
+}
+
+// Message is a mail recorded by Fake.
+type Message struct {
+	To, Subject, Body string
+}
+
+func (f *Fake) Send(to, subject, htmlBody string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, Message{To: to, Subject: subject, Body: htmlBody})
+	return nil
+}
+
+// Sent returns a copy of the messages recorded so far.
+func (f *Fake) Sent() []Message {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Message(nil), f.sent...)
+}
@@ -0,0 +1,93 @@
+package mailer
+
+import (
+	"errors"
+	"log"
+	"net"
+	"net/textproto"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrQueueFull is returned by Queue.Send when the backlog is at capacity.
+	ErrQueueFull = errors.New("mail queue is full")
+	// ErrQueueClosed is returned by Queue.Send after Close.
+	ErrQueueClosed = errors.New("mail queue is closed")
+)
+
+// retryDelay is how long a transient failure waits before its one retry.
+var retryDelay = 5 * time.Second
+
+type queued struct {
+	to, subject, body string
+}
+
+// Queue is a Mailer that hands messages to a background worker so callers
+// never wait on the mail server. Messages beyond the buffer are dropped.
+type Queue struct {
+	mailer   Mailer
+	messages chan queued
+	done     sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+func NewQueue(mailer Mailer, size int) *Queue {
+	q := &Queue{mailer: mailer, messages: make(chan queued, size)}
+	q.done.Add(1)
+	go q.run()
+	return q
+}
+
+func (q *Queue) Send(to, subject, body string) error {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	select {
+	case q.messages <- queued{to, subject, body}:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Close stops accepting mail and waits for the queued messages to go out.
+func (q *Queue) Close() {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.messages)
+	}
+	q.mu.Unlock()
+	q.done.Wait()
+}
+
+func (q *Queue) run() {
+	defer q.done.Done()
+	for m := range q.messages {
+		err := q.mailer.Send(m.to, m.subject, m.body)
+		if err != nil && transient(err) {
+			time.Sleep(retryDelay)
+			err = q.mailer.Send(m.to, m.subject, m.body)
+		}
+		if err != nil {
+			log.Printf("❌ Failed to send %q to %s: %v", m.subject, m.to, err)
+		}
+	}
+}
+
+// transient reports whether err is worth retrying: network errors and SMTP
+// 4xx replies.
+func transient(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var smtpErr *textproto.Error
+	return errors.As(err, &smtpErr) && smtpErr.Code >= 400 && smtpErr.Code < 500
+}
@@ -0,0 +1,66 @@
+package mailer
+
+import (
+	"errors"
+	"net/textproto"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flaky fails the first fails sends with err.
+type flaky struct {
+	mu       sync.Mutex
+	err      error
+	fails    int
+	attempts int
+}
+
+func (f *flaky) Send(to, subject, htmlBody string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.attempts++
+	if f.attempts <= f.fails {
+		return f.err
+	}
+	return nil
+}
+
+func TestQueueRetriesOnce(t *testing.T) {
+	defer func(delay time.Duration) { retryDelay = delay }(retryDelay)
+	retryDelay = time.Millisecond
+
+	cases := []struct {
+		name     string
+		err      error
+		fails    int
+		attempts int
+	}{
+		{"sent", nil, 0, 1},
+		{"transient then sent", &textproto.Error{Code: 421, Msg: "try later"}, 1, 2},
+		{"transient twice", &textproto.Error{Code: 451, Msg: "try later"}, 5, 2},
+		{"permanent", &textproto.Error{Code: 550, Msg: "no such user"}, 5, 1},
+		{"not an SMTP error", errors.New("bad address"), 5, 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			mailer := &flaky{err: tc.err, fails: tc.fails}
+			queue := NewQueue(mailer, 1)
+			if err := queue.Send("andi@go.id", "Hi", "<p>Hi</p>"); err != nil {
+				t.Fatal(err)
+			}
+			queue.Close()
+			if mailer.attempts != tc.attempts {
+				t.Errorf("attempts = %d, want %d", mailer.attempts, tc.attempts)
+			}
+		})
+	}
+}
+
+func TestQueueRefusesWhenClosed(t *testing.T) {
+	queue := NewQueue(LogMailer{}, 1)
+	queue.Close()
+	if err := queue.Send("andi@go.id", "Hi", "<p>Hi</p>"); !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("err = %v, want ErrQueueClosed", err)
+	}
+}
@@ -0,0 +1,54 @@
+package mailer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html"
+	"html/template"
+	"strings"
+)
+
+//go:embed templates/*.html
+var templateFiles embed.FS
+
+// templates holds one set per file since every file defines its own
+// "subject" and "body".
+var templates = loadTemplates()
+
+func loadTemplates() map[string]*template.Template {
+	entries, err := templateFiles.ReadDir("templates")
+	if err != nil {
+		panic(err)
+	}
+
+	loaded := make(map[string]*template.Template, len(entries))
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".html")
+		loaded[name] = template.Must(template.ParseFS(templateFiles, "templates/"+entry.Name()))
+	}
+	return loaded
+}
+
+// Render executes the named template (the file name without .html) and
+// returns its subject and HTML body.
+func Render(name string, data interface{}) (subject, body string, err error) {
+	page, ok := templates[name]
+	if !ok {
+		return "", "", fmt.Errorf("mailer: unknown template %q", name)
+	}
+
+	var out bytes.Buffer
+	if err := page.ExecuteTemplate(&out, "subject", data); err != nil {
+		return "", "", err
+	}
+	// the subject goes into a header, not HTML
+	subject = strings.TrimSpace(html.UnescapeString(out.String()))
+
+	out.Reset()
+	if err := page.ExecuteTemplate(&out, "body", data); err != nil {
+		return "", "", err
+	}
+
+	return subject, out.String(), nil
+}
@@ -1,47 +1,372 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	_ "time/tzdata" // user timezones resolve even without system zoneinfo
+	"tusk/activity"
+	"tusk/apierror"
+	"tusk/cache"
 	"tusk/config"
 	"tusk/controllers"
-	"tusk/models"
+	"tusk/events"
+	"tusk/idempotency"
+	"tusk/mailer"
+	"tusk/metrics"
+	"tusk/middlewares"
+	"tusk/migrations"
+	"tusk/notifications"
+	"tusk/ratelimit"
+	"tusk/reminders"
+	"tusk/routes"
+	"tusk/seed"
 
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"gorm.io/gorm"
 )
 
 func main() {
+	// Config
+	cfg, errConfig := config.Load()
+	if errConfig != nil {
+		log.Fatal("❌ Invalid configuration:\n", errConfig)
+	}
+
 	// Database
-	db := config.DatabaseConnection()
-	db.AutoMigrate(&models.User{}, &models.Task{})
-	config.CreateOwnerAccount(db)
+	db, errDB := config.DatabaseConnection(cfg, func(attempt int, wait time.Duration, err error) {
+		log.Printf("⏳ Database not ready (attempt %d): %v, retrying in %s", attempt, err, wait)
+	})
+	if errDB != nil {
+		log.Fatal("❌ Database connection failed: ", errDB)
+	}
+	// go run . migrate up|down|status manages the schema and exits
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(db, os.Args[2:])
+		return
+	}
+	config.RunMigrations(db, cfg)
+	if err := config.CreateOwnerAccount(db, cfg); err != nil {
+		log.Fatal("❌ Owner account failed: ", err)
+	}
+	if err := activity.Register(db, middlewares.UserIdFromContext); err != nil {
+		log.Fatal("❌ Activity callbacks failed:", err)
+	}
+	responseCache := cache.New[gin.H](cfg.CacheTTL)
+	errCache := cache.InvalidateOnWrite(db, map[string][]string{
+		"users": {"employees:", "stats:"},
+		"tasks": {"stats:"},
+	}, responseCache.Invalidate)
+	if errCache != nil {
+		log.Fatal("❌ Cache callbacks failed:", errCache)
+	}
+
+	// go run . seed [-employees N] [-tasks M] fills a development database
+	if len(os.Args) > 1 && os.Args[1] == "seed" {
+		runSeed(db, cfg, os.Args[2:])
+		return
+	}
+
+	var mail mailer.Mailer = mailer.LogMailer{}
+	if cfg.SMTP.Host != "" {
+		mail = &mailer.SMTPMailer{
+			Host:     cfg.SMTP.Host,
+			Port:     cfg.SMTP.Port,
+			Username: cfg.SMTP.Username,
+			Password: cfg.SMTP.Password,
+			From:     cfg.SMTP.From,
+			TLS:      cfg.SMTP.TLS,
+		}
+	}
+	mailQueue := mailer.NewQueue(mail, 100)
+
+	var notifier notifications.Notifier = notifications.LogNotifier{}
+	if cfg.FCMCredentials != "" {
+		fcm, errFCM := notifications.NewFCMNotifier(cfg.FCMCredentials)
+		if errFCM != nil {
+			log.Fatal("❌ FCM setup failed:", errFCM)
+		}
+		notifier = fcm
+	}
+
+	eventHub := events.NewHub(32)
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	appMetrics := metrics.New(registry)
+	if sqlDB, err := db.DB(); err == nil {
+		if err := metrics.RegisterDB(registry, sqlDB); err != nil {
+			log.Fatal("❌ Metrics setup failed:", err)
+		}
+	}
+	denylist := middlewares.NewMemoryDenylist(time.Minute)
 
 	// Controller
-	userController := controllers.UserController{DB: db}
-	taskController := controllers.TaskController{DB: db}
+
+	userController := controllers.UserController{
+		DB:             db,
+		JWTSecret:      cfg.JWTSecret,
+		TokenExpiry:    cfg.TokenExpiry,
+		RefreshExpiry:  cfg.RefreshExpiry,
+		Mailer:         mailQueue,
+		MailConfigured: cfg.SMTP.Host != "",
+		ResetURL:       cfg.ResetURL,
+		VerifyURL:      cfg.VerifyURL,
+		Lockout:        cfg.Lockout,
+		BcryptCost:     cfg.BcryptCost,
+		Denylist:       denylist,
+		Cache:          responseCache,
+		Metrics:        appMetrics,
+		Avatars:        cfg.Avatars,
+	}
+	attachmentController := controllers.AttachmentController{DB: db, Uploads: cfg.Uploads}
+	taskController := controllers.TaskController{
+		DB:             db,
+		Assigner:       &controllers.AutoAssigner{Config: cfg.AutoAssign},
+		ForecastConfig: cfg.Forecast,
+		UploadDir:      cfg.Uploads.Dir,
+		EvidenceMax:    cfg.Uploads.EvidenceMax,
+		Attachments:    &attachmentController,
+		Notifier:       notifier,
+		Mailer:         mailQueue,
+		Events:         eventHub,
+		BulkConfig:     cfg.Bulk,
+		Metrics:        appMetrics,
+	}
+	commentController := controllers.CommentController{DB: db, Events: eventHub}
+	tagController := controllers.TagController{DB: db}
+	departmentController := controllers.DepartmentController{DB: db}
+	subtaskController := controllers.SubtaskController{DB: db}
+	activityController := controllers.ActivityController{DB: db}
+	eventController := controllers.EventController{Hub: eventHub}
+	dashboardController := controllers.DashboardController{DB: db, Cache: responseCache}
+	reportController := controllers.ReportController{DB: db}
+	startedAt := time.Now()
+	requestStats := middlewares.NewRequestStats()
+	// due date reminders, stopped with the server below
+	reminderWorker := reminders.New(db, cfg.Reminders, taskController.Remind)
+	reminderCtx, stopReminders := context.WithCancel(context.Background())
+	defer stopReminders()
+	reminderWorker.Start(reminderCtx)
+	adminController := controllers.AdminController{DB: db, Stats: requestStats, StartedAt: startedAt, Reminders: reminderWorker}
+	healthController := controllers.HealthController{DB: db, StartedAt: startedAt}
 
 	// Router
-	router := gin.Default()
+	apierror.RegisterValidators()
+	requestLogger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	router := gin.New()
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal("❌ Invalid TRUSTED_PROXIES:", err)
+	}
+	router.Use(gin.Recovery())
+	router.Use(middlewares.SecurityHeaders())
+	router.Use(middlewares.RequestLogger(requestLogger, cfg.LogSkipPaths...))
+	// outside apierror.Middleware, so it sees the status of error responses
+	router.Use(appMetrics.Middleware())
+	router.Use(apierror.Middleware())
+	router.Use(requestStats.Middleware())
+	// before routing, so preflights of authenticated routes never reach JWTAuth
+	router.Use(middlewares.CORS(cfg.CORS))
+	router.Use(middlewares.QueryTimeout(cfg.QueryTimeout, routes.Untimed()...))
+
 	router.GET("/", func(c *gin.Context) {
 		c.JSON(http.StatusOK, "Welcome to Tusk API")
 	})
 
-	router.POST("/users/login", userController.Login)
-	router.POST("/users", userController.CreateAccount)
-	router.DELETE("/users/:id", userController.Delete)
-	router.GET("/users/Employee", userController.GetEmployee)
-
-	router.POST("/tasks", taskController.Create)
-	router.DELETE("/tasks/:id", taskController.Delete)
-	router.PATCH("/tasks/:id/submit", taskController.Submit)
-	router.PATCH("/tasks/:id/reject", taskController.Reject)
-	router.PATCH("/tasks/:id/fix", taskController.Fix)
-	router.PATCH("/tasks/:id/approve", taskController.Approve)
-	router.GET("/tasks/:id", taskController.FindById)
-	router.GET("/tasks/review/asc", taskController.NeedToBeReview)
-	router.GET("/tasks/progress/:userId", taskController.ProgressTasks)
-	router.GET("/tasks/stat/:userId", taskController.Statistic)
-	router.GET("/tasks/user/:userId/:status", taskController.FindByUserAndStatus)
-
-	router.Static("/attachments", "./attachments")
-	router.Run("192.168.1.4:8080")
+	// Probes for the load balancer, never behind auth
+	router.GET("/healthz", healthController.Healthz)
+	router.GET("/readyz", healthController.Readyz)
+
+	// Metrics get their own port when METRICS_ADDR is set, else need METRICS_TOKEN
+	metricsHandler := metrics.Handler(registry)
+	var metricsServer *http.Server
+	if cfg.Metrics.Addr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metricsHandler)
+		metricsServer = &http.Server{Addr: cfg.Metrics.Addr, Handler: mux}
+	} else if cfg.Metrics.Token != "" {
+		router.GET("/metrics", middlewares.RequireBearer(cfg.Metrics.Token), gin.WrapH(metricsHandler))
+	}
+
+	limiter := ratelimit.NewMemory(time.Minute, 100000)
+	idempotencyStore := idempotency.NewStore(db, 24*time.Hour, 10*time.Second, time.Hour)
+	routes.Setup(router, routes.Dependencies{
+		Users:       &userController,
+		Tasks:       &taskController,
+		Attachments: &attachmentController,
+		Comments:    &commentController,
+		Tags:        &tagController,
+		Departments: &departmentController,
+		Subtasks:    &subtaskController,
+		Activities:  &activityController,
+		Events:      &eventController,
+		Dashboard:   &dashboardController,
+		Reports:     &reportController,
+		Admin:       &adminController,
+		JWTSecret:   cfg.JWTSecret,
+		TokenChecks: []middlewares.TokenCheck{middlewares.DenylistCheck(denylist), userController.CheckTokenVersion, userController.ScopeDepartment},
+		Limiter:     limiter,
+		Idempotency: idempotencyStore,
+		RateLimit:   cfg.RateLimit,
+		LegacyDir:   "./attachments",
+	})
+
+	// Server
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
+
+	server := &http.Server{
+		Addr:    cfg.Addr(),
+		Handler: router,
+		// handlers still running after the grace period see their request
+		// context cancelled, which aborts queries made with db.WithContext
+		BaseContext: func(net.Listener) context.Context { return baseCtx },
+	}
+
+	// event streams never finish on their own
+	server.RegisterOnShutdown(eventHub.Close)
+
+	if metricsServer != nil {
+		go func() {
+			log.Println("📈 Metrics on", metricsServer.Addr)
+			if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Fatal("❌ Metrics server failed: ", err)
+			}
+		}()
+	}
+
+	listener, errListen := net.Listen("tcp", server.Addr)
+	if errListen != nil {
+		log.Fatal("❌ Server failed: ", errListen)
+	}
+	log.Println("🚀 Listening on", server.Addr)
+
+	signalCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err := serve(signalCtx, server, listener, cfg.ShutdownTimeout, cancelBase); err != nil {
+		log.Fatal("❌ Server failed: ", err)
+	}
+
+	if metricsServer != nil {
+		metricsCtx, cancelMetrics := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		metricsServer.Shutdown(metricsCtx)
+		cancelMetrics()
+	}
+	stopReminders()
+	reminderWorker.Wait()
+	mailQueue.Close()
+	limiter.Close()
+	idempotencyStore.Close()
+	denylist.Close()
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+	log.Println("✅ Server stopped")
+}
+
+// serve runs server on listener until ctx is done, then stops accepting
+// connections and lets in-flight requests finish for up to grace. Requests
+// still running after that are cancelled through cancelRequests, the
+// cancel of the server's BaseContext.
+func serve(ctx context.Context, server *http.Server, listener net.Listener, grace time.Duration, cancelRequests context.CancelFunc) error {
+	failed := make(chan error, 1)
+	go func() {
+		if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			failed <- err
+		}
+	}()
+
+	select {
+	case err := <-failed:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Println("🛑 Shutting down, draining in-flight requests for up to", grace)
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), grace)
+	defer cancelShutdown()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Println("⚠️ Grace period expired, cancelling remaining requests:", err)
+		cancelRequests()
+		server.Close()
+	}
+	return nil
+}
+
+func runMigrate(db *gorm.DB, args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	steps := flags.Int("steps", 1, "migrations to revert with down")
+	command := "status"
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+	flags.Parse(args)
+
+	switch command {
+	case "up":
+		applied, err := migrations.Up(db, log.Printf)
+		if err != nil {
+			log.Fatal("❌ Migration failed: ", err)
+		}
+		log.Printf("✅ %d migrations applied, schema at version %d", applied, migrations.Latest())
+	case "down":
+		reverted, err := migrations.Down(db, *steps, log.Printf)
+		if err != nil {
+			log.Fatal("❌ Revert failed: ", err)
+		}
+		log.Printf("✅ %d migrations reverted", reverted)
+	case "status":
+		statuses, err := migrations.StatusOf(db)
+		if err != nil {
+			log.Fatal("❌ Reading migrations failed: ", err)
+		}
+		for _, status := range statuses {
+			name, applied := status.Name, "pending"
+			if name == "" {
+				name = "(unknown, applied by a newer release)"
+			}
+			if status.AppliedAt != nil {
+				applied = "applied " + status.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d  %-40s %s\n", status.Version, name, applied)
+		}
+	default:
+		log.Fatalf("❌ Unknown migrate command %q, use up, down or status", command)
+	}
+}
+
+func runSeed(db *gorm.DB, cfg config.Config, args []string) {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	employees := flags.Int("employees", 10, "employees to generate")
+	tasks := flags.Int("tasks", 50, "tasks to generate")
+	password := flags.String("password", "tusk-seed-123", "password of every generated employee")
+	randSeed := flags.Int64("rand-seed", 1, "seed of the random generator; the same seed gives the same data")
+	flags.Parse(args)
+
+	result, err := seed.Run(db, seed.Options{
+		Env:        cfg.Env,
+		Employees:  *employees,
+		Tasks:      *tasks,
+		Password:   *password,
+		BcryptCost: cfg.BcryptCost,
+		UploadDir:  cfg.Uploads.Dir,
+		AdminEmail: cfg.Owner.Email,
+		RandSeed:   *randSeed,
+	})
+	if err != nil {
+		log.Fatal("❌ Seeding failed: ", err)
+	}
+	log.Printf("✅ Seeded %d employees, %d tasks, %d comments, %d attachments (password: %s)",
+		result.Employees, result.Tasks, result.Comments, result.Attachments, *password)
 }
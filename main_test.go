@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// startServer serves handler until SIGTERM and returns its URL and the
+// channel serve's result arrives on.
+func startServer(t *testing.T, handler http.Handler, grace time.Duration) (string, <-chan error) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	t.Cleanup(cancelBase)
+	server := &http.Server{Handler: handler, BaseContext: func(net.Listener) context.Context { return baseCtx }}
+
+	signalCtx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM)
+	t.Cleanup(stop)
+	done := make(chan error, 1)
+	go func() { done <- serve(signalCtx, server, listener, grace, cancelBase) }()
+	return "http://" + listener.Addr().String(), done
+}
+
+func get(url string) (int, string, error) {
+	res, err := http.Get(url)
+	if err != nil {
+		return 0, "", err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	return res.StatusCode, string(body), err
+}
+
+func TestShutdownDrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	url, done := startServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(300 * time.Millisecond)
+		w.Write([]byte("finished"))
+	}), 5*time.Second)
+
+	type response struct {
+		status int
+		body   string
+		err    error
+	}
+	responses := make(chan response, 1)
+	go func() {
+		status, body, err := get(url)
+		responses <- response{status, body, err}
+	}()
+
+	<-started
+	syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+	res := <-responses
+	if res.err != nil || res.status != http.StatusOK || res.body != "finished" {
+		t.Errorf("in-flight request got %d %q, %v; want it to finish", res.status, res.body, res.err)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := get(url); err == nil {
+		t.Error("new request accepted after shutdown")
+	}
+}
+
+func TestShutdownCancelsRequestsAfterGrace(t *testing.T) {
+	started := make(chan struct{})
+	cancelled := make(chan struct{})
+	url, done := startServer(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-r.Context().Done()
+		close(cancelled)
+	}), 100*time.Millisecond)
+
+	go get(url)
+	<-started
+	syscall.Kill(syscall.Getpid(), syscall.SIGTERM)
+
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("request context not cancelled after the grace period")
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,133 @@
+// Package metrics exposes the service's Prometheus metrics. Everything is
+// registered on the Registerer passed to New rather than the global one, so
+// each test can use its own registry.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "tusk"
+
+// Metrics holds the collectors. The recording methods are safe on a nil
+// *Metrics, which records nothing.
+type Metrics struct {
+	requests      *prometheus.CounterVec
+	duration      *prometheus.HistogramVec
+	inFlight      prometheus.Gauge
+	logins        *prometheus.CounterVec
+	tasksCreated  prometheus.Counter
+	notifications *prometheus.CounterVec
+}
+
+// New creates the collectors and registers them on reg.
+func New(reg prometheus.Registerer) *Metrics {
+	factory := promauto.With(reg)
+	return &Metrics{
+		requests: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests served, by method, route template and status.",
+		}, []string{"method", "route", "status"}),
+		duration: factory.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency, by method, route template and status.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		inFlight: factory.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "HTTP requests currently being served.",
+		}),
+		logins: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "logins_total",
+			Help:      "Login attempts, by result (succeeded or failed).",
+		}, []string{"result"}),
+		tasksCreated: factory.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "tasks_created_total",
+			Help:      "Tasks created.",
+		}),
+		notifications: factory.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "notifications_total",
+			Help:      "Push notifications, by result (sent or failed).",
+		}, []string{"result"}),
+	}
+}
+
+// RegisterDB adds the connection pool stats of db as go_sql_* metrics
+// labelled db_name="tusk" (open, in use, idle, waits), read from
+// sql.DBStats on every scrape.
+func RegisterDB(reg prometheus.Registerer, db *sql.DB) error {
+	return reg.Register(collectors.NewDBStatsCollector(db, namespace))
+}
+
+// Handler serves the metrics gathered from g in the Prometheus format.
+func Handler(g prometheus.Gatherer) http.Handler {
+	return promhttp.HandlerFor(g, promhttp.HandlerOpts{})
+}
+
+// Middleware records every request under its route template, so ids in the
+// path don't create a new series each time.
+func (m *Metrics) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if m == nil {
+			c.Next()
+			return
+		}
+
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
+		start := time.Now()
+
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		m.requests.WithLabelValues(c.Request.Method, route, status).Inc()
+		m.duration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+func (m *Metrics) Login(succeeded bool) {
+	if m == nil {
+		return
+	}
+	m.logins.WithLabelValues(result(succeeded, "succeeded")).Inc()
+}
+
+func (m *Metrics) TaskCreated() {
+	if m == nil {
+		return
+	}
+	m.tasksCreated.Inc()
+}
+
+func (m *Metrics) Notification(err error) {
+	if m == nil {
+		return
+	}
+	m.notifications.WithLabelValues(result(err == nil, "sent")).Inc()
+}
+
+func result(ok bool, success string) string {
+	if ok {
+		return success
+	}
+	return "failed"
+}
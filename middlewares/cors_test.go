@@ -0,0 +1,215 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"tusk/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+// corsRouter is wired the way main wires it: the security headers and CORS
+// for every request, and a route behind JWTAuth.
+func corsRouter(config middlewares.CORSConfig) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(middlewares.SecurityHeaders(), middlewares.CORS(config))
+	router.GET("/tasks", middlewares.JWTAuth(secret), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	return router
+}
+
+var corsConfig = middlewares.CORSConfig{
+	AllowedOrigins: []string{"https://app.example.com", "https://*.tusk.id"},
+	AllowedMethods: []string{"GET", "POST"},
+	AllowedHeaders: []string{"Authorization", "Content-Type"},
+	ExposedHeaders: []string{"X-Request-ID"},
+	MaxAge:         10 * time.Minute,
+}
+
+func corsRequest(router *gin.Engine, method, origin, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/tasks", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if method == http.MethodOptions {
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		req.Header.Set("Access-Control-Request-Headers", "Authorization")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	return res
+}
+
+func TestCORSAllowedOrigin(t *testing.T) {
+	router := corsRouter(corsConfig)
+	for _, origin := range []string{"https://app.example.com", "HTTPS://App.Example.com", "https://a.tusk.id", "https://a.b.tusk.id"} {
+		res := corsRequest(router, http.MethodGet, origin, token(t, "Employee"))
+		if res.Code != http.StatusNoContent {
+			t.Errorf("%s: status = %d, want 204", origin, res.Code)
+		}
+		if got := res.Header().Get("Access-Control-Allow-Origin"); got != origin {
+			t.Errorf("%s: Access-Control-Allow-Origin = %q", origin, got)
+		}
+		if got := res.Header().Get("Access-Control-Expose-Headers"); got != "X-Request-ID" {
+			t.Errorf("%s: Access-Control-Expose-Headers = %q", origin, got)
+		}
+		if got := res.Header().Get("Vary"); got != "Origin" {
+			t.Errorf("%s: Vary = %q, want Origin", origin, got)
+		}
+		if got := res.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+			t.Errorf("%s: Access-Control-Allow-Credentials = %q without credentials allowed", origin, got)
+		}
+	}
+}
+
+func TestCORSRejectedOrigin(t *testing.T) {
+	router := corsRouter(corsConfig)
+	origins := []string{
+		"https://evil.com",
+		"http://app.example.com", // another scheme
+		"https://app.example.com.evil.com",
+		"https://tusk.id", // the wildcard is for subdomains only
+		"https://eviltusk.id",
+		"http://a.tusk.id",
+	}
+	for _, origin := range origins {
+		// the request itself is served, the browser just can't read it
+		res := corsRequest(router, http.MethodGet, origin, token(t, "Employee"))
+		if res.Code != http.StatusNoContent {
+			t.Errorf("%s: status = %d, want 204", origin, res.Code)
+		}
+		if got := res.Header().Get("Access-Control-Allow-Origin"); got != "" {
+			t.Errorf("%s: Access-Control-Allow-Origin = %q, want none", origin, got)
+		}
+
+		res = corsRequest(router, http.MethodOptions, origin, "")
+		if res.Code != http.StatusForbidden {
+			t.Errorf("%s: preflight status = %d, want 403", origin, res.Code)
+		}
+		if got := res.Header().Get("Access-Control-Allow-Methods"); got != "" {
+			t.Errorf("%s: Access-Control-Allow-Methods = %q, want none", origin, got)
+		}
+	}
+}
+
+func TestCORSPreflightOfProtectedRoute(t *testing.T) {
+	router := corsRouter(corsConfig)
+
+	res := corsRequest(router, http.MethodOptions, "https://app.example.com", "")
+	if res.Code != http.StatusNoContent {
+		t.Fatalf("preflight status = %d, want 204\n%s", res.Code, res.Body.String())
+	}
+	want := map[string]string{
+		"Access-Control-Allow-Origin":  "https://app.example.com",
+		"Access-Control-Allow-Methods": "GET, POST",
+		"Access-Control-Allow-Headers": "Authorization, Content-Type",
+		"Access-Control-Max-Age":       "600",
+	}
+	for name, value := range want {
+		if got := res.Header().Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+
+	// the route itself still wants a token
+	res = corsRequest(router, http.MethodGet, "https://app.example.com", "")
+	if res.Code != http.StatusUnauthorized {
+		t.Errorf("status without a token = %d, want 401", res.Code)
+	}
+	if got := res.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Errorf("401 lacks Access-Control-Allow-Origin, got %q", got)
+	}
+
+	// an OPTIONS without Access-Control-Request-Method is no preflight
+	req := httptest.NewRequest(http.MethodOptions, "/tasks", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	res = httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	if res.Code == http.StatusNoContent {
+		t.Errorf("plain OPTIONS answered as a preflight")
+	}
+}
+
+func TestCORSCredentials(t *testing.T) {
+	t.Run("wildcard without credentials", func(t *testing.T) {
+		config := corsConfig
+		config.AllowedOrigins = []string{"*"}
+		res := corsRequest(corsRouter(config), http.MethodGet, "https://anything.dev", token(t, "Employee"))
+		if got := res.Header().Get("Access-Control-Allow-Origin"); got != "*" {
+			t.Errorf("Access-Control-Allow-Origin = %q, want *", got)
+		}
+		if got := res.Header().Get("Access-Control-Allow-Credentials"); got != "" {
+			t.Errorf("Access-Control-Allow-Credentials = %q, want none", got)
+		}
+	})
+
+	t.Run("listed origins with credentials", func(t *testing.T) {
+		config := corsConfig
+		config.AllowCredentials = true
+		for _, method := range []string{http.MethodGet, http.MethodOptions} {
+			res := corsRequest(corsRouter(config), method, "https://app.example.com", token(t, "Employee"))
+			if got := res.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+				t.Errorf("%s: Access-Control-Allow-Origin = %q", method, got)
+			}
+			if got := res.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+				t.Errorf("%s: Access-Control-Allow-Credentials = %q, want true", method, got)
+			}
+		}
+	})
+
+	// config.Load refuses this; should it get here anyway, the response
+	// never pairs "*" with credentials, which browsers would reject
+	t.Run("wildcard with credentials", func(t *testing.T) {
+		config := corsConfig
+		config.AllowedOrigins = []string{"*"}
+		config.AllowCredentials = true
+		res := corsRequest(corsRouter(config), http.MethodGet, "https://anything.dev", token(t, "Employee"))
+		if got := res.Header().Get("Access-Control-Allow-Origin"); got != "https://anything.dev" {
+			t.Errorf("Access-Control-Allow-Origin = %q, want the request's origin", got)
+		}
+		if got := res.Header().Get("Vary"); got != "Origin" {
+			t.Errorf("Vary = %q, want Origin", got)
+		}
+	})
+}
+
+func TestCORSWithoutOrigin(t *testing.T) {
+	res := corsRequest(corsRouter(corsConfig), http.MethodGet, "", token(t, "Employee"))
+	for name := range res.Header() {
+		if strings.HasPrefix(name, "Access-Control-") || name == "Vary" {
+			t.Errorf("same-origin request got %s", name)
+		}
+	}
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	router := corsRouter(corsConfig)
+	responses := map[string]*httptest.ResponseRecorder{
+		"ok":        corsRequest(router, http.MethodGet, "", token(t, "Employee")),
+		"401":       corsRequest(router, http.MethodGet, "", ""),
+		"preflight": corsRequest(router, http.MethodOptions, "https://app.example.com", ""),
+		"rejected":  corsRequest(router, http.MethodOptions, "https://evil.com", ""),
+	}
+	notFound := httptest.NewRecorder()
+	router.ServeHTTP(notFound, httptest.NewRequest(http.MethodGet, "/nowhere", nil))
+	responses["404"] = notFound
+
+	want := map[string]string{
+		"X-Content-Type-Options": "nosniff",
+		"X-Frame-Options":        "DENY",
+		"Referrer-Policy":        "no-referrer",
+	}
+	for name, res := range responses {
+		for header, value := range want {
+			if got := res.Header().Get(header); got != value {
+				t.Errorf("%s: %s = %q, want %q", name, header, got, value)
+			}
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package middlewares
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Denylist remembers revoked access tokens by JTI until they would have
+// expired anyway. It is an interface so a shared store such as Redis can
+// back it when the API runs on more than one instance.
+type Denylist interface {
+	Add(ctx context.Context, jti string, until time.Time) error
+	Contains(ctx context.Context, jti string) (bool, error)
+}
+
+// DenylistCheck rejects tokens whose JTI is on the denylist.
+func DenylistCheck(denylist Denylist) TokenCheck {
+	return func(c *gin.Context, claims *Claims) error {
+		if claims.ID == "" {
+			return nil
+		}
+		revoked, err := denylist.Contains(c.Request.Context(), claims.ID)
+		if err != nil {
+			return err
+		}
+		if revoked {
+			return ErrTokenRevoked
+		}
+		return nil
+	}
+}
+
+// MemoryDenylist is an in-process Denylist. Entries are dropped once their
+// token has expired, by a background sweep, so it never holds more than
+// the tokens revoked within one access token lifetime.
+type MemoryDenylist struct {
+	mu      sync.Mutex
+	entries map[string]time.Time
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// NewMemoryDenylist starts a denylist that sweeps every cleanupEvery.
+func NewMemoryDenylist(cleanupEvery time.Duration) *MemoryDenylist {
+	d := &MemoryDenylist{entries: map[string]time.Time{}, stop: make(chan struct{})}
+
+	go func() {
+		ticker := time.NewTicker(cleanupEvery)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				d.sweep(now)
+			case <-d.stop:
+				return
+			}
+		}
+	}()
+
+	return d
+}
+
+func (d *MemoryDenylist) Add(_ context.Context, jti string, until time.Time) error {
+	if !time.Now().Before(until) {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.entries[jti] = until
+	return nil
+}
+
+func (d *MemoryDenylist) Contains(_ context.Context, jti string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	until, ok := d.entries[jti]
+	return ok && time.Now().Before(until), nil
+}
+
+// Len is the number of entries currently held, expired or not.
+func (d *MemoryDenylist) Len() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.entries)
+}
+
+// Close stops the background sweep.
+func (d *MemoryDenylist) Close() {
+	d.once.Do(func() { close(d.stop) })
+}
+
+func (d *MemoryDenylist) sweep(now time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for jti, until := range d.entries {
+		if !now.Before(until) {
+			delete(d.entries, jti)
+		}
+	}
+}
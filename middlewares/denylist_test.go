@@ -0,0 +1,75 @@
+package middlewares_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"tusk/middlewares"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestMemoryDenylistEntriesExpire(t *testing.T) {
+	denylist := middlewares.NewMemoryDenylist(5 * time.Millisecond)
+	defer denylist.Close()
+	ctx := context.Background()
+
+	denylist.Add(ctx, "short", time.Now().Add(50*time.Millisecond))
+	denylist.Add(ctx, "long", time.Now().Add(time.Hour))
+	denylist.Add(ctx, "expired", time.Now().Add(-time.Second))
+	if denylist.Len() != 2 {
+		t.Fatalf("holding %d entries, want the expired token left out", denylist.Len())
+	}
+	if revoked, _ := denylist.Contains(ctx, "short"); !revoked {
+		t.Error("short isn't revoked")
+	}
+	if revoked, _ := denylist.Contains(ctx, "unknown"); revoked {
+		t.Error("unknown is revoked")
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for denylist.Len() > 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if denylist.Len() != 1 {
+		t.Errorf("holding %d entries, want the expired one swept", denylist.Len())
+	}
+	if revoked, _ := denylist.Contains(ctx, "short"); revoked {
+		t.Error("short is still revoked after it expired")
+	}
+	if revoked, _ := denylist.Contains(ctx, "long"); !revoked {
+		t.Error("long was dropped before it expired")
+	}
+}
+
+func TestDenylistCheck(t *testing.T) {
+	denylist := middlewares.NewMemoryDenylist(time.Minute)
+	defer denylist.Close()
+
+	router := gin.New()
+	router.GET("/", middlewares.JWTAuth(secret, middlewares.DenylistCheck(denylist)), func(c *gin.Context) { c.Status(http.StatusOK) })
+	get := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+		return res.Code
+	}
+
+	revoked, kept := token(t, "Employee"), token(t, "Employee")
+	claims := jwt.RegisteredClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(revoked, &claims); err != nil || claims.ID == "" {
+		t.Fatalf("token has no JTI: %v", err)
+	}
+	denylist.Add(context.Background(), claims.ID, claims.ExpiresAt.Time)
+
+	if got := get(revoked); got != http.StatusUnauthorized {
+		t.Errorf("revoked token: status = %d, want 401", got)
+	}
+	if got := get(kept); got != http.StatusOK {
+		t.Errorf("other token: status = %d, want 200", got)
+	}
+}
@@ -0,0 +1,160 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Claims is the payload of the access tokens issued by Login. The JTI
+// (RegisteredClaims.ID) identifies the token for the denylist; TokenVersion
+// must match the user's current version. PasswordChange marks a token that
+// may only be used to change the password.
+type Claims struct {
+	UserId         int    `json:"userId"`
+	Role           string `json:"role"`
+	TokenVersion   int    `json:"ver"`
+	PasswordChange bool   `json:"pwc,omitempty"`
+	jwt.RegisteredClaims
+}
+
+var (
+	ErrMissingSecret = errors.New("jwt secret is not configured")
+	ErrTokenRevoked  = errors.New("token has been revoked")
+
+	ErrPasswordChangeRequired = errors.New("password change required")
+)
+
+// TokenCheck runs after the signature and expiry are verified. It returns
+// ErrTokenRevoked or ErrPasswordChangeRequired to reject the token; any
+// other error is treated as the check being unavailable.
+type TokenCheck func(c *gin.Context, claims *Claims) error
+
+// GenerateToken signs an HS256 access token for the given user. With
+// passwordChange set the token only passes PasswordChangeCheck on the
+// change-password routes.
+func GenerateToken(secret string, userId int, role string, tokenVersion int, passwordChange bool, expiry time.Duration) (string, error) {
+	if secret == "" {
+		return "", ErrMissingSecret
+	}
+
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	claims := Claims{
+		UserId:         userId,
+		Role:           role,
+		TokenVersion:   tokenVersion,
+		PasswordChange: passwordChange,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        hex.EncodeToString(jti),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(expiry)),
+		},
+	}
+
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+}
+
+// ParseToken validates the signature and expiry of an access token.
+func ParseToken(secret, tokenString string) (*Claims, error) {
+	if secret == "" {
+		return nil, ErrMissingSecret
+	}
+
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// JWTAuth requires a valid "Authorization: Bearer <token>" header and puts
+// userId, role and the claims into the gin context, and the userId into the
+// request context for code that only sees a context.Context. Every check must pass
+// too, e.g. the denylist. With an empty secret every request is rejected.
+func JWTAuth(secret string, checks ...TokenCheck) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		header := c.GetHeader("Authorization")
+		tokenString, found := strings.CutPrefix(header, "Bearer ")
+		if !found || tokenString == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing or malformed token"})
+			return
+		}
+
+		claims, err := ParseToken(secret, tokenString)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			return
+		}
+
+		for _, check := range checks {
+			errCheck := check(c, claims)
+			if errors.Is(errCheck, ErrTokenRevoked) {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+				return
+			}
+			if errors.Is(errCheck, ErrPasswordChangeRequired) {
+				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Password change required", "mustChangePassword": true})
+				return
+			}
+			if errCheck != nil {
+				Logger(c).Error("token check failed", "error", errCheck)
+				c.AbortWithStatusJSON(http.StatusServiceUnavailable, gin.H{"error": "Could not verify token, please retry"})
+				return
+			}
+		}
+
+		c.Set("userId", claims.UserId)
+		c.Set("role", claims.Role)
+		c.Set("claims", claims)
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), userIdKey{}, claims.UserId))
+		c.Next()
+	}
+}
+
+// PasswordChangeCheck is a TokenCheck that only lets password-change tokens
+// through on the allowed routes, given as gin route paths.
+func PasswordChangeCheck(allowed ...string) TokenCheck {
+	return func(c *gin.Context, claims *Claims) error {
+		if !claims.PasswordChange {
+			return nil
+		}
+		for _, path := range allowed {
+			if c.FullPath() == path {
+				return nil
+			}
+		}
+		return ErrPasswordChangeRequired
+	}
+}
+
+type userIdKey struct{}
+
+// UserIdFromContext returns the authenticated user of a request context.
+func UserIdFromContext(ctx context.Context) (int, bool) {
+	userId, ok := ctx.Value(userIdKey{}).(int)
+	return userId, ok
+}
+
+// TokenClaims returns the claims set by JWTAuth, or nil outside it.
+func TokenClaims(c *gin.Context) *Claims {
+	if claims, ok := c.Get("claims"); ok {
+		return claims.(*Claims)
+	}
+	return nil
+}
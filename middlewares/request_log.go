@@ -0,0 +1,89 @@
+package middlewares
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const requestIdHeader = "X-Request-ID"
+
+// RequestLogger tags every request with an id, taken from X-Request-ID
+// when the caller sent a sane one, and writes one JSON line per request.
+// Bodies are never logged. Routes in skip (e.g. health checks) still get
+// an id but no log line.
+func RequestLogger(logger *slog.Logger, skip ...string) gin.HandlerFunc {
+	skipped := make(map[string]bool, len(skip))
+	for _, path := range skip {
+		skipped[path] = true
+	}
+
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestId := c.GetHeader(requestIdHeader)
+		if !validRequestId(requestId) {
+			requestId = newRequestId()
+		}
+		c.Set("requestId", requestId)
+		c.Set("logger", logger.With("request_id", requestId))
+		c.Header(requestIdHeader, requestId)
+
+		c.Next()
+
+		if skipped[c.Request.URL.Path] {
+			return
+		}
+
+		attrs := []any{
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"route", c.FullPath(),
+			"status", c.Writer.Status(),
+			"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"bytes", c.Writer.Size(),
+			"client_ip", c.ClientIP(),
+		}
+		if userId, ok := c.Get("userId"); ok {
+			attrs = append(attrs, "user_id", userId)
+		}
+
+		level := slog.LevelInfo
+		if c.Writer.Status() >= 500 {
+			level = slog.LevelError
+		}
+		logger.Log(c.Request.Context(), level, "request", append(attrs, "request_id", requestId)...)
+	}
+}
+
+// Logger returns the request scoped logger set by RequestLogger, so every
+// line carries the request id.
+func Logger(c *gin.Context) *slog.Logger {
+	if logger, ok := c.Get("logger"); ok {
+		return logger.(*slog.Logger)
+	}
+	return slog.Default()
+}
+
+func newRequestId() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// validRequestId keeps client ids short and printable so they can't break
+// the log line or the response header.
+func validRequestId(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,62 @@
+package middlewares
+
+import (
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RouteStats counts the requests served by one route template.
+type RouteStats struct {
+	Requests int64 `json:"requests"`
+	Errors   int64 `json:"errors"`
+}
+
+// RequestStats keeps in-memory request and error counters per route.
+type RequestStats struct {
+	mu     sync.Mutex
+	routes map[string]*RouteStats
+}
+
+func NewRequestStats() *RequestStats {
+	return &RequestStats{routes: map[string]*RouteStats{}}
+}
+
+// Middleware records every request under "METHOD /route/:template" so ids
+// in the path don't create a new key each time.
+func (s *RequestStats) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		key := c.Request.Method + " " + route
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		stats, ok := s.routes[key]
+		if !ok {
+			stats = &RouteStats{}
+			s.routes[key] = stats
+		}
+		stats.Requests++
+		if c.Writer.Status() >= 500 {
+			stats.Errors++
+		}
+	}
+}
+
+// Snapshot copies the current counters without resetting them.
+func (s *RequestStats) Snapshot() map[string]RouteStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := make(map[string]RouteStats, len(s.routes))
+	for key, stats := range s.routes {
+		snapshot[key] = *stats
+	}
+	return snapshot
+}
@@ -0,0 +1,41 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireRole only lets through callers whose role, set by JWTAuth, is one
+// of roles. It must run after JWTAuth.
+func RequireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("role")
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+			"error":         "You do not have permission to access this resource",
+			"requiredRoles": roles,
+		})
+	}
+}
+
+// RequireBearer only lets through requests carrying "Authorization: Bearer
+// <token>", for machine clients such as a metrics scraper.
+func RequireBearer(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		given, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid token"})
+			return
+		}
+		c.Next()
+	}
+}
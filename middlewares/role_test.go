@@ -0,0 +1,61 @@
+package middlewares_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"tusk/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+const secret = "test-secret"
+
+func token(t *testing.T, role string) string {
+	t.Helper()
+
+	token, err := middlewares.GenerateToken(secret, 7, role, 0, false, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestRequireRole(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusNoContent) }
+	router.GET("/admin", middlewares.JWTAuth(secret), middlewares.RequireRole("Admin"), ok)
+	router.GET("/managers", middlewares.JWTAuth(secret), middlewares.RequireRole("Admin", "Manager"), ok)
+
+	cases := []struct {
+		name, path, role string
+		status           int
+	}{
+		{"employee on an admin route", "/admin", "Employee", http.StatusForbidden},
+		{"admin on an admin route", "/admin", "Admin", http.StatusNoContent},
+		{"no token", "/admin", "", http.StatusUnauthorized},
+		{"manager on an admin route", "/admin", "Manager", http.StatusForbidden},
+		{"manager among several roles", "/managers", "Manager", http.StatusNoContent},
+		{"employee among several roles", "/managers", "Employee", http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.role != "" {
+				req.Header.Set("Authorization", "Bearer "+token(t, tc.role))
+			}
+			res := httptest.NewRecorder()
+			router.ServeHTTP(res, req)
+
+			if res.Code != tc.status {
+				t.Fatalf("status = %d, want %d\n%s", res.Code, tc.status, res.Body.String())
+			}
+			if tc.status == http.StatusForbidden && !strings.Contains(res.Body.String(), "requiredRoles") {
+				t.Errorf("403 body lacks requiredRoles: %s", res.Body.String())
+			}
+		})
+	}
+}
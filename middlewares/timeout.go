@@ -0,0 +1,28 @@
+package middlewares
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// QueryTimeout bounds the request context so queries issued through
+// db.WithContext(c.Request.Context()) are cancelled once it expires.
+// Long-lived routes such as streams are listed in skip.
+func QueryTimeout(timeout time.Duration, skip ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, path := range skip {
+			if c.FullPath() == path {
+				c.Next()
+				return
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
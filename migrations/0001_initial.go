@@ -0,0 +1,97 @@
+package migrations
+
+import (
+	"log"
+	"strings"
+	"time"
+	"tusk/migrations/initial"
+
+	"gorm.io/gorm"
+)
+
+// The initial schema is what AutoMigrate built before versioned
+// migrations. On a database AutoMigrate already created it finds every
+// table in place and only records the version, so existing installs adopt
+// it cleanly.
+func init() {
+	register(Migration{
+		Version: 1,
+		Name:    "initial_schema",
+		Up: func(tx *gorm.DB) error {
+			if err := migrateDueDates(tx); err != nil {
+				return err
+			}
+			if err := tx.AutoMigrate(initial.Models()...); err != nil {
+				return err
+			}
+			// tasks from before priorities existed become Medium
+			return tx.Table("tasks").
+				Where("priority IS NULL OR priority = 0").
+				Update("priority", 2).Error
+		},
+		Down: func(tx *gorm.DB) error {
+			tables := []interface{}{"task_tags"}
+			models := initial.Models()
+			for i := len(models) - 1; i >= 0; i-- {
+				tables = append(tables, models[i])
+			}
+			return tx.Migrator().DropTable(tables...)
+		},
+	})
+}
+
+// migrateDueDates converts the old free-text due_date column into a
+// timestamp. The values are parsed in Go because each database handles ”
+// and RFC3339 offsets differently when altering the column in place.
+func migrateDueDates(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable("tasks") {
+		return nil
+	}
+
+	columns, err := migrator.ColumnTypes("tasks")
+	if err != nil {
+		return err
+	}
+	legacy := false
+	for _, column := range columns {
+		typeName := strings.ToUpper(column.DatabaseTypeName())
+		if column.Name() == "due_date" && (strings.Contains(typeName, "CHAR") || strings.Contains(typeName, "TEXT")) {
+			legacy = true
+		}
+	}
+	if !legacy {
+		return nil
+	}
+
+	if err := db.Exec("ALTER TABLE tasks RENAME COLUMN due_date TO due_date_legacy").Error; err != nil {
+		return err
+	}
+	if err := migrator.AddColumn(&initial.Task{}, "DueDate"); err != nil {
+		return err
+	}
+
+	rows := []struct {
+		Id            int
+		DueDateLegacy string
+	}{}
+	if err := db.Table("tasks").Select("id, due_date_legacy").Where("due_date_legacy<>''").Scan(&rows).Error; err != nil {
+		return err
+	}
+	for _, row := range rows {
+		dueDate, err := time.Parse(time.RFC3339, row.DueDateLegacy)
+		if err != nil {
+			dueDate, err = time.ParseInLocation("2006-01-02", row.DueDateLegacy, time.Local)
+		}
+		if err != nil {
+			log.Printf("⚠️ Task %d has an unreadable due date %q, leaving it empty", row.Id, row.DueDateLegacy)
+			continue
+		}
+		if err := db.Table("tasks").Where("id=?", row.Id).Update("due_date", dueDate).Error; err != nil {
+			return err
+		}
+	}
+
+	log.Printf("ℹ️ Converted %d task due dates to timestamps", len(rows))
+	return migrator.DropColumn(&initial.Task{}, "due_date_legacy")
+}
@@ -0,0 +1,21 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	// User as of this migration, only the column it adds
+	type User struct {
+		MustChangePassword bool `gorm:"default:false"`
+	}
+
+	register(Migration{
+		Version: 2,
+		Name:    "user_must_change_password",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&User{}, "MustChangePassword")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&User{}, "MustChangePassword")
+		},
+	})
+}
@@ -0,0 +1,22 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	// User as of this migration, only the column it adds. Existing
+	// accounts take the default and stay active.
+	type User struct {
+		IsActive bool `gorm:"default:true"`
+	}
+
+	register(Migration{
+		Version: 3,
+		Name:    "user_is_active",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&User{}, "IsActive")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&User{}, "IsActive")
+		},
+	})
+}
@@ -0,0 +1,46 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	// User as of this migration, only the column it adds
+	type User struct {
+		Id            int  `gorm:"type:int;primaryKey;autoIncrement"`
+		EmailVerified bool `gorm:"default:false"`
+	}
+	type EmailVerification struct {
+		Id        int    `gorm:"type:int;primaryKey;autoIncrement"`
+		UserId    int    `gorm:"type:int;index"`
+		Email     string `gorm:"type:varchar(50)"`
+		TokenHash string `gorm:"type:varchar(64);uniqueIndex"`
+		ExpiresAt time.Time
+		UsedAt    *time.Time
+		CreatedAt time.Time
+		User      User `gorm:"foreignKey:UserId;constraint:OnDelete:CASCADE"`
+	}
+
+	register(Migration{
+		Version: 4,
+		Name:    "email_verification",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().AddColumn(&User{}, "EmailVerified"); err != nil {
+				return err
+			}
+			// accounts from before verification existed already get mail
+			if err := tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Model(&User{}).Update("email_verified", true).Error; err != nil {
+				return err
+			}
+			return tx.Migrator().CreateTable(&EmailVerification{})
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropTable(&EmailVerification{}); err != nil {
+				return err
+			}
+			return tx.Migrator().DropColumn(&User{}, "EmailVerified")
+		},
+	})
+}
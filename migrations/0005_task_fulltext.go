@@ -0,0 +1,24 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// Only MySQL gets a FULLTEXT index; task search falls back to LIKE on the
+// other drivers.
+func init() {
+	register(Migration{
+		Version: 5,
+		Name:    "task_fulltext",
+		Up: func(tx *gorm.DB) error {
+			if tx.Dialector.Name() != "mysql" {
+				return nil
+			}
+			return tx.Exec("CREATE FULLTEXT INDEX idx_tasks_fulltext ON tasks (title, description)").Error
+		},
+		Down: func(tx *gorm.DB) error {
+			if tx.Dialector.Name() != "mysql" {
+				return nil
+			}
+			return tx.Exec("DROP INDEX idx_tasks_fulltext ON tasks").Error
+		},
+	})
+}
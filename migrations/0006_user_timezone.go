@@ -0,0 +1,21 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	// User as of this migration, only the column it adds
+	type User struct {
+		Timezone string `gorm:"type:varchar(64)"`
+	}
+
+	register(Migration{
+		Version: 6,
+		Name:    "user_timezone",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&User{}, "Timezone")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&User{}, "Timezone")
+		},
+	})
+}
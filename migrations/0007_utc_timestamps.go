@@ -0,0 +1,166 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Until this version the MySQL DSN had loc=Local, so DATETIME columns hold
+// the wall clock of the zone the server ran in. The DSN now uses loc=UTC
+// and every value is rewritten from that zone to UTC, which means the
+// upgrade must run with the same TZ the server had before; under TZ=UTC
+// nothing changes. Postgres (timestamptz) and SQLite (stored with an
+// offset) keep absolute times and need nothing.
+func init() {
+	register(Migration{
+		Version: 7,
+		Name:    "utc_timestamps",
+		Up: func(tx *gorm.DB) error {
+			if tx.Dialector.Name() != "mysql" {
+				return nil
+			}
+			log.Printf("ℹ️ Converting DATETIME columns from %s to UTC", zoneName(time.Local))
+			return shiftDatetimes(tx, time.Local, time.UTC)
+		},
+		Down: func(tx *gorm.DB) error {
+			if tx.Dialector.Name() != "mysql" {
+				return nil
+			}
+			return shiftDatetimes(tx, time.UTC, time.Local)
+		},
+	})
+}
+
+const shiftBatch = 500
+
+// shiftDatetimes reads every DATETIME value as a wall clock in from and
+// writes back the wall clock of the same instant in to. Rows are visited by
+// id so each is converted exactly once; tables without an id column have
+// no timestamps worth converting and are skipped.
+func shiftDatetimes(tx *gorm.DB, from, to *time.Location) error {
+	tables, err := tx.Migrator().GetTables()
+	if err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		columns, hasId, err := datetimeColumns(tx, table)
+		if err != nil {
+			return err
+		}
+		if len(columns) == 0 {
+			continue
+		}
+		if !hasId {
+			log.Printf("⚠️ Skipping %s: no id column to convert its timestamps by", table)
+			continue
+		}
+
+		lastId := 0
+		for {
+			converted, next, err := shiftBatchOf(tx, table, columns, lastId, from, to)
+			if err != nil {
+				return fmt.Errorf("%s: %w", table, err)
+			}
+			if converted == 0 {
+				break
+			}
+			lastId = next
+		}
+	}
+	return nil
+}
+
+func datetimeColumns(tx *gorm.DB, table string) ([]string, bool, error) {
+	columnTypes, err := tx.Migrator().ColumnTypes(table)
+	if err != nil {
+		return nil, false, err
+	}
+
+	columns := []string{}
+	hasId := false
+	for _, column := range columnTypes {
+		if column.Name() == "id" {
+			hasId = true
+		}
+		if strings.EqualFold(column.DatabaseTypeName(), "DATETIME") {
+			columns = append(columns, column.Name())
+		}
+	}
+	return columns, hasId, nil
+}
+
+// shiftBatchOf converts up to shiftBatch rows after lastId and returns how
+// many it read and the last id.
+func shiftBatchOf(tx *gorm.DB, table string, columns []string, lastId int, from, to *time.Location) (int, int, error) {
+	rows, err := tx.Table(table).
+		Select(append([]string{"id"}, columns...)).
+		Where("id > ?", lastId).
+		Order("id").
+		Limit(shiftBatch).
+		Rows()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	type row struct {
+		id      int
+		updates map[string]interface{}
+	}
+	batch := []row{}
+	for rows.Next() {
+		id := 0
+		values := make([]sql.NullTime, len(columns))
+		dest := []interface{}{&id}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+
+		updates := map[string]interface{}{}
+		for i, value := range values {
+			if value.Valid {
+				updates[columns[i]] = rewall(value.Time, from, to)
+			}
+		}
+		batch = append(batch, row{id: id, updates: updates})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	for _, r := range batch {
+		if len(r.updates) == 0 {
+			continue
+		}
+		if err := tx.Table(table).Where("id = ?", r.id).UpdateColumns(r.updates).Error; err != nil {
+			return 0, 0, err
+		}
+	}
+	if len(batch) == 0 {
+		return 0, lastId, nil
+	}
+	return len(batch), batch[len(batch)-1].id, nil
+}
+
+// rewall takes the wall clock of t (read with loc=UTC) as a time in from
+// and returns the wall clock of that instant in to, again labelled UTC so
+// the driver writes it unchanged.
+func rewall(t time.Time, from, to *time.Location) time.Time {
+	instant := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), from)
+	wall := instant.In(to)
+	return time.Date(wall.Year(), wall.Month(), wall.Day(), wall.Hour(), wall.Minute(), wall.Second(), wall.Nanosecond(), time.UTC)
+}
+
+func zoneName(location *time.Location) string {
+	return time.Now().In(location).Format("MST -07:00")
+}
@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	// Department and User as of this migration, only the column it adds
+	type Department struct {
+		Id        int    `gorm:"type:int;primaryKey;autoIncrement"`
+		Name      string `gorm:"type:varchar(100);uniqueIndex"`
+		CreatedAt time.Time
+		UpdatedAt time.Time
+	}
+	type User struct {
+		DepartmentId *int `gorm:"type:int;index"`
+	}
+
+	register(Migration{
+		Version: 8,
+		Name:    "departments",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Migrator().CreateTable(&Department{}); err != nil {
+				return err
+			}
+			if err := tx.Migrator().AddColumn(&User{}, "DepartmentId"); err != nil {
+				return err
+			}
+			return tx.Migrator().CreateIndex(&User{}, "DepartmentId")
+		},
+		Down: func(tx *gorm.DB) error {
+			if err := tx.Migrator().DropIndex(&User{}, "DepartmentId"); err != nil {
+				return err
+			}
+			if err := tx.Migrator().DropColumn(&User{}, "DepartmentId"); err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable(&Department{})
+		},
+	})
+}
@@ -0,0 +1,35 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	// IdempotencyKey as of this migration
+	type IdempotencyKey struct {
+		Id          int    `gorm:"type:int;primaryKey;autoIncrement"`
+		UserId      int    `gorm:"type:int;uniqueIndex:idx_idempotency_keys_scope"`
+		Scope       string `gorm:"type:varchar(50);uniqueIndex:idx_idempotency_keys_scope"`
+		RequestKey  string `gorm:"type:varchar(255);uniqueIndex:idx_idempotency_keys_scope"`
+		RequestHash string `gorm:"type:varchar(64)"`
+		StatusCode  int    `gorm:"type:int"`
+		ContentType string `gorm:"type:varchar(100)"`
+		Response    string `gorm:"type:text"`
+		CompletedAt *time.Time
+		ExpiresAt   time.Time `gorm:"index"`
+		CreatedAt   time.Time
+	}
+
+	register(Migration{
+		Version: 9,
+		Name:    "idempotency_keys",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&IdempotencyKey{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&IdempotencyKey{})
+		},
+	})
+}
@@ -0,0 +1,21 @@
+package migrations
+
+import "gorm.io/gorm"
+
+func init() {
+	// User as of this migration, only the column it adds
+	type User struct {
+		AvatarPath string `gorm:"type:varchar(255)"`
+	}
+
+	register(Migration{
+		Version: 10,
+		Name:    "user_avatar",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().AddColumn(&User{}, "AvatarPath")
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropColumn(&User{}, "AvatarPath")
+		},
+	})
+}
@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	// TaskRequest as of this migration; User only for the foreign key
+	type User struct {
+		Id int `gorm:"type:int;primaryKey;autoIncrement"`
+	}
+	type TaskRequest struct {
+		Id          int    `gorm:"type:int;primaryKey;autoIncrement"`
+		RequesterId int    `gorm:"type:int;index"`
+		Title       string `gorm:"type:varchar(255)"`
+		Description string `gorm:"type:text"`
+		Priority    int    `gorm:"type:int;default:2"`
+		DueDate     *time.Time
+		Status      string `gorm:"type:varchar(10);index"`
+		Reason      string `gorm:"type:text"`
+		ReviewedBy  *int   `gorm:"type:int"`
+		ReviewedAt  *time.Time
+		TaskId      *int `gorm:"type:int"`
+		CreatedAt   time.Time
+		UpdatedAt   time.Time
+		Requester   User `gorm:"foreignKey:RequesterId;constraint:OnDelete:CASCADE"`
+	}
+
+	register(Migration{
+		Version: 11,
+		Name:    "task_requests",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&TaskRequest{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&TaskRequest{})
+		},
+	})
+}
@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func init() {
+	// TaskReminder as of this migration
+	type TaskReminder struct {
+		Id      int       `gorm:"type:int;primaryKey;autoIncrement"`
+		TaskId  int       `gorm:"type:int;uniqueIndex:idx_task_reminders_once"`
+		Kind    string    `gorm:"type:varchar(20);uniqueIndex:idx_task_reminders_once"`
+		DueDate time.Time `gorm:"uniqueIndex:idx_task_reminders_once"`
+		SentAt  time.Time
+	}
+
+	register(Migration{
+		Version: 12,
+		Name:    "task_reminders",
+		Up: func(tx *gorm.DB) error {
+			return tx.Migrator().CreateTable(&TaskReminder{})
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Migrator().DropTable(&TaskReminder{})
+		},
+	})
+}
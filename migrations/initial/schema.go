@@ -0,0 +1,155 @@
+// Package initial is a frozen copy of the models as migration 0001 created
+// them. The type names match package models so GORM derives the same
+// table, column and constraint names. Never change these types; schema
+// changes belong in a new migration.
+package initial
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type User struct {
+	Id             int    `gorm:"type:int;primaryKey;autoIncrement"`
+	Role           string `gorm:"type:varchar(10)"`
+	Name           string `gorm:"type:varchar(255)"`
+	Email          string `gorm:"type:varchar(50);uniqueIndex"`
+	Password       string `gorm:"type:varchar(255)"`
+	FailedAttempts int    `gorm:"type:int;default:0"`
+	LastFailedAt   *time.Time
+	LockedUntil    *time.Time
+	DeviceToken    *string `gorm:"type:varchar(255)"`
+	TokenVersion   int     `gorm:"type:int;default:0"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	DeletedAt      gorm.DeletedAt `gorm:"index"`
+	Tasks          []Task         `gorm:"constraint:OnDelete:CASCADE"`
+}
+
+type Task struct {
+	Id              int        `gorm:"type:int; primaryKey; autoIncrement"`
+	UserId          int        `gorm:"int; uniqueIndex:idx_tasks_user_client_token"`
+	PreviousUserId  *int       `gorm:"type:int"`
+	Title           string     `gorm:"type:varchar(255)"`
+	Description     string     `gorm:"type:text"`
+	Status          string     `gorm:"type:varchar(50)"`
+	Priority        int        `gorm:"type:smallint; default:2; index"`
+	Reason          string     `gorm:"type:text; default:"`
+	Revision        int8       `gorm:"type:int; default:0"`
+	DueDate         *time.Time `gorm:"index"`
+	Estimate        int        `gorm:"type:int; default:0"`
+	SubmitDate      string     `gorm:"type:varchar(50)"`
+	RejectedDate    string     `gorm:"type:varchar(50)"`
+	ApprovedDate    string     `gorm:"type:varchar(50)"`
+	StatusChangedAt *time.Time
+	StatusChangedBy *int   `gorm:"type:int"`
+	Attachment      string `gorm:"type:varchar(255)"`
+	EvidencePath    string `gorm:"type:varchar(255)"`
+	SubmittedAt     *time.Time
+	SubmitNote      string    `gorm:"type:text"`
+	AutoAssigned    bool      `gorm:"default:false"`
+	ClientToken     *string   `gorm:"type:varchar(64); uniqueIndex:idx_tasks_user_client_token"`
+	CreatedAt       time.Time `gorm:"index"`
+	UpdatedAt       time.Time
+	User            User  `gorm:"foreignKey:UserId"`
+	Tags            []Tag `gorm:"many2many:task_tags"`
+}
+
+type RefreshToken struct {
+	Id        int    `gorm:"type:int;primaryKey;autoIncrement"`
+	UserId    int    `gorm:"type:int;index"`
+	TokenHash string `gorm:"type:varchar(64);uniqueIndex"`
+	FamilyId  string `gorm:"type:varchar(64);index"`
+	ExpiresAt time.Time
+	Revoked   bool `gorm:"default:false"`
+	CreatedAt time.Time
+	User      User `gorm:"foreignKey:UserId;constraint:OnDelete:CASCADE"`
+}
+
+type PasswordReset struct {
+	Id        int    `gorm:"type:int;primaryKey;autoIncrement"`
+	UserId    int    `gorm:"type:int;index"`
+	TokenHash string `gorm:"type:varchar(64);uniqueIndex"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+	User      User `gorm:"foreignKey:UserId;constraint:OnDelete:CASCADE"`
+}
+
+type Attachment struct {
+	Id         int    `gorm:"type:int;primaryKey;autoIncrement"`
+	TaskId     int    `gorm:"type:int;index"`
+	UploaderId int    `gorm:"type:int;index"`
+	FileName   string `gorm:"type:varchar(255)"`
+	Path       string `gorm:"type:varchar(255)"`
+	Size       int64
+	Mime       string `gorm:"type:varchar(100)"`
+	CreatedAt  time.Time
+	Task       Task `gorm:"foreignKey:TaskId;constraint:OnDelete:CASCADE"`
+}
+
+type TaskSubmission struct {
+	Id           int    `gorm:"type:int;primaryKey;autoIncrement"`
+	TaskId       int    `gorm:"type:int;index"`
+	UserId       int    `gorm:"type:int"`
+	EvidencePath string `gorm:"type:varchar(255)"`
+	Note         string `gorm:"type:text"`
+	SubmittedAt  time.Time
+	Task         Task `gorm:"foreignKey:TaskId;constraint:OnDelete:CASCADE"`
+}
+
+type Comment struct {
+	Id        int    `gorm:"type:int;primaryKey;autoIncrement"`
+	TaskId    int    `gorm:"type:int;index"`
+	AuthorId  int    `gorm:"type:int;index"`
+	Body      string `gorm:"type:text"`
+	CreatedAt time.Time
+	Task      Task `gorm:"foreignKey:TaskId;constraint:OnDelete:CASCADE"`
+	Author    User `gorm:"foreignKey:AuthorId"`
+}
+
+type Tag struct {
+	Id        int    `gorm:"type:int;primaryKey;autoIncrement"`
+	Name      string `gorm:"type:varchar(50);uniqueIndex"`
+	CreatedAt time.Time
+}
+
+type Subtask struct {
+	Id        int    `gorm:"type:int;primaryKey;autoIncrement"`
+	TaskId    int    `gorm:"type:int;index"`
+	Title     string `gorm:"type:varchar(255)"`
+	Done      bool   `gorm:"default:false"`
+	Position  int    `gorm:"type:int;default:0"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Task      Task `gorm:"foreignKey:TaskId;constraint:OnDelete:CASCADE"`
+}
+
+type TaskActivity struct {
+	Id        int    `gorm:"type:int;primaryKey;autoIncrement"`
+	TaskId    int    `gorm:"type:int;index"`
+	ActorId   *int   `gorm:"type:int"`
+	Action    string `gorm:"type:varchar(50)"`
+	OldValue  string `gorm:"type:text"`
+	NewValue  string `gorm:"type:text"`
+	CreatedAt time.Time
+	Task      Task `gorm:"foreignKey:TaskId;constraint:OnDelete:CASCADE"`
+	Actor     User `gorm:"foreignKey:ActorId"`
+}
+
+// Models lists the tables in creation order.
+func Models() []interface{} {
+	return []interface{}{
+		&User{},
+		&Task{},
+		&RefreshToken{},
+		&PasswordReset{},
+		&Attachment{},
+		&TaskSubmission{},
+		&Comment{},
+		&Tag{},
+		&Subtask{},
+		&TaskActivity{},
+	}
+}
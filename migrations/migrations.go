@@ -0,0 +1,185 @@
+// Package migrations applies the versioned schema changes in order and
+// records them in the schema_migrations table. Each migration runs in its
+// own transaction; on MySQL DDL commits implicitly, so a failed migration
+// there may be half applied and needs fixing by hand.
+package migrations
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is one schema change. Down undoes Up.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// all is every migration this binary knows, see register.
+var all []Migration
+
+// register adds a migration from its file's init.
+func register(migration Migration) {
+	all = append(all, migration)
+	sort.Slice(all, func(i, j int) bool { return all[i].Version < all[j].Version })
+}
+
+// ErrDatabaseAhead means the database has migrations applied that this
+// binary doesn't know, i.e. a newer release migrated it.
+var ErrDatabaseAhead = errors.New("database schema is newer than this binary")
+
+type schemaMigration struct {
+	Version   int    `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"type:varchar(255)"`
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// Status is one row of the status report. Unknown versions, applied by a
+// newer binary, have an empty Name.
+type Status struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+// Logf receives a line for every migration applied or reverted.
+type Logf func(format string, args ...interface{})
+
+// Latest is the version the schema has after Up.
+func Latest() int {
+	if len(all) == 0 {
+		return 0
+	}
+	return all[len(all)-1].Version
+}
+
+func applied(db *gorm.DB) (map[int]schemaMigration, error) {
+	if err := db.AutoMigrate(&schemaMigration{}); err != nil {
+		return nil, err
+	}
+	rows := []schemaMigration{}
+	if err := db.Order("version").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	done := make(map[int]schemaMigration, len(rows))
+	for _, row := range rows {
+		done[row.Version] = row
+	}
+	return done, nil
+}
+
+func checkAhead(done map[int]schemaMigration) error {
+	known := map[int]bool{}
+	for _, migration := range all {
+		known[migration.Version] = true
+	}
+	unknown := []int{}
+	for version := range done {
+		if !known[version] {
+			unknown = append(unknown, version)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Ints(unknown)
+		return fmt.Errorf("%w: unknown versions %v, this binary knows up to %d", ErrDatabaseAhead, unknown, Latest())
+	}
+	return nil
+}
+
+// Up applies every pending migration in order and returns how many ran.
+// It changes nothing when the database is ahead of the binary.
+func Up(db *gorm.DB, logf Logf) (int, error) {
+	done, err := applied(db)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkAhead(done); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, migration := range all {
+		if _, ok := done[migration.Version]; ok {
+			continue
+		}
+		errTx := db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Up(tx); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+		})
+		if errTx != nil {
+			return count, fmt.Errorf("migration %04d %s: %w", migration.Version, migration.Name, errTx)
+		}
+		logf("✅ Applied migration %04d %s", migration.Version, migration.Name)
+		count++
+	}
+	return count, nil
+}
+
+// Down reverts the last steps applied migrations, newest first.
+func Down(db *gorm.DB, steps int, logf Logf) (int, error) {
+	done, err := applied(db)
+	if err != nil {
+		return 0, err
+	}
+	if err := checkAhead(done); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(all) - 1; i >= 0 && count < steps; i-- {
+		migration := all[i]
+		if _, ok := done[migration.Version]; !ok {
+			continue
+		}
+		errTx := db.Transaction(func(tx *gorm.DB) error {
+			if err := migration.Down(tx); err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{}, migration.Version).Error
+		})
+		if errTx != nil {
+			return count, fmt.Errorf("reverting migration %04d %s: %w", migration.Version, migration.Name, errTx)
+		}
+		logf("↩️ Reverted migration %04d %s", migration.Version, migration.Name)
+		count++
+	}
+	return count, nil
+}
+
+// StatusOf lists every known migration and any unknown applied one, by
+// version.
+func StatusOf(db *gorm.DB) ([]Status, error) {
+	done, err := applied(db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := []Status{}
+	for _, migration := range all {
+		status := Status{Version: migration.Version, Name: migration.Name}
+		if row, ok := done[migration.Version]; ok {
+			appliedAt := row.AppliedAt
+			status.AppliedAt = &appliedAt
+			delete(done, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, row := range done {
+		appliedAt := row.AppliedAt
+		statuses = append(statuses, Status{Version: row.Version, AppliedAt: &appliedAt})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// Attachment is a file uploaded to a task. Path is the generated name on
+// disk; FileName keeps the name the client uploaded.
+type Attachment struct {
+	Id         int       `gorm:"type:int;primaryKey;autoIncrement" json:"id"`
+	TaskId     int       `gorm:"type:int;index" json:"taskId"`
+	UploaderId int       `gorm:"type:int;index" json:"uploaderId"`
+	FileName   string    `gorm:"type:varchar(255)" json:"fileName"`
+	Path       string    `gorm:"type:varchar(255)" json:"-"`
+	Size       int64     `json:"size"`
+	Mime       string    `gorm:"type:varchar(100)" json:"mime"`
+	CreatedAt  time.Time `json:"createdAt"`
+	Task       Task      `gorm:"foreignKey:TaskId;constraint:OnDelete:CASCADE" json:"-"`
+}
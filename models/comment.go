@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Comment is a message on a task between the assignee and the admins.
+type Comment struct {
+	Id        int       `gorm:"type:int;primaryKey;autoIncrement" json:"id"`
+	TaskId    int       `gorm:"type:int;index" json:"taskId"`
+	AuthorId  int       `gorm:"type:int;index" json:"authorId"`
+	Body      string    `gorm:"type:text" json:"body"`
+	CreatedAt time.Time `json:"createdAt"`
+	Task      Task      `gorm:"foreignKey:TaskId;constraint:OnDelete:CASCADE" json:"-"`
+	Author    User      `gorm:"foreignKey:AuthorId" json:"-"`
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+// Department mengelompokkan user, misalnya "Engineering" atau "Finance".
+// Manager hanya melihat employee dan task di department-nya sendiri.
+type Department struct {
+	Id        int       `gorm:"type:int;primaryKey;autoIncrement" json:"id"`
+	Name      string    `gorm:"type:varchar(100);uniqueIndex" json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// EmailVerification is a single-use token proving the user owns Email;
+// only its SHA-256 hash is stored. It stops working once the user's email
+// no longer matches Email.
+type EmailVerification struct {
+	Id        int        `gorm:"type:int;primaryKey;autoIncrement" json:"id"`
+	UserId    int        `gorm:"type:int;index" json:"userId"`
+	Email     string     `gorm:"type:varchar(50)" json:"email"`
+	TokenHash string     `gorm:"type:varchar(64);uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+	User      User       `gorm:"foreignKey:UserId;constraint:OnDelete:CASCADE" json:"-"`
+}
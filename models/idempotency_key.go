@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// IdempotencyKey remembers a create request sent with an Idempotency-Key
+// header so a retry gets the first response instead of running twice.
+// CompletedAt is nil while the first request is still being handled.
+type IdempotencyKey struct {
+	Id          int    `gorm:"type:int;primaryKey;autoIncrement"`
+	UserId      int    `gorm:"type:int;uniqueIndex:idx_idempotency_keys_scope"`
+	Scope       string `gorm:"type:varchar(50);uniqueIndex:idx_idempotency_keys_scope"` // e.g. "create-task"
+	RequestKey  string `gorm:"type:varchar(255);uniqueIndex:idx_idempotency_keys_scope"`
+	RequestHash string `gorm:"type:varchar(64)"` // SHA-256 of the body
+	StatusCode  int    `gorm:"type:int"`
+	ContentType string `gorm:"type:varchar(100)"`
+	Response    string `gorm:"type:text"`
+	CompletedAt *time.Time
+	ExpiresAt   time.Time `gorm:"index"`
+	CreatedAt   time.Time
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// PasswordReset is a single-use reset token; only its SHA-256 hash is stored.
+type PasswordReset struct {
+	Id        int        `gorm:"type:int;primaryKey;autoIncrement" json:"id"`
+	UserId    int        `gorm:"type:int;index" json:"userId"`
+	TokenHash string     `gorm:"type:varchar(64);uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `json:"expiresAt"`
+	UsedAt    *time.Time `json:"usedAt"`
+	CreatedAt time.Time  `json:"createdAt"`
+	User      User       `gorm:"foreignKey:UserId;constraint:OnDelete:CASCADE" json:"-"`
+}
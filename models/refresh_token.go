@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// RefreshToken is an opaque, rotating login token. Only its SHA-256 hash is
+// stored. Tokens rotated from the same login share a FamilyId so reuse of
+// an old token can revoke the whole chain.
+type RefreshToken struct {
+	Id        int       `gorm:"type:int;primaryKey;autoIncrement" json:"id"`
+	UserId    int       `gorm:"type:int;index" json:"userId"`
+	TokenHash string    `gorm:"type:varchar(64);uniqueIndex" json:"-"`
+	FamilyId  string    `gorm:"type:varchar(64);index" json:"familyId"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	Revoked   bool      `gorm:"default:false" json:"revoked"`
+	CreatedAt time.Time `json:"createdAt"`
+	User      User      `gorm:"foreignKey:UserId;constraint:OnDelete:CASCADE" json:"-"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Subtask is one checklist step of a task, shown in Position order.
+type Subtask struct {
+	Id        int       `gorm:"type:int;primaryKey;autoIncrement" json:"id"`
+	TaskId    int       `gorm:"type:int;index" json:"taskId"`
+	Title     string    `gorm:"type:varchar(255)" json:"title"`
+	Done      bool      `gorm:"default:false" json:"done"`
+	Position  int       `gorm:"type:int;default:0" json:"position"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+	Task      Task      `gorm:"foreignKey:TaskId;constraint:OnDelete:CASCADE" json:"-"`
+}
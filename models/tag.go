@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// Tag labels tasks, e.g. "frontend" or "client-x". Names are stored in
+// lower case so the unique index is case-insensitive on every database.
+type Tag struct {
+	Id        int       `gorm:"type:int;primaryKey;autoIncrement" json:"id"`
+	Name      string    `gorm:"type:varchar(50);uniqueIndex" json:"name"`
+	CreatedAt time.Time `json:"createdAt"`
+}
@@ -2,20 +2,115 @@ package models
 
 import "time"
 
+// Approved is the final ("done") state of a task.
+const (
+	StatusQueue      = "Queue"
+	StatusInProgress = "InProgress"
+	StatusReview     = "Review"
+	StatusRejected   = "Rejected"
+	StatusApproved   = "Approved"
+)
+
+// Statuses lists every task status in workflow order.
+var Statuses = []string{StatusQueue, StatusInProgress, StatusReview, StatusRejected, StatusApproved}
+
+// Priorities are stored as small integers so ORDER BY ranks them
+// correctly; Priorities[n-1] is the name of priority n.
+const (
+	PriorityLow = iota + 1
+	PriorityMedium
+	PriorityHigh
+	PriorityUrgent
+)
+
+var Priorities = []string{"Low", "Medium", "High", "Urgent"}
+
+// ParsePriority returns the stored value of a priority name, or false.
+func ParsePriority(name string) (int, bool) {
+	for i, valid := range Priorities {
+		if name == valid {
+			return i + 1, true
+		}
+	}
+	return 0, false
+}
+
+// PriorityName is the API name of a stored priority.
+func PriorityName(priority int) string {
+	if priority < PriorityLow || priority > PriorityUrgent {
+		return Priorities[PriorityMedium-1]
+	}
+	return Priorities[priority-1]
+}
+
+func ValidStatus(status string) bool {
+	for _, valid := range Statuses {
+		if status == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// statusTransitions is the task lifecycle: Queue → InProgress → Review →
+// Approved, with Review → Rejected → InProgress for rework.
+var statusTransitions = map[string][]string{
+	StatusQueue:      {StatusInProgress},
+	StatusInProgress: {StatusReview},
+	StatusReview:     {StatusApproved, StatusRejected},
+	StatusRejected:   {StatusInProgress},
+}
+
+// NextStatuses returns the statuses a task in status from may move to.
+func NextStatuses(from string) []string {
+	next := statusTransitions[from]
+	if next == nil {
+		return []string{}
+	}
+	return next
+}
+
+// CanTransition reports whether the workflow allows from → to.
+func CanTransition(from, to string) bool {
+	for _, status := range statusTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+	return false
+}
+
 type Task struct {
-	Id           int       `gorm:"type:int; primaryKey; autoIncrement" json:"id"`
-	UserId       int       `gorm:"int" json:"userId"`
-	Title        string    `gorm:"type:varchar(255)" json:"title"`
-	Description  string    `gorm:"type:text" json:"description"`
-	Status       string    `gorm:"type:varchar(50)" json:"status"`
-	Reason       string    `gorm:"type:text; default:" json:"reason"`
-	Revision     int8      `gorm:"type:int; default:0" json:"revision"`
-	DueDate      string    `gorm:"type:varchar(50)" json:"dueDate"`
-	SubmitDate   string    `gorm:"type:varchar(50)" json:"submitDate"`
-	RejectedDate string    `gorm:"type:varchar(50)" json:"rejectedDate"`
-	ApprovedDate string    `gorm:"type:varchar(50)" json:"approvedDate"`
-	Attachment   string    `gorm:"type:varchar(255)" json:"attachment"`
-	CreatedAt    time.Time `json:"createdAt"`
-	UpdatedAt    time.Time `json:"updatedAt"`
-	User         User      `gorm:"foreignKey:UserId" json:"user,omitempty"` // belongs to
+	Id              int        `gorm:"type:int; primaryKey; autoIncrement" json:"id"`
+	UserId          int        `gorm:"int; uniqueIndex:idx_tasks_user_client_token" json:"userId"`
+	PreviousUserId  *int       `gorm:"type:int" json:"previousUserId"`
+	Title           string     `gorm:"type:varchar(255)" json:"title"`
+	Description     string     `gorm:"type:text" json:"description"`
+	Status          string     `gorm:"type:varchar(50)" json:"status"`
+	Priority        int        `gorm:"type:smallint; default:2; index" json:"priority"`
+	Reason          string     `gorm:"type:text; default:" json:"reason"`
+	Revision        int8       `gorm:"type:int; default:0" json:"revision"`
+	DueDate         *time.Time `gorm:"index" json:"dueDate"`
+	Estimate        int        `gorm:"type:int; default:0" json:"estimate"` // minutes
+	SubmitDate      string     `gorm:"type:varchar(50)" json:"submitDate"`
+	RejectedDate    string     `gorm:"type:varchar(50)" json:"rejectedDate"`
+	ApprovedDate    string     `gorm:"type:varchar(50)" json:"approvedDate"`
+	StatusChangedAt *time.Time `json:"statusChangedAt"`
+	StatusChangedBy *int       `gorm:"type:int" json:"statusChangedBy"`
+	Attachment      string     `gorm:"type:varchar(255)" json:"attachment"`
+	EvidencePath    string     `gorm:"type:varchar(255)" json:"evidencePath"`
+	SubmittedAt     *time.Time `json:"submittedAt"`
+	SubmitNote      string     `gorm:"type:text" json:"submitNote"`
+	AutoAssigned    bool       `gorm:"default:false" json:"autoAssigned"`
+	ClientToken     *string    `gorm:"type:varchar(64); uniqueIndex:idx_tasks_user_client_token" json:"clientToken,omitempty"`
+	CreatedAt       time.Time  `gorm:"index" json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+	User            User       `gorm:"foreignKey:UserId" json:"user,omitempty"` // belongs to
+	Tags            []Tag      `gorm:"many2many:task_tags" json:"tags,omitempty"`
+}
+
+// IsOverdue reports whether the task is past its due date and not yet
+// approved. Tasks without a due date are never overdue.
+func (t Task) IsOverdue(now time.Time) bool {
+	return t.DueDate != nil && t.Status != StatusApproved && t.DueDate.Before(now)
 }
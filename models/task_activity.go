@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// TaskActivity is one entry in a task's history. OldValue and NewValue are
+// JSON objects holding only the fields that changed. ActorId is nil for
+// changes made outside a request, such as migrations or background jobs.
+type TaskActivity struct {
+	Id        int       `gorm:"type:int;primaryKey;autoIncrement" json:"id"`
+	TaskId    int       `gorm:"type:int;index" json:"taskId"`
+	ActorId   *int      `gorm:"type:int" json:"actorId"`
+	Action    string    `gorm:"type:varchar(50)" json:"action"`
+	OldValue  string    `gorm:"type:text" json:"oldValue"`
+	NewValue  string    `gorm:"type:text" json:"newValue"`
+	CreatedAt time.Time `json:"createdAt"`
+	Task      Task      `gorm:"foreignKey:TaskId;constraint:OnDelete:CASCADE" json:"-"`
+	Actor     User      `gorm:"foreignKey:ActorId" json:"-"`
+}
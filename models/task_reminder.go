@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// The reminders sent about a due date: one shortly before it and one
+// right after it has passed.
+const (
+	ReminderDueSoon = "due_soon"
+	ReminderOverdue = "overdue"
+)
+
+// TaskReminder records that the assignee was reminded about a task. The
+// unique index makes the row the claim of whichever instance inserts it
+// first, so a reminder goes out once however many instances run. DueDate
+// is part of it so that moving the due date arms the reminders again.
+type TaskReminder struct {
+	Id      int       `gorm:"type:int;primaryKey;autoIncrement"`
+	TaskId  int       `gorm:"type:int;uniqueIndex:idx_task_reminders_once"`
+	Kind    string    `gorm:"type:varchar(20);uniqueIndex:idx_task_reminders_once"`
+	DueDate time.Time `gorm:"uniqueIndex:idx_task_reminders_once"`
+	SentAt  time.Time
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// A task request is Pending until an Admin approves or rejects it, or the
+// requester cancels it.
+const (
+	RequestPending   = "Pending"
+	RequestApproved  = "Approved"
+	RequestRejected  = "Rejected"
+	RequestCancelled = "Cancelled"
+)
+
+// TaskRequest is a task proposed by an Employee. Approving it creates the
+// real task, linked through TaskId; Reason explains a rejection.
+type TaskRequest struct {
+	Id          int        `gorm:"type:int;primaryKey;autoIncrement" json:"id"`
+	RequesterId int        `gorm:"type:int;index" json:"requesterId"`
+	Title       string     `gorm:"type:varchar(255)" json:"title"`
+	Description string     `gorm:"type:text" json:"description"`
+	Priority    int        `gorm:"type:int;default:2" json:"-"` // suggested, see Priorities
+	DueDate     *time.Time `json:"dueDate"`                     // suggested
+	Status      string     `gorm:"type:varchar(10);index" json:"status"`
+	Reason      string     `gorm:"type:text" json:"reason"`
+	ReviewedBy  *int       `gorm:"type:int" json:"reviewedBy"`
+	ReviewedAt  *time.Time `json:"reviewedAt"`
+	TaskId      *int       `gorm:"type:int" json:"taskId"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	Requester   User       `gorm:"foreignKey:RequesterId;constraint:OnDelete:CASCADE" json:"-"`
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// TaskSubmission keeps every evidence photo ever submitted for a task, so
+// re-submitting after a rejection doesn't lose the earlier proof.
+type TaskSubmission struct {
+	Id           int       `gorm:"type:int;primaryKey;autoIncrement" json:"id"`
+	TaskId       int       `gorm:"type:int;index" json:"taskId"`
+	UserId       int       `gorm:"type:int" json:"userId"`
+	EvidencePath string    `gorm:"type:varchar(255)" json:"evidencePath"`
+	Note         string    `gorm:"type:text" json:"note"`
+	SubmittedAt  time.Time `json:"submittedAt"`
+	Task         Task      `gorm:"foreignKey:TaskId;constraint:OnDelete:CASCADE" json:"-"`
+}
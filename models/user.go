@@ -4,21 +4,61 @@ import (
 	"time"
 
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 )
 
-type User struct {
-	Id        int       `gorm:"type:int;primaryKey;autoIncrement" json:"id"`
-	Role      string    `gorm:"type:varchar(10)" json:"role"`
-	Name      string    `gorm:"type:varchar(255)" json:"name"`
-	Email     string    `gorm:"type:varchar(50)" json:"email"`
-	Password  string    `gorm:"type:varchar(255)" json:"password"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
-	Tasks     []Task    `gorm:"constraint:OnDelete:CASCADE" json:"tasks,omitempty"` // has many
+const (
+	RoleAdmin    = "Admin"
+	RoleManager  = "Manager"
+	RoleEmployee = "Employee"
+)
+
+// Roles is the fixed set of roles a user can have. A Manager administers
+// only the people and tasks of their own department.
+var Roles = []string{RoleAdmin, RoleManager, RoleEmployee}
+
+func ValidRole(role string) bool {
+	for _, valid := range Roles {
+		if role == valid {
+			return true
+		}
+	}
+	return false
 }
 
-func (u *User) AfterDelete(tx *gorm.DB) (err error) {
-	tx.Clauses(clause.Returning{}).Where("user_id = ?", u.Id).Delete(&Task{})
-	return
+// Location mengembalikan zona waktu pilihan user, UTC kalau tidak ada atau
+// tidak dikenal.
+func (u User) Location() *time.Location {
+	if u.Timezone == "" {
+		return time.UTC
+	}
+	location, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+// Email disimpan dalam huruf kecil; unique index menjaga satu akun per email.
+type User struct {
+	Id                 int            `gorm:"type:int;primaryKey;autoIncrement" json:"id"`
+	Role               string         `gorm:"type:varchar(10)" json:"role"`
+	Name               string         `gorm:"type:varchar(255)" json:"name"`
+	Email              string         `gorm:"type:varchar(50);uniqueIndex" json:"email"`
+	Password           string         `gorm:"type:varchar(255)" json:"password"`
+	FailedAttempts     int            `gorm:"type:int;default:0" json:"-"`
+	LastFailedAt       *time.Time     `json:"-"`
+	LockedUntil        *time.Time     `json:"lockedUntil"`
+	DeviceToken        *string        `gorm:"type:varchar(255)" json:"-"`
+	TokenVersion       int            `gorm:"type:int;default:0" json:"-"`             // dinaikkan untuk mencabut semua access token
+	MustChangePassword bool           `gorm:"default:false" json:"mustChangePassword"` // login hanya boleh ganti password
+	IsActive           bool           `gorm:"default:true" json:"isActive"`            // false: akun dinonaktifkan, histori tetap ada
+	EmailVerified      bool           `gorm:"default:false" json:"emailVerified"`      // email notifikasi hanya dikirim kalau true
+	Timezone           string         `gorm:"type:varchar(64)" json:"timezone"`        // nama IANA, kosong berarti UTC
+	DepartmentId       *int           `gorm:"type:int;index" json:"departmentId"`      // nil untuk Admin atau yang belum punya department
+	Department         *Department    `json:"department,omitempty"`
+	AvatarPath         string         `gorm:"type:varchar(255)" json:"-"` // nama file di AvatarConfig.Dir, kosong kalau belum ada
+	CreatedAt          time.Time      `json:"createdAt"`
+	UpdatedAt          time.Time      `json:"updatedAt"`
+	DeletedAt          gorm.DeletedAt `gorm:"index" json:"-"`                                     // soft delete
+	Tasks              []Task         `gorm:"constraint:OnDelete:CASCADE" json:"tasks,omitempty"` // has many
 }
@@ -0,0 +1,182 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+
+// FCMNotifier sends through the Firebase Cloud Messaging HTTP v1 API,
+// authenticating with a service account key.
+type FCMNotifier struct {
+	ProjectId   string
+	ClientEmail string
+	TokenURI    string
+	PrivateKey  *rsa.PrivateKey
+	Client      *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expiresAt   time.Time
+}
+
+// NewFCMNotifier reads a service account JSON file as downloaded from the
+// Firebase console.
+func NewFCMNotifier(credentialsFile string) (*FCMNotifier, error) {
+	content, err := os.ReadFile(credentialsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	var credentials struct {
+		ProjectId   string `json:"project_id"`
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(content, &credentials); err != nil {
+		return nil, fmt.Errorf("parse FCM credentials: %w", err)
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(credentials.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("parse FCM private key: %w", err)
+	}
+	if credentials.TokenURI == "" {
+		credentials.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+
+	return &FCMNotifier{
+		ProjectId:   credentials.ProjectId,
+		ClientEmail: credentials.ClientEmail,
+		TokenURI:    credentials.TokenURI,
+		PrivateKey:  key,
+		Client:      &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (f *FCMNotifier) Send(ctx context.Context, notification Notification) error {
+	accessToken, err := f.token(ctx)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"message": map[string]interface{}{
+			"token": notification.Token,
+			"notification": map[string]string{
+				"title": notification.Title,
+				"body":  notification.Body,
+			},
+			"data": notification.Data,
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	endpoint := "https://fcm.googleapis.com/v1/projects/" + f.ProjectId + "/messages:send"
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Authorization", "Bearer "+accessToken)
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := f.Client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 64<<10))
+	var fcmErr struct {
+		Error struct {
+			Status  string `json:"status"`
+			Message string `json:"message"`
+			Details []struct {
+				ErrorCode string `json:"errorCode"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	json.Unmarshal(body, &fcmErr)
+	for _, detail := range fcmErr.Error.Details {
+		if detail.ErrorCode == "UNREGISTERED" {
+			return ErrUnregistered
+		}
+	}
+
+	return fmt.Errorf("fcm: %s %s", response.Status, fcmErr.Error.Message)
+}
+
+// token returns a cached OAuth access token, exchanging a freshly signed
+// service account assertion when it is about to expire.
+func (f *FCMNotifier) token(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.accessToken != "" && time.Now().Before(f.expiresAt) {
+		return f.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   f.ClientEmail,
+		"scope": fcmScope,
+		"aud":   f.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(f.PrivateKey)
+	if err != nil {
+		return "", err
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, f.TokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	response, err := f.Client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("fcm token: %s", response.Status)
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return "", err
+	}
+
+	f.accessToken = result.AccessToken
+	f.expiresAt = now.Add(time.Duration(result.ExpiresIn)*time.Second - time.Minute)
+	return f.accessToken, nil
+}
@@ -0,0 +1,57 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+)
+
+// ErrUnregistered means the device token is no longer valid and should be
+// forgotten.
+var ErrUnregistered = errors.New("device token is not registered")
+
+// Notification is a push message to a single device.
+type Notification struct {
+	Token string
+	Title string
+	Body  string
+	Data  map[string]string
+}
+
+// Notifier delivers push notifications. Implementations must be safe for
+// concurrent use.
+type Notifier interface {
+	Send(ctx context.Context, notification Notification) error
+}
+
+// LogNotifier only logs notifications; used when FCM isn't configured.
+type LogNotifier struct{}
+
+func (LogNotifier) Send(ctx context.Context, notification Notification) error {
+	log.Printf("🔔 Push to %s: %s - %s", notification.Token, notification.Title, notification.Body)
+	return nil
+}
+
+// Fake records every notification instead of sending it, for tests.
+type Fake struct {
+	// Err, when set, is returned from every Send.
+	Err error
+
+	mu   sync.Mutex
+	sent []Notification
+}
+
+func (f *Fake) Send(ctx context.Context, notification Notification) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.sent = append(f.sent, notification)
+	return f.Err
+}
+
+// Sent returns a copy of the notifications recorded so far.
+func (f *Fake) Sent() []Notification {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Notification(nil), f.sent...)
+}
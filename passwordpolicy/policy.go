@@ -0,0 +1,66 @@
+// Package passwordpolicy decides whether a new password is acceptable.
+// It only applies to passwords people choose; generated ones and existing
+// hashes are never re-checked.
+package passwordpolicy
+
+import "strings"
+
+// MinLength is the shortest password accepted.
+const MinLength = 8
+
+// Rules reported in Violation.Rule.
+const (
+	RuleMinLength = "min"
+	RuleEmail     = "not_email"
+	RuleCommon    = "not_common"
+)
+
+// Violation is the first rule a password breaks. Message completes a
+// sentence starting with the field name, e.g. "password is too common".
+type Violation struct {
+	Rule    string
+	Message string
+}
+
+func (v *Violation) Error() string {
+	return "password " + v.Message
+}
+
+// common holds the most used passwords from public breach lists, lower
+// cased. Short ones are already caught by MinLength but are kept so the
+// list stays valid if the minimum changes.
+var common = map[string]bool{
+	"123456": true, "123456789": true, "12345678": true, "12345": true,
+	"1234567": true, "1234567890": true, "111111": true, "000000": true,
+	"123123": true, "654321": true, "666666": true, "121212": true,
+	"11111111": true, "987654321": true, "123321": true, "112233": true,
+	"password": true, "password1": true, "password123": true, "passw0rd": true,
+	"qwerty": true, "qwerty123": true, "qwertyuiop": true, "1q2w3e4r": true,
+	"1qaz2wsx": true, "zaq12wsx": true, "asdfghjkl": true, "abc123": true,
+	"abcd1234": true, "iloveyou": true, "sunshine": true, "princess": true,
+	"football": true, "baseball": true, "whatever": true, "trustno1": true,
+	"welcome": true, "welcome1": true, "admin": true, "admin123": true,
+	"letmein": true, "monkey": true, "dragon": true, "master": true,
+	"superman": true, "starwars": true, "computer": true, "michelle": true,
+	"changeme": true, "secret123": true, "tusk1234": true, "bismillah": true,
+}
+
+// Check returns nil when password may be used by the account with email,
+// otherwise the first rule it breaks.
+func Check(password, email string) *Violation {
+	if len([]rune(password)) < MinLength {
+		return &Violation{Rule: RuleMinLength, Message: "must be at least 8 characters"}
+	}
+
+	lowered := strings.ToLower(password)
+	localPart, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(email)), "@")
+	if localPart != "" && lowered == localPart {
+		return &Violation{Rule: RuleEmail, Message: "must not be the same as the email address"}
+	}
+
+	if common[lowered] {
+		return &Violation{Rule: RuleCommon, Message: "is too common, please choose another"}
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package passwordpolicy
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	cases := []struct {
+		name     string
+		password string
+		email    string
+		rule     string // "" when it's accepted
+	}{
+		{"accepted", "kopi-susu-9", "andi@go.id", ""},
+		{"exactly the minimum", "kopisusu", "andi@go.id", ""},
+		{"too short", "kopi9", "andi@go.id", RuleMinLength},
+		{"empty", "", "andi@go.id", RuleMinLength},
+		{"length in characters, not bytes", "kopi☕☕☕", "andi@go.id", RuleMinLength},
+		{"multibyte long enough", "kopi☕☕☕☕", "andi@go.id", ""},
+		{"email local part", "andi.pratama", "andi.pratama@go.id", RuleEmail},
+		{"email local part in another case", "Andi.Pratama", " ANDI.pratama@go.id ", RuleEmail},
+		{"contains the local part", "andi.pratama99", "andi.pratama@go.id", ""},
+		{"no email", "kopi-susu-9", "", ""},
+		{"common", "password123", "andi@go.id", RuleCommon},
+		{"common in another case", "PassWord1", "andi@go.id", RuleCommon},
+		{"seeded owner password", "123456", "owner@go.id", RuleMinLength},
+		{"common and long enough", "12345678", "owner@go.id", RuleCommon},
+		{"short rule comes first", "admin", "admin@go.id", RuleMinLength},
+		{"email rule before common", "password", "password@go.id", RuleEmail},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			violation := Check(tc.password, tc.email)
+			switch {
+			case tc.rule == "" && violation != nil:
+				t.Errorf("Check(%q) = %v, want it accepted", tc.password, violation)
+			case tc.rule != "" && violation == nil:
+				t.Errorf("Check(%q) accepted it, want %s", tc.password, tc.rule)
+			case violation != nil && violation.Rule != tc.rule:
+				t.Errorf("Check(%q) broke %s, want %s", tc.password, violation.Rule, tc.rule)
+			}
+		})
+	}
+}
+
+func TestViolationError(t *testing.T) {
+	if got := Check("kopi", "").Error(); got != "password must be at least 8 characters" {
+		t.Errorf("Error() = %q", got)
+	}
+}
+
+func TestCommonListIsLowerCase(t *testing.T) {
+	for password := range common {
+		if password != strings.ToLower(password) {
+			t.Errorf("%q can never match a lowered password", password)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+// Package ratelimit throttles requests with token buckets. Limiter is the
+// storage interface so a shared backend such as Redis can replace Memory
+// when the API runs on more than one instance.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rate allows Limit requests per Period, refilled evenly, with bursts of up
+// to Limit. A zero Limit disables limiting.
+type Rate struct {
+	Limit  int
+	Period time.Duration
+}
+
+// ParseRate reads a rate written as "10/1m" or "5/1h".
+func ParseRate(value string) (Rate, error) {
+	count, period, ok := strings.Cut(value, "/")
+	if !ok {
+		return Rate{}, errors.New("rate must look like 10/1m")
+	}
+
+	limit, err := strconv.Atoi(strings.TrimSpace(count))
+	if err != nil || limit < 0 {
+		return Rate{}, errors.New("rate limit must be a non-negative integer")
+	}
+	duration, err := time.ParseDuration(strings.TrimSpace(period))
+	if err != nil || duration <= 0 {
+		return Rate{}, errors.New("rate period must be a positive duration")
+	}
+	return Rate{Limit: limit, Period: duration}, nil
+}
+
+// Limiter takes tokens from per-key buckets.
+type Limiter interface {
+	// Allow takes one token from key's bucket. When the bucket is empty it
+	// returns false and how long until the next token is available.
+	Allow(ctx context.Context, key string, rate Rate) (bool, time.Duration, error)
+}
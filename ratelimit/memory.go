@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	full   time.Time // when the bucket is refilled and can be forgotten
+}
+
+// Memory is an in-process Limiter. A background sweep drops buckets that
+// have refilled, since they behave exactly like a missing one, so memory
+// only holds clients seen within the last period.
+type Memory struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	maxKeys int
+	now     func() time.Time
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// NewMemory starts a limiter that sweeps every cleanupEvery. maxKeys bounds
+// the map between sweeps; once reached, a sweep runs inline.
+func NewMemory(cleanupEvery time.Duration, maxKeys int) *Memory {
+	m := &Memory{
+		buckets: map[string]*bucket{},
+		maxKeys: maxKeys,
+		now:     time.Now,
+		stop:    make(chan struct{}),
+	}
+
+	go func() {
+		ticker := time.NewTicker(cleanupEvery)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.mu.Lock()
+				m.sweep()
+				m.mu.Unlock()
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+
+	return m
+}
+
+func (m *Memory) Allow(_ context.Context, key string, rate Rate) (bool, time.Duration, error) {
+	if rate.Limit <= 0 {
+		return true, 0, nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	perToken := rate.Period / time.Duration(rate.Limit)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		if m.maxKeys > 0 && len(m.buckets) >= m.maxKeys {
+			m.sweep()
+		}
+		b = &bucket{tokens: float64(rate.Limit), last: now}
+		m.buckets[key] = b
+	}
+
+	b.tokens += float64(now.Sub(b.last)) / float64(perToken)
+	if b.tokens > float64(rate.Limit) {
+		b.tokens = float64(rate.Limit)
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) * float64(perToken))
+		return false, wait, nil
+	}
+
+	b.tokens--
+	b.full = now.Add(time.Duration((float64(rate.Limit) - b.tokens) * float64(perToken)))
+	return true, 0, nil
+}
+
+// Len is the number of buckets currently held.
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.buckets)
+}
+
+// Close stops the background sweep.
+func (m *Memory) Close() {
+	m.once.Do(func() { close(m.stop) })
+}
+
+// sweep must be called with mu held.
+func (m *Memory) sweep() {
+	now := m.now()
+	for key, b := range m.buckets {
+		if !now.Before(b.full) {
+			delete(m.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+	"tusk/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// clock is a fake time that only moves when told to.
+type clock struct {
+	nanos atomic.Int64
+}
+
+func newClock(m *Memory) *clock {
+	c := &clock{}
+	c.nanos.Store(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC).UnixNano())
+	m.now = c.now
+	return c
+}
+
+func (c *clock) now() time.Time          { return time.Unix(0, c.nanos.Load()).UTC() }
+func (c *clock) advance(d time.Duration) { c.nanos.Add(int64(d)) }
+
+func TestMemoryConcurrentHammering(t *testing.T) {
+	m := NewMemory(time.Hour, 0)
+	defer m.Close()
+	clock := newClock(m)
+	rate := Rate{Limit: 100, Period: time.Hour}
+
+	var allowed atomic.Int64
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				ok, _, err := m.Allow(context.Background(), "login:10.0.0.1", rate)
+				if err != nil {
+					t.Error(err)
+				}
+				if ok {
+					allowed.Add(1)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if got := allowed.Load(); got != 100 {
+		t.Fatalf("allowed %d of 1000, want exactly 100", got)
+	}
+
+	ok, wait, _ := m.Allow(context.Background(), "login:10.0.0.1", rate)
+	if ok || wait != 36*time.Second {
+		t.Errorf("Allow = %v, wait %v; want refused until the next token in 36s", ok, wait)
+	}
+	if ok, _, _ := m.Allow(context.Background(), "login:10.0.0.2", rate); !ok {
+		t.Error("another client was refused")
+	}
+
+	clock.advance(36 * time.Second)
+	if ok, _, _ := m.Allow(context.Background(), "login:10.0.0.1", rate); !ok {
+		t.Error("refused after a token was refilled")
+	}
+	if ok, _, _ := m.Allow(context.Background(), "login:10.0.0.1", rate); ok {
+		t.Error("allowed more than the refilled token")
+	}
+}
+
+func TestMemoryStaysBoundedForUniqueIPs(t *testing.T) {
+	const maxKeys = 100
+	m := NewMemory(time.Hour, maxKeys)
+	defer m.Close()
+	clock := newClock(m)
+	// a bucket that gave one token away is full again a millisecond later
+	rate := Rate{Limit: 10, Period: 10 * time.Millisecond}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 500; j++ {
+				clock.advance(time.Millisecond)
+				ip := strconv.Itoa(i) + "." + strconv.Itoa(j)
+				if ok, _, _ := m.Allow(context.Background(), "login:"+ip, rate); !ok {
+					t.Errorf("first request of %s refused", ip)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if got := m.Len(); got > maxKeys {
+		t.Errorf("holding %d buckets for 10000 clients, want at most %d", got, maxKeys)
+	}
+}
+
+func TestMemorySweepForgetsRefilledBuckets(t *testing.T) {
+	m := NewMemory(time.Millisecond, 0)
+	defer m.Close()
+	clock := newClock(m)
+	rate := Rate{Limit: 5, Period: time.Minute}
+
+	for i := 0; i < 1000; i++ {
+		m.Allow(context.Background(), "login:"+strconv.Itoa(i), rate)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if got := m.Len(); got != 1000 {
+		t.Fatalf("swept to %d buckets before any refilled", got)
+	}
+
+	clock.advance(time.Minute)
+	deadline := time.Now().Add(2 * time.Second)
+	for m.Len() > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if got := m.Len(); got != 0 {
+		t.Errorf("%d buckets left after they all refilled", got)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	serve := func(t *testing.T, trustedProxies []string, rate Rate) func(forwardedFor string) *httptest.ResponseRecorder {
+		m := NewMemory(time.Hour, 0)
+		t.Cleanup(m.Close)
+
+		router := gin.New()
+		if err := router.SetTrustedProxies(trustedProxies); err != nil {
+			t.Fatal(err)
+		}
+		router.Use(apierror.Middleware())
+		router.POST("/login", Middleware(m, "login", rate), func(c *gin.Context) { c.Status(http.StatusOK) })
+		return func(forwardedFor string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodPost, "/login", nil)
+			req.RemoteAddr = "10.0.0.1:1234"
+			if forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", forwardedFor)
+			}
+			res := httptest.NewRecorder()
+			router.ServeHTTP(res, req)
+			return res
+		}
+	}
+	rate := Rate{Limit: 2, Period: time.Minute}
+
+	t.Run("429 with Retry-After", func(t *testing.T) {
+		login := serve(t, nil, rate)
+		login("")
+		login("")
+		res := login("")
+		if res.Code != http.StatusTooManyRequests || res.Header().Get("Retry-After") != "30" {
+			t.Errorf("status = %d, Retry-After %q; want 429 after 30s", res.Code, res.Header().Get("Retry-After"))
+		}
+	})
+
+	t.Run("X-Forwarded-For ignored without trusted proxies", func(t *testing.T) {
+		login := serve(t, nil, rate)
+		login("203.0.113.1")
+		login("203.0.113.2")
+		if res := login("203.0.113.3"); res.Code != http.StatusTooManyRequests {
+			t.Errorf("status = %d, want the proxy's own bucket to be used up", res.Code)
+		}
+	})
+
+	t.Run("X-Forwarded-For from a trusted proxy", func(t *testing.T) {
+		login := serve(t, []string{"10.0.0.0/8"}, rate)
+		login("203.0.113.1")
+		login("203.0.113.1")
+		if res := login("203.0.113.2"); res.Code != http.StatusOK {
+			t.Errorf("status = %d, want each forwarded client limited on its own", res.Code)
+		}
+		if res := login("203.0.113.1"); res.Code != http.StatusTooManyRequests {
+			t.Errorf("status = %d, want 429", res.Code)
+		}
+	})
+
+	t.Run("zero limit disables it", func(t *testing.T) {
+		login := serve(t, nil, Rate{})
+		for i := 0; i < 20; i++ {
+			if res := login(""); res.Code != http.StatusOK {
+				t.Fatalf("request %d: status = %d", i, res.Code)
+			}
+		}
+	})
+}
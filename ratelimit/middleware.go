@@ -0,0 +1,36 @@
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"tusk/apierror"
+	"tusk/middlewares"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Middleware limits each client IP to rate on the routes it's attached to.
+// name keeps the buckets of different routes apart. The IP comes from
+// c.ClientIP, which only honours X-Forwarded-For from the router's trusted
+// proxies. If the limiter fails the request is let through.
+func Middleware(limiter Limiter, name string, rate Rate) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		allowed, wait, err := limiter.Allow(c.Request.Context(), name+":"+c.ClientIP(), rate)
+		if err != nil {
+			middlewares.Logger(c).Warn("rate limiter unavailable", "limit", name, "error", err)
+			c.Next()
+			return
+		}
+		if allowed {
+			c.Next()
+			return
+		}
+
+		retryAfter := int(math.Ceil(wait.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.Error(apierror.New(http.StatusTooManyRequests, apierror.CodeRateLimited, "Too many requests, please try again later").
+			WithDetails(gin.H{"retryAfterSeconds": retryAfter}))
+		c.Abort()
+	}
+}
@@ -0,0 +1,8 @@
+package reminders
+
+import "time"
+
+// SetClock replaces the worker's clock in tests.
+func (w *Worker) SetClock(now func() time.Time) {
+	w.now = now
+}
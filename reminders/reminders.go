@@ -0,0 +1,151 @@
+// Package reminders reminds assignees of their due dates: once when a task
+// is about to be due and once when it has just become overdue.
+package reminders
+
+import (
+	"context"
+	"log"
+	"time"
+	"tusk/config"
+	"tusk/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// sendTimeout bounds one notification, which is sent even while shutting
+// down once its reminder is claimed.
+const sendTimeout = 30 * time.Second
+
+// Notify delivers one reminder of the given kind to the task's assignee.
+type Notify func(ctx context.Context, task models.Task, kind string)
+
+// Result counts the reminders one pass sent.
+type Result struct {
+	DueSoon int `json:"dueSoon"`
+	Overdue int `json:"overdue"`
+}
+
+// Worker runs reminder passes. Each reminder is claimed by inserting its
+// task_reminders row before it is sent, so instances running passes at
+// the same time, or a pass after a restart, never send it twice.
+type Worker struct {
+	db     *gorm.DB
+	config config.ReminderConfig
+	notify Notify
+	now    func() time.Time
+	done   chan struct{}
+}
+
+// New returns a worker; Start runs it on the configured interval.
+func New(db *gorm.DB, cfg config.ReminderConfig, notify Notify) *Worker {
+	return &Worker{
+		db:     db,
+		config: cfg,
+		notify: notify,
+		now:    func() time.Time { return time.Now().UTC() },
+		done:   make(chan struct{}),
+	}
+}
+
+// Start runs a pass every Interval until ctx is cancelled; Wait returns
+// once the pass in progress has finished. With Interval 0 it does nothing.
+func (w *Worker) Start(ctx context.Context) {
+	if w.config.Interval <= 0 {
+		close(w.done)
+		return
+	}
+
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(w.config.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				result, err := w.Run(ctx)
+				if err != nil && ctx.Err() == nil {
+					log.Printf("⚠️ Reminder pass failed: %v", err)
+				}
+				if result.DueSoon+result.Overdue > 0 {
+					log.Printf("⏰ Sent %d due soon and %d overdue reminders", result.DueSoon, result.Overdue)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// Wait blocks until the worker started by Start has stopped.
+func (w *Worker) Wait() {
+	<-w.done
+}
+
+// Run makes one pass: open tasks due within DueSoon, and tasks that became
+// overdue less than OverdueWindow ago, whose assignee wasn't reminded of
+// that due date yet. Older overdue tasks are left alone so that turning
+// reminders on doesn't flood everyone about long forgotten work.
+func (w *Worker) Run(ctx context.Context) (Result, error) {
+	var result Result
+	now := w.now()
+
+	sent, err := w.remind(ctx, models.ReminderDueSoon, now, now.Add(w.config.DueSoon), now)
+	result.DueSoon = sent
+	if err != nil {
+		return result, err
+	}
+	sent, err = w.remind(ctx, models.ReminderOverdue, now.Add(-w.config.OverdueWindow), now, now)
+	result.Overdue = sent
+	return result, err
+}
+
+// remind sends kind for the tasks due in (from, to], loading them
+// BatchSize at a time in id order.
+func (w *Worker) remind(ctx context.Context, kind string, from, to, now time.Time) (int, error) {
+	sent, lastId := 0, 0
+	for {
+		tasks := []models.Task{}
+		errDB := w.db.WithContext(ctx).
+			Joins("JOIN users ON users.id = tasks.user_id AND users.is_active = ? AND users.deleted_at IS NULL", true).
+			Where("tasks.id > ? AND tasks.status <> ?", lastId, models.StatusApproved).
+			Where("tasks.due_date > ? AND tasks.due_date <= ?", from, to).
+			Where("NOT EXISTS (SELECT 1 FROM task_reminders WHERE task_reminders.task_id = tasks.id AND task_reminders.kind = ? AND task_reminders.due_date = tasks.due_date)", kind).
+			Order("tasks.id ASC").
+			Limit(w.config.BatchSize).
+			Find(&tasks).Error
+		if errDB != nil {
+			return sent, errDB
+		}
+
+		for _, task := range tasks {
+			if err := ctx.Err(); err != nil {
+				return sent, err
+			}
+			claimed, err := w.claim(ctx, task, kind, now)
+			if err != nil {
+				return sent, err
+			}
+			if !claimed {
+				continue // another instance got it first
+			}
+			// the claim is in, so send it even if ctx is cancelled meanwhile
+			sendCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sendTimeout)
+			w.notify(sendCtx, task, kind)
+			cancel()
+			sent++
+		}
+
+		if len(tasks) < w.config.BatchSize {
+			return sent, nil
+		}
+		lastId = tasks[len(tasks)-1].Id
+	}
+}
+
+// claim inserts the reminder row and reports whether this call did.
+func (w *Worker) claim(ctx context.Context, task models.Task, kind string, now time.Time) (bool, error) {
+	reminder := models.TaskReminder{TaskId: task.Id, Kind: kind, DueDate: *task.DueDate, SentAt: now}
+	result := w.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&reminder)
+	return result.RowsAffected == 1, result.Error
+}
@@ -0,0 +1,117 @@
+package reminders_test
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+	"tusk/config"
+	"tusk/models"
+	"tusk/reminders"
+	"tusk/testutil"
+)
+
+// recorder collects the reminders a worker sends as "kind task-title".
+type recorder struct {
+	mu   sync.Mutex
+	sent []string
+}
+
+func (r *recorder) notify(_ context.Context, task models.Task, kind string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sent = append(r.sent, kind+" "+task.Title)
+}
+
+func (r *recorder) take() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sent := r.sent
+	r.sent = nil
+	sort.Strings(sent)
+	return sent
+}
+
+func TestRun(t *testing.T) {
+	db := testutil.DB(t)
+	employee := testutil.User(t, db, models.RoleEmployee)
+	inactive := testutil.User(t, db, models.RoleEmployee)
+	db.Model(&inactive).Update("is_active", false)
+
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	task := func(title string, userId int, due time.Time, status string) {
+		t.Helper()
+
+		task := models.Task{UserId: userId, Title: title, Status: status, DueDate: &due}
+		if err := db.Create(&task).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	task("due soon", employee.Id, now.Add(2*time.Hour), models.StatusInProgress)
+	task("due later", employee.Id, now.Add(48*time.Hour), models.StatusQueue)
+	task("just overdue", employee.Id, now.Add(-time.Hour), models.StatusReview)
+	task("long overdue", employee.Id, now.Add(-72*time.Hour), models.StatusQueue)
+	task("approved", employee.Id, now.Add(time.Hour), models.StatusApproved)
+	task("inactive assignee", inactive.Id, now.Add(time.Hour), models.StatusQueue)
+
+	// a batch of one makes every task its own page
+	cfg := config.ReminderConfig{Interval: time.Minute, DueSoon: 24 * time.Hour, OverdueWindow: 24 * time.Hour, BatchSize: 1}
+	sent := &recorder{}
+	worker := reminders.New(db, cfg, sent.notify)
+	clock := now
+	worker.SetClock(func() time.Time { return clock })
+
+	run := func(t *testing.T, want []string) {
+		t.Helper()
+
+		result, err := worker.Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := sent.take()
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("sent %v, want %v", got, want)
+		}
+		if result.DueSoon+result.Overdue != len(want) {
+			t.Errorf("result = %+v, want %d reminders", result, len(want))
+		}
+	}
+
+	t.Run("due soon and just overdue", func(t *testing.T) {
+		run(t, []string{"due_soon due soon", "overdue just overdue"})
+	})
+
+	t.Run("not sent twice", func(t *testing.T) {
+		run(t, nil)
+	})
+
+	t.Run("once overdue", func(t *testing.T) {
+		clock = now.Add(3 * time.Hour)
+		run(t, []string{"overdue due soon"})
+	})
+
+	t.Run("a new due date is reminded again", func(t *testing.T) {
+		db.Model(&models.Task{}).Where("title = ?", "due soon").Update("due_date", clock.Add(time.Hour))
+		run(t, []string{"due_soon due soon"})
+	})
+}
+
+func TestStartWithoutInterval(t *testing.T) {
+	worker := reminders.New(nil, config.ReminderConfig{}, func(context.Context, models.Task, string) {
+		t.Error("reminder sent with Interval 0")
+	})
+	worker.Start(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		worker.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Wait blocked with Interval 0")
+	}
+}
@@ -0,0 +1,223 @@
+// Package routes mounts the HTTP API on a gin engine.
+package routes
+
+import (
+	"strings"
+	"tusk/config"
+	"tusk/controllers"
+	"tusk/idempotency"
+	"tusk/middlewares"
+	"tusk/models"
+	"tusk/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Prefix is where the current API version is mounted.
+const Prefix = "/api/v1"
+
+// untimed are the routes that may run longer than the query timeout: the
+// event stream, the exports, which stream rows after the headers are sent,
+// and the import, which hashes a password for every row.
+var untimed = []string{"/events", "/tasks/export", "/users/export", "/users/import"}
+
+// Untimed returns the untimed routes under both mounts, for
+// middlewares.QueryTimeout to skip.
+func Untimed() []string {
+	paths := make([]string, 0, 2*len(untimed))
+	for _, path := range untimed {
+		paths = append(paths, path, Prefix+path)
+	}
+	return paths
+}
+
+// Dependencies is everything the routes need. Setup only wires what it is
+// given, so a router can be built with any controllers and limiter.
+type Dependencies struct {
+	Users       *controllers.UserController
+	Tasks       *controllers.TaskController
+	Attachments *controllers.AttachmentController
+	Comments    *controllers.CommentController
+	Tags        *controllers.TagController
+	Departments *controllers.DepartmentController
+	Subtasks    *controllers.SubtaskController
+	Activities  *controllers.ActivityController
+	Events      *controllers.EventController
+	Dashboard   *controllers.DashboardController
+	Reports     *controllers.ReportController
+	Admin       *controllers.AdminController
+
+	JWTSecret   string
+	TokenChecks []middlewares.TokenCheck // e.g. the denylist and token version
+	Limiter     ratelimit.Limiter
+	Idempotency *idempotency.Store // Idempotency-Key support on the create routes
+	RateLimit   config.RateLimitConfig
+	LegacyDir   string // attachments uploaded before attachment records existed
+}
+
+// Setup mounts the API under Prefix. The same routes stay reachable
+// without the prefix for one more release; those responses carry a
+// Deprecation header pointing at the versioned path.
+func Setup(r *gin.Engine, deps Dependencies) {
+	mount(r.Group(Prefix), deps)
+	mount(r.Group("", deprecated()), deps)
+}
+
+// deprecated marks responses of the unversioned aliases.
+func deprecated() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Deprecation", "true")
+		c.Header("Link", "<"+Prefix+c.Request.URL.Path+`>; rel="successor-version"`)
+		c.Next()
+	}
+}
+
+// chain holds the middleware shared by the route groups of one mount.
+type chain struct {
+	auth      gin.HandlerFunc
+	adminOnly gin.HandlerFunc
+	managers  gin.HandlerFunc // Admins and Managers; Managers are scoped to their department
+	limit     func(name string, rate ratelimit.Rate) gin.HandlerFunc
+}
+
+func mount(g *gin.RouterGroup, deps Dependencies) {
+	// a token issued while the password must be changed is good for nothing else
+	base := strings.TrimSuffix(g.BasePath(), "/")
+	passwordChange := middlewares.PasswordChangeCheck(base+"/users/password", base+"/auth/logout", base+"/auth/logout-all")
+	checks := append(append([]middlewares.TokenCheck{}, deps.TokenChecks...), passwordChange)
+
+	mw := chain{
+		auth:      middlewares.JWTAuth(deps.JWTSecret, checks...),
+		adminOnly: middlewares.RequireRole(models.RoleAdmin),
+		managers:  middlewares.RequireRole(models.RoleAdmin, models.RoleManager),
+		limit: func(name string, rate ratelimit.Rate) gin.HandlerFunc {
+			return ratelimit.Middleware(deps.Limiter, name, rate)
+		},
+	}
+
+	authRoutes(g, deps, mw)
+	userRoutes(g, deps, mw)
+	taskRoutes(g, deps, mw)
+	taskRequestRoutes(g, deps, mw)
+	otherRoutes(g, deps, mw)
+}
+
+func authRoutes(g *gin.RouterGroup, deps Dependencies, mw chain) {
+	g.POST("/users/login", mw.limit("login", deps.RateLimit.Login), deps.Users.Login)
+
+	auth := g.Group("/auth")
+	auth.POST("/refresh", deps.Users.Refresh)
+	auth.POST("/forgot-password", mw.limit("forgot-password", deps.RateLimit.ForgotPassword), deps.Users.ForgotPassword)
+	auth.POST("/reset-password", deps.Users.ResetPassword)
+	auth.GET("/verify", deps.Users.VerifyEmail)
+	auth.POST("/resend-verification", mw.limit("resend-verification", deps.RateLimit.ResendVerify), deps.Users.ResendVerification)
+	auth.POST("/logout", mw.auth, deps.Users.Logout)
+	auth.POST("/logout-all", mw.auth, deps.Users.LogoutAll)
+}
+
+func userRoutes(g *gin.RouterGroup, deps Dependencies, mw chain) {
+	users := g.Group("/users", mw.auth)
+	users.PUT("/password", deps.Users.ChangePassword)
+	users.PUT("/me", deps.Users.UpdateMe)
+	users.PUT("/me/device-token", deps.Users.SetDeviceToken)
+	users.POST("/me/avatar", deps.Users.UploadAvatar)
+	users.DELETE("/me/avatar", deps.Users.DeleteAvatar)
+	users.GET("/:id", deps.Users.GetByID)
+	users.GET("/:id/stats", deps.Dashboard.UserStats)
+
+	users.GET("/Employee", mw.managers, deps.Users.GetEmployee)
+
+	admin := users.Group("", mw.adminOnly)
+	admin.POST("", mw.limit("create-account", deps.RateLimit.CreateAccount), deps.Idempotency.Middleware("create-user"), deps.Users.CreateAccount)
+	admin.PUT("/:id", deps.Users.Update)
+	admin.PATCH("/:id/role", deps.Users.ChangeRole)
+	admin.GET("/deleted", deps.Users.GetDeleted)
+	admin.DELETE("/:id", deps.Users.Delete)
+	admin.POST("/:id/restore", deps.Users.Restore)
+	admin.POST("/:id/unlock", deps.Users.Unlock)
+	admin.POST("/:id/deactivate", deps.Users.Deactivate)
+	admin.POST("/:id/activate", deps.Users.Activate)
+	admin.GET("/export", deps.Users.Export)
+	admin.POST("/import", deps.Users.Import)
+}
+
+func taskRoutes(g *gin.RouterGroup, deps Dependencies, mw chain) {
+	tasks := g.Group("/tasks", mw.auth)
+	tasks.GET("", deps.Tasks.GetAll)
+	tasks.GET("/overdue", deps.Tasks.Overdue)
+	tasks.GET("/search", deps.Tasks.Search)
+	tasks.PATCH("/:id/submit", deps.Tasks.Submit)
+	tasks.POST("/:id/submit", deps.Tasks.SubmitEvidence)
+	tasks.PATCH("/:id/fix", deps.Tasks.Fix)
+	tasks.PATCH("/:id/status", deps.Tasks.UpdateStatus)
+	tasks.GET("/:id", deps.Tasks.GetByID)
+	tasks.POST("/:id/attachments", deps.Attachments.Upload)
+	tasks.GET("/:id/attachments", deps.Attachments.List)
+	tasks.POST("/:id/comments", deps.Comments.Create)
+	tasks.GET("/:id/comments", deps.Comments.List)
+	tasks.GET("/:id/activities", deps.Activities.List)
+	tasks.GET("/:id/subtasks", deps.Subtasks.List)
+	tasks.POST("/:id/subtasks", deps.Subtasks.Create)
+	tasks.PUT("/:id/subtasks/order", deps.Subtasks.Reorder)
+	tasks.PATCH("/:id/subtasks/:subtaskId", deps.Subtasks.Update)
+	tasks.POST("/:id/subtasks/:subtaskId/toggle", deps.Subtasks.Toggle)
+	tasks.DELETE("/:id/subtasks/:subtaskId", deps.Subtasks.Delete)
+	tasks.GET("/review/asc", deps.Tasks.NeedToBeReview)
+	tasks.GET("/progress/:userId", deps.Tasks.ProgressTasks)
+	tasks.GET("/stat/:userId", deps.Tasks.Statistic)
+	tasks.GET("/user/:userId/:status", deps.Tasks.FindByUserAndStatus)
+
+	admin := tasks.Group("", mw.adminOnly)
+	admin.POST("", deps.Idempotency.Middleware("create-task"), deps.Tasks.Create)
+	admin.PUT("/:id", deps.Tasks.Update)
+	admin.DELETE("/:id", deps.Tasks.Delete)
+	admin.GET("/export", deps.Tasks.Export)
+	admin.POST("/bulk", deps.Tasks.Bulk)
+	admin.POST("/:id/reject", deps.Tasks.RejectSubmission)
+	admin.PATCH("/:id/reject", deps.Tasks.Reject)
+	admin.PATCH("/:id/approve", deps.Tasks.Approve)
+	admin.PATCH("/:id/assign", deps.Tasks.Assign)
+}
+
+// taskRequestRoutes are Employees proposing tasks and Admins reviewing them.
+func taskRequestRoutes(g *gin.RouterGroup, deps Dependencies, mw chain) {
+	requests := g.Group("/task-requests", mw.auth)
+
+	employees := requests.Group("", middlewares.RequireRole(models.RoleEmployee))
+	employees.POST("", deps.Tasks.ProposeTask)
+	employees.GET("", deps.Tasks.MyTaskRequests)
+	employees.POST("/:id/cancel", deps.Tasks.CancelTaskRequest)
+
+	admin := requests.Group("", mw.adminOnly)
+	admin.GET("/pending", deps.Tasks.PendingTaskRequests)
+	admin.POST("/:id/approve", deps.Tasks.ApproveTaskRequest)
+	admin.POST("/:id/reject", deps.Tasks.RejectTaskRequest)
+}
+
+func otherRoutes(g *gin.RouterGroup, deps Dependencies, mw chain) {
+	// download links go through auth, older files under LegacyDir don't
+	g.GET("/attachments/*path", deps.Attachments.Serve(deps.LegacyDir, mw.auth))
+	// public so <img> tags work without a token
+	g.GET("/users/:id/avatar", deps.Users.Avatar)
+
+	authed := g.Group("", mw.auth)
+	authed.GET("/events", deps.Events.Stream)
+	authed.GET("/stats", deps.Tasks.Summary)
+	authed.DELETE("/attachments/:id", deps.Attachments.Delete)
+	authed.DELETE("/comments/:id", deps.Comments.Delete)
+	authed.GET("/tags", deps.Tags.List)
+	authed.GET("/dashboard/stats", mw.managers, deps.Dashboard.Stats)
+	authed.GET("/stats/forecast", mw.managers, deps.Tasks.Forecast)
+
+	admin := authed.Group("", mw.adminOnly)
+	admin.GET("/reports/tasks", deps.Reports.MonthlyTasks)
+	admin.GET("/admin/metrics-snapshot", deps.Admin.MetricsSnapshot)
+	admin.POST("/admin/reminders/run", deps.Admin.RunReminders)
+	admin.POST("/tags", deps.Tags.Create)
+	admin.DELETE("/tags/:id", deps.Tags.Delete)
+	admin.GET("/departments", deps.Departments.List)
+	admin.POST("/departments", deps.Departments.Create)
+	admin.GET("/departments/:id", deps.Departments.GetByID)
+	admin.PUT("/departments/:id", deps.Departments.Update)
+	admin.DELETE("/departments/:id", deps.Departments.Delete)
+}
@@ -0,0 +1,321 @@
+package routes_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+	"tusk/apierror"
+	"tusk/controllers"
+	"tusk/middlewares"
+	"tusk/models"
+	"tusk/ratelimit"
+	"tusk/routes"
+
+	"github.com/gin-gonic/gin"
+)
+
+const secret = "test-secret"
+
+// reached is the status of a request that got through every middleware
+// and into a handler, which panics on its empty controller.
+const reached = 599
+
+// recordingLimiter allows everything and remembers the limits asked for.
+type recordingLimiter struct {
+	names []string
+}
+
+func (l *recordingLimiter) Allow(_ context.Context, key string, _ ratelimit.Rate) (bool, time.Duration, error) {
+	name, _, _ := strings.Cut(key, ":")
+	l.names = append(l.names, name)
+	return true, 0, nil
+}
+
+func newRouter(limiter ratelimit.Limiter) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(gin.CustomRecoveryWithWriter(io.Discard, func(c *gin.Context, _ interface{}) { c.AbortWithStatus(reached) }))
+	router.Use(apierror.Middleware())
+	routes.Setup(router, routes.Dependencies{
+		Users:       &controllers.UserController{},
+		Tasks:       &controllers.TaskController{},
+		Attachments: &controllers.AttachmentController{},
+		Comments:    &controllers.CommentController{},
+		Tags:        &controllers.TagController{},
+		Departments: &controllers.DepartmentController{},
+		Subtasks:    &controllers.SubtaskController{},
+		Activities:  &controllers.ActivityController{},
+		Events:      &controllers.EventController{},
+		Dashboard:   &controllers.DashboardController{},
+		Reports:     &controllers.ReportController{},
+		Admin:       &controllers.AdminController{},
+		JWTSecret:   secret,
+		Limiter:     limiter,
+	})
+	return router
+}
+
+// Who may call a route, by role; public routes don't need a token at all.
+var (
+	public    []string
+	anyone    = []string{models.RoleAdmin, models.RoleEmployee, models.RoleManager}
+	admins    = []string{models.RoleAdmin}
+	managers  = []string{models.RoleAdmin, models.RoleManager}
+	employees = []string{models.RoleEmployee}
+)
+
+type route struct {
+	method, path, handler string
+	roles                 []string
+	limit                 string // the rate limit name, if any
+	idempotent            bool   // takes an Idempotency-Key
+}
+
+var routeTable = []route{
+	{"POST", "/users/login", "UserController.Login", public, "login", false},
+	{"POST", "/auth/refresh", "UserController.Refresh", public, "", false},
+	{"POST", "/auth/forgot-password", "UserController.ForgotPassword", public, "forgot-password", false},
+	{"POST", "/auth/reset-password", "UserController.ResetPassword", public, "", false},
+	{"GET", "/auth/verify", "UserController.VerifyEmail", public, "", false},
+	{"POST", "/auth/resend-verification", "UserController.ResendVerification", public, "resend-verification", false},
+	{"POST", "/auth/logout", "UserController.Logout", anyone, "", false},
+	{"POST", "/auth/logout-all", "UserController.LogoutAll", anyone, "", false},
+
+	{"PUT", "/users/password", "UserController.ChangePassword", anyone, "", false},
+	{"PUT", "/users/me", "UserController.UpdateMe", anyone, "", false},
+	{"PUT", "/users/me/device-token", "UserController.SetDeviceToken", anyone, "", false},
+	{"POST", "/users/me/avatar", "UserController.UploadAvatar", anyone, "", false},
+	{"DELETE", "/users/me/avatar", "UserController.DeleteAvatar", anyone, "", false},
+	{"GET", "/users/:id", "UserController.GetByID", anyone, "", false},
+	{"GET", "/users/:id/stats", "DashboardController.UserStats", anyone, "", false},
+	{"GET", "/users/:id/avatar", "UserController.Avatar", public, "", false},
+	{"GET", "/users/Employee", "UserController.GetEmployee", managers, "", false},
+	{"POST", "/users", "UserController.CreateAccount", admins, "create-account", true},
+	{"PUT", "/users/:id", "UserController.Update", admins, "", false},
+	{"PATCH", "/users/:id/role", "UserController.ChangeRole", admins, "", false},
+	{"GET", "/users/deleted", "UserController.GetDeleted", admins, "", false},
+	{"DELETE", "/users/:id", "UserController.Delete", admins, "", false},
+	{"POST", "/users/:id/restore", "UserController.Restore", admins, "", false},
+	{"POST", "/users/:id/unlock", "UserController.Unlock", admins, "", false},
+	{"POST", "/users/:id/deactivate", "UserController.Deactivate", admins, "", false},
+	{"POST", "/users/:id/activate", "UserController.Activate", admins, "", false},
+	{"GET", "/users/export", "UserController.Export", admins, "", false},
+	{"POST", "/users/import", "UserController.Import", admins, "", false},
+
+	{"GET", "/tasks", "TaskController.GetAll", anyone, "", false},
+	{"GET", "/tasks/overdue", "TaskController.Overdue", anyone, "", false},
+	{"GET", "/tasks/search", "TaskController.Search", anyone, "", false},
+	{"PATCH", "/tasks/:id/submit", "TaskController.Submit", anyone, "", false},
+	{"POST", "/tasks/:id/submit", "TaskController.SubmitEvidence", anyone, "", false},
+	{"PATCH", "/tasks/:id/fix", "TaskController.Fix", anyone, "", false},
+	{"PATCH", "/tasks/:id/status", "TaskController.UpdateStatus", anyone, "", false},
+	{"GET", "/tasks/:id", "TaskController.GetByID", anyone, "", false},
+	{"POST", "/tasks/:id/attachments", "AttachmentController.Upload", anyone, "", false},
+	{"GET", "/tasks/:id/attachments", "AttachmentController.List", anyone, "", false},
+	{"POST", "/tasks/:id/comments", "CommentController.Create", anyone, "", false},
+	{"GET", "/tasks/:id/comments", "CommentController.List", anyone, "", false},
+	{"GET", "/tasks/:id/activities", "ActivityController.List", anyone, "", false},
+	{"GET", "/tasks/:id/subtasks", "SubtaskController.List", anyone, "", false},
+	{"POST", "/tasks/:id/subtasks", "SubtaskController.Create", anyone, "", false},
+	{"PUT", "/tasks/:id/subtasks/order", "SubtaskController.Reorder", anyone, "", false},
+	{"PATCH", "/tasks/:id/subtasks/:subtaskId", "SubtaskController.Update", anyone, "", false},
+	{"POST", "/tasks/:id/subtasks/:subtaskId/toggle", "SubtaskController.Toggle", anyone, "", false},
+	{"DELETE", "/tasks/:id/subtasks/:subtaskId", "SubtaskController.Delete", anyone, "", false},
+	{"GET", "/tasks/review/asc", "TaskController.NeedToBeReview", anyone, "", false},
+	{"GET", "/tasks/progress/:userId", "TaskController.ProgressTasks", anyone, "", false},
+	{"GET", "/tasks/stat/:userId", "TaskController.Statistic", anyone, "", false},
+	{"GET", "/tasks/user/:userId/:status", "TaskController.FindByUserAndStatus", anyone, "", false},
+	{"POST", "/tasks", "TaskController.Create", admins, "", true},
+	{"PUT", "/tasks/:id", "TaskController.Update", admins, "", false},
+	{"DELETE", "/tasks/:id", "TaskController.Delete", admins, "", false},
+	{"GET", "/tasks/export", "TaskController.Export", admins, "", false},
+	{"POST", "/tasks/bulk", "TaskController.Bulk", admins, "", false},
+	{"POST", "/tasks/:id/reject", "TaskController.RejectSubmission", admins, "", false},
+	{"PATCH", "/tasks/:id/reject", "TaskController.Reject", admins, "", false},
+	{"PATCH", "/tasks/:id/approve", "TaskController.Approve", admins, "", false},
+	{"PATCH", "/tasks/:id/assign", "TaskController.Assign", admins, "", false},
+
+	{"POST", "/task-requests", "TaskController.ProposeTask", employees, "", false},
+	{"GET", "/task-requests", "TaskController.MyTaskRequests", employees, "", false},
+	{"POST", "/task-requests/:id/cancel", "TaskController.CancelTaskRequest", employees, "", false},
+	{"GET", "/task-requests/pending", "TaskController.PendingTaskRequests", admins, "", false},
+	{"POST", "/task-requests/:id/approve", "TaskController.ApproveTaskRequest", admins, "", false},
+	{"POST", "/task-requests/:id/reject", "TaskController.RejectTaskRequest", admins, "", false},
+
+	{"GET", "/attachments/*path", "AttachmentController.Serve", anyone, "", false}, // probed as a download link
+	{"DELETE", "/attachments/:id", "AttachmentController.Delete", anyone, "", false},
+	{"DELETE", "/comments/:id", "CommentController.Delete", anyone, "", false},
+	{"GET", "/events", "EventController.Stream", anyone, "", false},
+	{"GET", "/stats", "TaskController.Summary", anyone, "", false},
+	{"GET", "/tags", "TagController.List", anyone, "", false},
+	{"GET", "/dashboard/stats", "DashboardController.Stats", managers, "", false},
+	{"GET", "/stats/forecast", "TaskController.Forecast", managers, "", false},
+	{"GET", "/reports/tasks", "ReportController.MonthlyTasks", admins, "", false},
+	{"GET", "/admin/metrics-snapshot", "AdminController.MetricsSnapshot", admins, "", false},
+	{"POST", "/admin/reminders/run", "AdminController.RunReminders", admins, "", false},
+	{"POST", "/tags", "TagController.Create", admins, "", false},
+	{"DELETE", "/tags/:id", "TagController.Delete", admins, "", false},
+	{"GET", "/departments", "DepartmentController.List", admins, "", false},
+	{"POST", "/departments", "DepartmentController.Create", admins, "", false},
+	{"GET", "/departments/:id", "DepartmentController.GetByID", admins, "", false},
+	{"PUT", "/departments/:id", "DepartmentController.Update", admins, "", false},
+	{"DELETE", "/departments/:id", "DepartmentController.Delete", admins, "", false},
+}
+
+// handlerName shortens gin's "tusk/controllers.(*UserController).Login-fm"
+// to "UserController.Login".
+func handlerName(name string) string {
+	name = name[strings.LastIndex(name, "/")+1:]
+	name = strings.TrimPrefix(name, "controllers.(*")
+	name = strings.Replace(name, ")", "", 1)
+	name = strings.TrimSuffix(name, "-fm")
+	return strings.TrimSuffix(name, ".func1")
+}
+
+func TestRouteTable(t *testing.T) {
+	router := newRouter(&recordingLimiter{})
+
+	for _, mount := range []string{routes.Prefix, ""} {
+		got := []string{}
+		for _, info := range router.Routes() {
+			path, versioned := strings.CutPrefix(info.Path, routes.Prefix)
+			if versioned == (mount != "") {
+				got = append(got, info.Method+" "+path+" "+handlerName(info.Handler))
+			}
+		}
+		want := []string{}
+		for _, r := range routeTable {
+			want = append(want, r.method+" "+r.path+" "+r.handler)
+		}
+		sort.Strings(got)
+		sort.Strings(want)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("routes under %q differ from the table\ngot  %q\nwant %q", mount, got, want)
+		}
+	}
+}
+
+// probePath fills in the parameters of path.
+func probePath(path string) string {
+	if path == "/attachments/*path" {
+		return "/attachments/1/download"
+	}
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") {
+			parts[i] = "1"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func TestRouteMiddleware(t *testing.T) {
+	limiter := &recordingLimiter{}
+	router := newRouter(limiter)
+	tokens := map[string]string{}
+	for _, role := range anyone {
+		token, err := middlewares.GenerateToken(secret, 1, role, 0, false, time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens[role] = token
+	}
+
+	for _, r := range routeTable {
+		t.Run(r.method+" "+r.path, func(t *testing.T) {
+			limiter.names = nil
+			probe := func(token string) *httptest.ResponseRecorder {
+				req := httptest.NewRequest(r.method, routes.Prefix+probePath(r.path), nil)
+				req.Header.Set("Idempotency-Key", strings.Repeat("k", 300))
+				if token != "" {
+					req.Header.Set("Authorization", "Bearer "+token)
+				}
+				ctx, cancel := context.WithTimeout(req.Context(), time.Second)
+				defer cancel()
+				res := httptest.NewRecorder()
+				router.ServeHTTP(res, req.WithContext(ctx))
+				return res
+			}
+
+			res := probe("")
+			needsToken := res.Code == http.StatusUnauthorized
+			if needsToken != (r.roles != nil) {
+				t.Errorf("without a token: status = %d, want a token required to be %v", res.Code, r.roles != nil)
+			}
+
+			allowed := []string{}
+			idempotent := false
+			for _, role := range anyone {
+				res := probe(tokens[role])
+				if res.Code == http.StatusForbidden && strings.Contains(res.Body.String(), "requiredRoles") {
+					continue
+				}
+				allowed = append(allowed, role)
+				idempotent = idempotent || strings.Contains(res.Body.String(), "Idempotency-Key must be at most")
+			}
+			if needsToken && !reflect.DeepEqual(allowed, r.roles) {
+				t.Errorf("allowed roles = %v, want %v", allowed, r.roles)
+			}
+			if idempotent != r.idempotent {
+				t.Errorf("takes an Idempotency-Key = %v, want %v", idempotent, r.idempotent)
+			}
+
+			limits := map[string]bool{}
+			for _, name := range limiter.names {
+				limits[name] = true
+			}
+			if r.limit == "" && len(limits) > 0 || r.limit != "" && (len(limits) != 1 || !limits[r.limit]) {
+				t.Errorf("rate limits = %v, want %q", limiter.names, r.limit)
+			}
+		})
+	}
+}
+
+func TestPasswordChangeTokenIsRestricted(t *testing.T) {
+	router := newRouter(&recordingLimiter{})
+	token, err := middlewares.GenerateToken(secret, 1, models.RoleAdmin, 0, true, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	allowed := map[string]bool{"PUT /users/password": true, "POST /auth/logout": true, "POST /auth/logout-all": true}
+	for _, mount := range []string{routes.Prefix, ""} {
+		for _, r := range routeTable {
+			if r.roles == nil || r.path == "/attachments/*path" {
+				continue
+			}
+			req := httptest.NewRequest(r.method, mount+probePath(r.path), nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			res := httptest.NewRecorder()
+			router.ServeHTTP(res, req)
+
+			restricted := res.Code == http.StatusForbidden && strings.Contains(res.Body.String(), "Password change required")
+			if restricted == allowed[r.method+" "+r.path] {
+				t.Errorf("%s %s%s: status = %d, want it restricted to be %v", r.method, mount, r.path, res.Code, !allowed[r.method+" "+r.path])
+			}
+		}
+	}
+}
+
+func TestLegacyRoutesAreDeprecated(t *testing.T) {
+	router := newRouter(&recordingLimiter{})
+	for _, path := range []string{"/tasks", routes.Prefix + "/tasks"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		res := httptest.NewRecorder()
+		router.ServeHTTP(res, req)
+
+		legacy := path == "/tasks"
+		if got := res.Header().Get("Deprecation") == "true"; got != legacy {
+			t.Errorf("%s: Deprecation header = %v, want %v", path, got, legacy)
+		}
+		if legacy && res.Header().Get("Link") != `<`+routes.Prefix+`/tasks>; rel="successor-version"` {
+			t.Errorf("%s: Link = %q", path, res.Header().Get("Link"))
+		}
+	}
+}
@@ -0,0 +1,164 @@
+// Package seed fills a development database with fake but realistic data.
+// The generators are deterministic for a given *rand.Rand, so they can also
+// build fixtures for an in-memory test database.
+package seed
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+	"tusk/models"
+)
+
+// EmailDomain is used for every generated account, which keeps them apart
+// from real ones.
+const EmailDomain = "seed.tusk.local"
+
+var firstNames = []string{
+	"Andi", "Budi", "Citra", "Dewi", "Eko", "Fajar", "Gita", "Hendra",
+	"Indah", "Joko", "Kartika", "Lukman", "Maya", "Nanda", "Putri", "Rizky",
+	"Sari", "Taufik", "Wulan", "Yusuf",
+}
+
+var lastNames = []string{
+	"Saputra", "Wijaya", "Pratama", "Lestari", "Santoso", "Hidayat",
+	"Kusuma", "Nugroho", "Rahmawati", "Siregar",
+}
+
+var taskVerbs = []string{"Review", "Update", "Fix", "Write", "Prepare", "Migrate", "Design", "Test"}
+
+var taskSubjects = []string{
+	"monthly sales report", "onboarding checklist", "invoice template",
+	"login page layout", "customer feedback survey", "inventory sheet",
+	"API documentation", "backup schedule", "payroll export", "landing page copy",
+}
+
+var commentBodies = []string{
+	"Sudah saya mulai, target selesai besok.",
+	"Ada data yang kurang, mohon dicek lagi.",
+	"Looks good, just fix the typo in the title.",
+	"Can we move the deadline by a day?",
+	"Sudah diperbaiki sesuai catatan.",
+	"Please attach the final version.",
+}
+
+// Email is the address of the i-th generated employee, starting at 1.
+func Email(i int) string {
+	return fmt.Sprintf("employee%03d@%s", i, EmailDomain)
+}
+
+// Employees returns n Employee accounts sharing passwordHash, with
+// deterministic names and emails.
+func Employees(n int, passwordHash string) []models.User {
+	users := make([]models.User, 0, n)
+	for i := 1; i <= n; i++ {
+		users = append(users, models.User{
+			Role:          models.RoleEmployee,
+			Name:          firstNames[(i-1)%len(firstNames)] + " " + lastNames[(i-1)/len(firstNames)%len(lastNames)],
+			Email:         Email(i),
+			Password:      passwordHash,
+			IsActive:      true,
+			EmailVerified: true,
+		})
+	}
+	return users
+}
+
+// ClientToken marks the i-th generated task, so a second run can tell
+// which ones already exist.
+func ClientToken(i int) string {
+	return fmt.Sprintf("seed-%04d", i)
+}
+
+// Tasks returns n tasks assigned round-robin to userIds, spread over every
+// status and priority with due dates from two weeks ago to a month ahead.
+func Tasks(r *rand.Rand, userIds []int, n int, now time.Time) []models.Task {
+	tasks := make([]models.Task, 0, n)
+	if len(userIds) == 0 {
+		return tasks
+	}
+
+	for i := 1; i <= n; i++ {
+		token := ClientToken(i)
+		status := models.Statuses[r.Intn(len(models.Statuses))]
+		created := now.Add(-time.Duration(r.Intn(30*24)) * time.Hour)
+		task := models.Task{
+			UserId:      userIds[(i-1)%len(userIds)],
+			Title:       taskVerbs[r.Intn(len(taskVerbs))] + " " + taskSubjects[r.Intn(len(taskSubjects))],
+			Description: "Generated by the development seed.",
+			Status:      status,
+			Priority:    models.PriorityLow + r.Intn(models.PriorityUrgent),
+			Estimate:    30 * (1 + r.Intn(16)),
+			ClientToken: &token,
+			CreatedAt:   created,
+		}
+		// a few tasks have no due date at all
+		if r.Intn(10) > 0 {
+			due := now.AddDate(0, 0, r.Intn(45)-14).Truncate(time.Hour)
+			task.DueDate = &due
+		}
+
+		changed := created.Add(time.Duration(1+r.Intn(48)) * time.Hour)
+		if status != models.StatusQueue {
+			task.StatusChangedAt = &changed
+		}
+		switch status {
+		case models.StatusReview:
+			task.SubmittedAt = &changed
+			task.SubmitDate = changed.Format("2006-01-02 15:04:05")
+			task.SubmitNote = "Ready for review."
+		case models.StatusRejected:
+			task.Revision = 1
+			task.Reason = "Please add the missing figures."
+			task.RejectedDate = changed.Format("2006-01-02 15:04:05")
+		case models.StatusApproved:
+			task.ApprovedDate = changed.Format("2006-01-02 15:04:05")
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks
+}
+
+// Comments returns zero to three comments per task, written by its
+// assignee or by adminId.
+func Comments(r *rand.Rand, tasks []models.Task, adminId int) []models.Comment {
+	comments := []models.Comment{}
+	for _, task := range tasks {
+		for n := r.Intn(4); n > 0; n-- {
+			author := task.UserId
+			if r.Intn(2) == 0 {
+				author = adminId
+			}
+			comments = append(comments, models.Comment{
+				TaskId:    task.Id,
+				AuthorId:  author,
+				Body:      commentBodies[r.Intn(len(commentBodies))],
+				CreatedAt: task.CreatedAt.Add(time.Duration(1+r.Intn(72)) * time.Hour),
+			})
+		}
+	}
+	return comments
+}
+
+// Attachments returns a text attachment for roughly one task in five. The
+// caller writes AttachmentContent to each Path.
+func Attachments(r *rand.Rand, tasks []models.Task) []models.Attachment {
+	attachments := []models.Attachment{}
+	for _, task := range tasks {
+		if r.Intn(5) != 0 {
+			continue
+		}
+		attachments = append(attachments, models.Attachment{
+			TaskId:     task.Id,
+			UploaderId: task.UserId,
+			FileName:   "notes.txt",
+			Path:       fmt.Sprintf("seed-task-%d.txt", task.Id),
+			Size:       int64(len(AttachmentContent)),
+			Mime:       "text/plain",
+		})
+	}
+	return attachments
+}
+
+// AttachmentContent is the body of every generated attachment.
+const AttachmentContent = "Seed attachment, safe to delete.\n"
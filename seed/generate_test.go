@@ -0,0 +1,131 @@
+package seed_test
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"tusk/models"
+	"tusk/seed"
+)
+
+func TestEmployees(t *testing.T) {
+	users := seed.Employees(120, "hash")
+	if len(users) != 120 {
+		t.Fatalf("%d employees, want 120", len(users))
+	}
+
+	emails, names := map[string]bool{}, map[string]bool{}
+	for i, user := range users {
+		if user.Email != seed.Email(i+1) || !strings.HasSuffix(user.Email, "@"+seed.EmailDomain) {
+			t.Errorf("employee %d email = %q, want %q", i+1, user.Email, seed.Email(i+1))
+		}
+		if user.Role != models.RoleEmployee || user.Password != "hash" || !user.IsActive || !user.EmailVerified {
+			t.Errorf("employee %d = %+v, want an active, verified Employee", i+1, user)
+		}
+		emails[user.Email], names[user.Name] = true, true
+	}
+	if len(emails) != 120 || len(names) != 120 {
+		t.Errorf("%d emails and %d names for 120 employees, want them unique", len(emails), len(names))
+	}
+	if !reflect.DeepEqual(users[:10], seed.Employees(10, "hash")) {
+		t.Error("a shorter run generated different employees")
+	}
+}
+
+func TestTasks(t *testing.T) {
+	now := time.Date(2024, 6, 15, 12, 0, 0, 0, time.UTC)
+	userIds := []int{4, 8, 15}
+	tasks := seed.Tasks(rand.New(rand.NewSource(1)), userIds, 300, now)
+	if len(tasks) != 300 {
+		t.Fatalf("%d tasks, want 300", len(tasks))
+	}
+	again := seed.Tasks(rand.New(rand.NewSource(1)), userIds, 300, now)
+	if !reflect.DeepEqual(tasks, again) {
+		t.Error("the same seed generated different tasks")
+	}
+
+	statuses, priorities := map[string]bool{}, map[int]bool{}
+	past, future, undated := 0, 0, 0
+	for i, task := range tasks {
+		if task.UserId != userIds[i%len(userIds)] || *task.ClientToken != seed.ClientToken(i+1) {
+			t.Errorf("task %d = user %d, token %s; want round-robin and %s", i+1, task.UserId, *task.ClientToken, seed.ClientToken(i+1))
+		}
+		statuses[task.Status], priorities[task.Priority] = true, true
+		switch {
+		case task.DueDate == nil:
+			undated++
+		case task.DueDate.Before(now):
+			past++
+		default:
+			future++
+		}
+		if (task.Status == models.StatusQueue) != (task.StatusChangedAt == nil) {
+			t.Errorf("task %d is %s with status_changed_at %v", i+1, task.Status, task.StatusChangedAt)
+		}
+		if (task.Status == models.StatusRejected) != (task.Reason != "") {
+			t.Errorf("task %d is %s with reason %q", i+1, task.Status, task.Reason)
+		}
+		if task.CreatedAt.After(now) {
+			t.Errorf("task %d created in the future", i+1)
+		}
+	}
+	if len(statuses) != len(models.Statuses) || len(priorities) != len(models.Priorities) {
+		t.Errorf("statuses %v, priorities %v; want every one", statuses, priorities)
+	}
+	for priority := range priorities {
+		if priority < models.PriorityLow || priority > models.PriorityUrgent {
+			t.Errorf("priority %d out of range", priority)
+		}
+	}
+	if past == 0 || future == 0 || undated == 0 {
+		t.Errorf("%d past, %d future and %d undated due dates, want some of each", past, future, undated)
+	}
+
+	if got := seed.Tasks(rand.New(rand.NewSource(1)), nil, 10, now); len(got) != 0 {
+		t.Errorf("%d tasks without users, want none", len(got))
+	}
+}
+
+func TestCommentsAndAttachments(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	tasks := seed.Tasks(r, []int{4, 8}, 100, time.Now().UTC())
+	for i := range tasks {
+		tasks[i].Id = 1000 + i
+	}
+	const adminId = 1
+
+	comments := seed.Comments(r, tasks, adminId)
+	if len(comments) == 0 {
+		t.Fatal("no comments for 100 tasks")
+	}
+	byId := map[int]models.Task{}
+	for _, task := range tasks {
+		byId[task.Id] = task
+	}
+	for _, comment := range comments {
+		task := byId[comment.TaskId]
+		if comment.AuthorId != adminId && comment.AuthorId != task.UserId {
+			t.Errorf("comment on %d by %d, want the assignee or the admin", comment.TaskId, comment.AuthorId)
+		}
+		if !comment.CreatedAt.After(task.CreatedAt) {
+			t.Errorf("comment on %d written before the task", comment.TaskId)
+		}
+	}
+
+	attachments := seed.Attachments(r, tasks)
+	if len(attachments) == 0 || len(attachments) == len(tasks) {
+		t.Fatalf("%d attachments for %d tasks, want a few", len(attachments), len(tasks))
+	}
+	paths := map[string]bool{}
+	for _, attachment := range attachments {
+		paths[attachment.Path] = true
+		if attachment.UploaderId != byId[attachment.TaskId].UserId || attachment.Size != int64(len(seed.AttachmentContent)) {
+			t.Errorf("attachment = %+v, want it uploaded by the assignee with the seed content", attachment)
+		}
+	}
+	if len(paths) != len(attachments) {
+		t.Error("attachments share a path")
+	}
+}
@@ -0,0 +1,171 @@
+package seed
+
+import (
+	"errors"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"time"
+	"tusk/models"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// ErrProduction is returned by Run when Options.Env is production.
+var ErrProduction = errors.New("seeding is disabled when APP_ENV=production")
+
+// Options controls what Run generates.
+type Options struct {
+	Env        string
+	Employees  int
+	Tasks      int
+	Password   string // shared by every generated employee
+	BcryptCost int
+	UploadDir  string // where attachment files go; empty skips attachments
+	AdminEmail string // the comment author on the admin side
+	RandSeed   int64
+}
+
+// Result counts the rows Run inserted.
+type Result struct {
+	Employees   int
+	Tasks       int
+	Comments    int
+	Attachments int
+}
+
+// Run inserts the generated data in one transaction. It is idempotent:
+// employees are matched by email, tasks by their seed client token, and
+// comments and attachments are only added to tasks created by this run.
+func Run(db *gorm.DB, opts Options) (Result, error) {
+	result := Result{}
+	if opts.Env == "production" {
+		return result, ErrProduction
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(opts.Password), opts.BcryptCost)
+	if err != nil {
+		return result, err
+	}
+	r := rand.New(rand.NewSource(opts.RandSeed))
+	now := time.Now().UTC()
+	attachments := []models.Attachment{}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		admin := models.User{}
+		if err := tx.Where("email=?", opts.AdminEmail).First(&admin).Error; err != nil {
+			return err
+		}
+
+		// employees
+		employees := Employees(opts.Employees, string(hash))
+		emails := make([]string, 0, len(employees))
+		for _, employee := range employees {
+			emails = append(emails, employee.Email)
+		}
+		existing := []models.User{}
+		if err := tx.Unscoped().Select("id, email").Where("email IN ?", emails).Find(&existing).Error; err != nil {
+			return err
+		}
+		known := map[string]int{}
+		for _, user := range existing {
+			known[user.Email] = user.Id
+		}
+		missing := []models.User{}
+		for _, employee := range employees {
+			if _, ok := known[employee.Email]; !ok {
+				missing = append(missing, employee)
+			}
+		}
+		if len(missing) > 0 {
+			if err := tx.CreateInBatches(&missing, 100).Error; err != nil {
+				return err
+			}
+		}
+		result.Employees = len(missing)
+
+		userIds := make([]int, 0, len(employees))
+		for _, user := range missing {
+			known[user.Email] = user.Id
+		}
+		for _, email := range emails {
+			userIds = append(userIds, known[email])
+		}
+
+		// tasks, generated in full every run so the output stays
+		// deterministic, then filtered to the ones not seeded yet
+		tokens := []string{}
+		if err := tx.Model(&models.Task{}).Where("client_token LIKE ?", "seed-%").Pluck("client_token", &tokens).Error; err != nil {
+			return err
+		}
+		seeded := map[string]bool{}
+		for _, token := range tokens {
+			seeded[token] = true
+		}
+		tasks := []models.Task{}
+		for _, task := range Tasks(r, userIds, opts.Tasks, now) {
+			if !seeded[*task.ClientToken] {
+				tasks = append(tasks, task)
+			}
+		}
+		if len(tasks) == 0 {
+			return nil
+		}
+		// Reason has an empty SQL default, so a batch mixing rejected and other
+		// tasks would need the DEFAULT keyword, which sqlite rejects
+		rejected, others := []models.Task{}, []models.Task{}
+		for _, task := range tasks {
+			if task.Reason != "" {
+				rejected = append(rejected, task)
+			} else {
+				others = append(others, task)
+			}
+		}
+		for _, batch := range [][]models.Task{others, rejected} {
+			if len(batch) == 0 {
+				continue
+			}
+			if err := tx.CreateInBatches(&batch, 100).Error; err != nil {
+				return err
+			}
+		}
+		tasks = append(others, rejected...)
+		result.Tasks = len(tasks)
+
+		comments := Comments(r, tasks, admin.Id)
+		if len(comments) > 0 {
+			if err := tx.CreateInBatches(&comments, 100).Error; err != nil {
+				return err
+			}
+		}
+		result.Comments = len(comments)
+
+		if opts.UploadDir != "" {
+			attachments = Attachments(r, tasks)
+			if len(attachments) > 0 {
+				if err := tx.CreateInBatches(&attachments, 100).Error; err != nil {
+					return err
+				}
+			}
+		}
+		result.Attachments = len(attachments)
+		return nil
+	})
+	if err != nil {
+		return Result{}, err
+	}
+
+	if len(attachments) == 0 {
+		return result, nil
+	}
+	if err := os.MkdirAll(opts.UploadDir, 0o755); err != nil {
+		return result, err
+	}
+	for _, attachment := range attachments {
+		if err := os.WriteFile(filepath.Join(opts.UploadDir, attachment.Path), []byte(AttachmentContent), 0o644); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,126 @@
+package seed_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"tusk/models"
+	"tusk/seed"
+	"tusk/testutil"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+func options(t *testing.T, db *gorm.DB) seed.Options {
+	t.Helper()
+
+	admin := testutil.User(t, db, models.RoleAdmin)
+	return seed.Options{
+		Env:        "development",
+		Employees:  5,
+		Tasks:      40,
+		Password:   "seed-password-1",
+		BcryptCost: bcrypt.MinCost,
+		UploadDir:  t.TempDir(),
+		AdminEmail: admin.Email,
+		RandSeed:   1,
+	}
+}
+
+func count(t *testing.T, db *gorm.DB, model interface{}) int64 {
+	t.Helper()
+
+	var n int64
+	if err := db.Model(model).Count(&n).Error; err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestRun(t *testing.T) {
+	db := testutil.DB(t)
+	opts := options(t, db)
+
+	first, err := seed.Run(db, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Employees != 5 || first.Tasks != 40 || first.Comments == 0 || first.Attachments == 0 {
+		t.Fatalf("result = %+v, want everything seeded", first)
+	}
+	if got := count(t, db, &models.Task{}); got != 40 {
+		t.Errorf("%d tasks, want 40", got)
+	}
+	if got := count(t, db, &models.Comment{}); got != int64(first.Comments) {
+		t.Errorf("%d comments, want %d", got, first.Comments)
+	}
+
+	employee := models.User{}
+	if err := db.Where("email = ?", seed.Email(1)).First(&employee).Error; err != nil {
+		t.Fatal(err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(employee.Password), []byte(opts.Password)) != nil {
+		t.Error("employees don't have the known password")
+	}
+
+	attachments := []models.Attachment{}
+	db.Find(&attachments)
+	for _, attachment := range attachments {
+		content, err := os.ReadFile(filepath.Join(opts.UploadDir, attachment.Path))
+		if err != nil || string(content) != seed.AttachmentContent {
+			t.Errorf("attachment %s = %q, %v", attachment.Path, content, err)
+		}
+	}
+
+	t.Run("again", func(t *testing.T) {
+		again, err := seed.Run(db, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if again != (seed.Result{}) {
+			t.Errorf("result = %+v, want nothing inserted", again)
+		}
+		if users, tasks := count(t, db, &models.User{}), count(t, db, &models.Task{}); users != 6 || tasks != 40 {
+			t.Errorf("%d users and %d tasks, want 6 and 40", users, tasks)
+		}
+	})
+
+	t.Run("bigger", func(t *testing.T) {
+		opts.Employees, opts.Tasks = 7, 50
+		more, err := seed.Run(db, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if more.Employees != 2 || more.Tasks != 10 {
+			t.Errorf("result = %+v, want only the 2 new employees and 10 new tasks", more)
+		}
+	})
+}
+
+func TestRunRefusesProduction(t *testing.T) {
+	db := testutil.DB(t)
+	opts := options(t, db)
+	opts.Env = "production"
+
+	if _, err := seed.Run(db, opts); !errors.Is(err, seed.ErrProduction) {
+		t.Fatalf("err = %v, want ErrProduction", err)
+	}
+	if users, tasks := count(t, db, &models.User{}), count(t, db, &models.Task{}); users != 1 || tasks != 0 {
+		t.Errorf("%d users and %d tasks after a refused run, want only the admin", users, tasks)
+	}
+}
+
+func TestRunWithoutAdmin(t *testing.T) {
+	db := testutil.DB(t)
+	opts := options(t, db)
+	opts.AdminEmail = "nobody@go.id"
+
+	if _, err := seed.Run(db, opts); !errors.Is(err, gorm.ErrRecordNotFound) {
+		t.Fatalf("err = %v, want the missing admin", err)
+	}
+	if got := count(t, db, &models.User{}); got != 1 {
+		t.Errorf("%d users, want the transaction rolled back", got)
+	}
+}
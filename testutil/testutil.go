@@ -0,0 +1,289 @@
+// Package testutil holds what the handler tests share: a database with the
+// current schema, users with tokens, and the full router wired the way
+// main wires it, with fakes for the notifier and the mailer.
+package testutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"tusk/activity"
+	"tusk/apierror"
+	"tusk/config"
+	"tusk/controllers"
+	"tusk/events"
+	"tusk/idempotency"
+	"tusk/mailer"
+	"tusk/metrics"
+	"tusk/middlewares"
+	"tusk/migrations"
+	"tusk/models"
+	"tusk/notifications"
+	"tusk/ratelimit"
+	"tusk/reminders"
+	"tusk/routes"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// Secret signs the tokens of Token and of the router.
+const Secret = "test-secret"
+
+// Password is the password of every user made by User.
+const Password = "kopi-susu-9"
+
+var databases atomic.Int64
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	apierror.RegisterValidators()
+}
+
+// DB opens a fresh in-memory sqlite database with every migration applied.
+// It has a single connection, so a transaction and a query outside it
+// can't run at the same time.
+func DB(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	dsn := fmt.Sprintf("file:tusk%d?mode=memory&cache=shared&_foreign_keys=1", databases.Add(1))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		TranslateError: true,
+		NowFunc:        func() time.Time { return time.Now().UTC() },
+		Logger:         logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if _, err := migrations.Up(db, func(string, ...interface{}) {}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// User creates an active, verified user with Password; change can adjust
+// it before it is inserted.
+func User(t testing.TB, db *gorm.DB, role string, change ...func(*models.User)) models.User {
+	t.Helper()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(Password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := databases.Add(1)
+	user := models.User{
+		Role:          role,
+		Name:          fmt.Sprintf("%s %d", role, n),
+		Email:         fmt.Sprintf("%s%d@go.id", strings.ToLower(role), n),
+		Password:      string(hash),
+		EmailVerified: true,
+		IsActive:      true,
+	}
+	for _, f := range change {
+		f(&user)
+	}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// Token is an access token for user signed with Secret.
+func Token(t testing.TB, user models.User) string {
+	t.Helper()
+
+	token, err := middlewares.GenerateToken(Secret, user.Id, user.Role, user.TokenVersion, user.MustChangePassword, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+// Server is the API on a test database.
+type Server struct {
+	DB       *gorm.DB
+	Router   *gin.Engine
+	Notifier *notifications.Fake
+	Mailer   *mailer.Fake
+	Events   *events.Hub
+	Users    *controllers.UserController
+	Tasks    *controllers.TaskController
+}
+
+// NewServer builds the router on db the way main does. change can adjust
+// the configuration first.
+func NewServer(t testing.TB, db *gorm.DB, change ...func(*config.Config)) *Server {
+	t.Helper()
+
+	cfg := Config(t)
+	for _, f := range change {
+		f(&cfg)
+	}
+
+	if err := activity.Register(db, middlewares.UserIdFromContext); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Server{DB: db, Notifier: &notifications.Fake{}, Mailer: &mailer.Fake{}, Events: events.NewHub(32)}
+	appMetrics := metrics.New(prometheus.NewRegistry())
+	denylist := middlewares.NewMemoryDenylist(time.Minute)
+	limiter := ratelimit.NewMemory(time.Minute, 1000)
+	store := idempotency.NewStore(db, time.Hour, time.Second, time.Hour)
+	t.Cleanup(func() {
+		s.Events.Close()
+		denylist.Close()
+		limiter.Close()
+		store.Close()
+	})
+
+	s.Users = &controllers.UserController{
+		DB:            db,
+		JWTSecret:     Secret,
+		TokenExpiry:   cfg.TokenExpiry,
+		RefreshExpiry: cfg.RefreshExpiry,
+		Mailer:        s.Mailer,
+		Lockout:       cfg.Lockout,
+		BcryptCost:    bcrypt.MinCost,
+		Denylist:      denylist,
+		Metrics:       appMetrics,
+		Avatars:       cfg.Avatars,
+	}
+	attachments := &controllers.AttachmentController{DB: db, Uploads: cfg.Uploads}
+	s.Tasks = &controllers.TaskController{
+		DB:             db,
+		Assigner:       &controllers.AutoAssigner{Config: cfg.AutoAssign},
+		ForecastConfig: cfg.Forecast,
+		UploadDir:      cfg.Uploads.Dir,
+		EvidenceMax:    cfg.Uploads.EvidenceMax,
+		Attachments:    attachments,
+		Notifier:       s.Notifier,
+		Mailer:         s.Mailer,
+		Events:         s.Events,
+		BulkConfig:     cfg.Bulk,
+		Metrics:        appMetrics,
+	}
+	startedAt := time.Now()
+
+	router := gin.New()
+	router.Use(apierror.Middleware())
+	router.Use(middlewares.QueryTimeout(cfg.QueryTimeout, routes.Untimed()...))
+	routes.Setup(router, routes.Dependencies{
+		Users:       s.Users,
+		Tasks:       s.Tasks,
+		Attachments: attachments,
+		Comments:    &controllers.CommentController{DB: db, Events: s.Events},
+		Tags:        &controllers.TagController{DB: db},
+		Departments: &controllers.DepartmentController{DB: db},
+		Subtasks:    &controllers.SubtaskController{DB: db},
+		Activities:  &controllers.ActivityController{DB: db},
+		Events:      &controllers.EventController{Hub: s.Events},
+		Dashboard:   &controllers.DashboardController{DB: db},
+		Reports:     &controllers.ReportController{DB: db},
+		Admin: &controllers.AdminController{
+			DB:        db,
+			Stats:     middlewares.NewRequestStats(),
+			StartedAt: startedAt,
+			Reminders: reminders.New(db, cfg.Reminders, s.Tasks.Remind),
+		},
+		JWTSecret:   Secret,
+		TokenChecks: []middlewares.TokenCheck{middlewares.DenylistCheck(denylist), s.Users.CheckTokenVersion, s.Users.ScopeDepartment},
+		Limiter:     limiter,
+		Idempotency: store,
+		RateLimit:   cfg.RateLimit,
+		LegacyDir:   t.TempDir(),
+	})
+	s.Router = router
+	return s
+}
+
+// Config is the development configuration with uploads in a temporary
+// directory.
+func Config(t testing.TB) config.Config {
+	t.Helper()
+
+	t.Setenv("JWT_SECRET", Secret)
+	t.Setenv("DB_DRIVER", "sqlite")
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	cfg.Uploads.Dir = dir
+	cfg.Avatars.Dir = dir + "/avatars"
+	return cfg
+}
+
+// Do sends a request to the router. A body that isn't an io.Reader is sent
+// as JSON; token may be empty.
+func (s *Server) Do(t testing.TB, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	return Do(t, s.Router, method, path, token, body)
+}
+
+// Do sends a request to handler, like Server.Do.
+func Do(t testing.TB, handler http.Handler, method, path, token string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	var reader io.Reader
+	contentType := ""
+	switch b := body.(type) {
+	case nil:
+	case io.Reader:
+		reader = b
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	res := httptest.NewRecorder()
+	handler.ServeHTTP(res, req)
+	return res
+}
+
+// Decode unmarshals the JSON body of res into v, failing the test when it
+// isn't JSON.
+func Decode(t testing.TB, res *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+
+	if err := json.Unmarshal(res.Body.Bytes(), v); err != nil {
+		t.Fatalf("response %d is not JSON: %v\n%s", res.Code, err, res.Body.String())
+	}
+}
+
+// Expect fails the test when res doesn't have status.
+func Expect(t testing.TB, res *httptest.ResponseRecorder, status int) {
+	t.Helper()
+
+	if res.Code != status {
+		t.Fatalf("status = %d, want %d\n%s", res.Code, status, res.Body.String())
+	}
+}